@@ -1,9 +1,14 @@
 package ai_tools
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/otkinlife/go_tools/http_tools"
 )
 
 func TestNewAIClient(t *testing.T) {
@@ -156,14 +161,78 @@ func TestChatOptions(t *testing.T) {
 	}
 }
 
-// TestRealAPICall 测试实际的 API 调用
-func TestRealAPICall(t *testing.T) {
-	apiKey := ""
-	baseURL := ""
-	model := ""
+// TestChatCompletionWithTestServer 使用本地服务测试请求和错误处理
+func TestChatCompletionWithTestServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Header.Get("Authorization") {
+		case "Bearer good-key":
+			_, _ = w.Write([]byte(`{"id":"1","choices":[{"index":0,"message":{"role":"assistant","content":"hi"}}]}`))
+		case "Bearer bad-key":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"invalid api key","type":"auth"}}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`upstream down`))
+		}
+	}))
+	defer server.Close()
+
+	reply, err := NewAIClient(NewAPIConfig("good-key", server.URL)).SimpleChat("test-model", "hello")
+	if err != nil || reply != "hi" {
+		t.Errorf("Expected reply 'hi', got %q, %v", reply, err)
+	}
+
+	_, err = NewAIClient(NewAPIConfig("bad-key", server.URL)).SimpleChat("test-model", "hello")
+	if err == nil || err.Error() != "API error (401): invalid api key" {
+		t.Errorf("Expected API error, got %v", err)
+	}
+
+	_, err = NewAIClient(NewAPIConfig("other-key", server.URL)).SimpleChat("test-model", "hello")
+	if err == nil || err.Error() != "API request failed with status 502: upstream down" {
+		t.Errorf("Expected status error, got %v", err)
+	}
+}
+
+// fixtureClient 回放 testdata/chat_fixture.json 中的接口响应，测试不需要访问网络
+// 该文件是按 OpenAI 接口格式手写的模拟响应，不是真实服务的录制
+// 设置环境变量 AI_API_KEY 时访问真实服务并用录制结果覆盖该文件，AI_BASE_URL、AI_MODEL 可以指定服务地址和模型
+func fixtureClient(t *testing.T, apiKey string) *http_tools.Client {
+	mode := http_tools.ModeReplay
+	if apiKey != "" {
+		mode = http_tools.ModeRecord
+	}
+	recorder, err := http_tools.NewRecorder("testdata/chat_fixture.json", mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 按请求体匹配，录制和回放的服务地址可以不同
+	recorder.SetMatchers(http_tools.MatchMethod, http_tools.MatchPath, http_tools.MatchBody)
+	t.Cleanup(func() {
+		if err := recorder.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+	return recorder.Client()
+}
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// TestChatCompletionFixture 使用模拟响应测试完整的对话流程，设置 AI_API_KEY 时访问真实服务
+func TestChatCompletionFixture(t *testing.T) {
+	apiKey := os.Getenv("AI_API_KEY")
+	baseURL := getenv("AI_BASE_URL", "https://api.openai.com/v1")
+	model := getenv("AI_MODEL", "gpt-4o-mini")
+	httpClient := fixtureClient(t, apiKey)
 
 	var config *AIConfig
 	config = NewAPIConfig(apiKey, baseURL)
+	config.HTTPClient = httpClient
 
 	client := NewAIClient(config)
 
@@ -245,7 +314,8 @@ func TestRealAPICall(t *testing.T) {
 	})
 
 	t.Run("错误处理测试", func(t *testing.T) {
-		invalidConfig := DefaultConfig("invalid-key-test")
+		invalidConfig := NewAPIConfig("invalid-key-test", baseURL)
+		invalidConfig.HTTPClient = httpClient
 		invalidClient := NewAIClient(invalidConfig)
 
 		_, err := invalidClient.SimpleChat(model, "测试")
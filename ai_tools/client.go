@@ -1,6 +1,7 @@
 package ai_tools
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -26,11 +27,10 @@ func NewAIClient(config *AIConfig) *AIClient {
 // ChatCompletion sends a chat completion request to the AI API
 func (c *AIClient) ChatCompletion(request *ChatRequest) (*ChatResponse, error) {
 	// Create HTTP client
-	client, err := http_tools.NewReqClient("POST", c.config.BaseURL+"/chat/completions")
+	client, err := c.httpClient().NewRequest("POST", c.config.BaseURL+"/chat/completions")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
 	}
-	defer client.Close()
 
 	// Set headers
 	headers := map[string]string{
@@ -47,30 +47,34 @@ func (c *AIClient) ChatCompletion(request *ChatRequest) (*ChatResponse, error) {
 		return nil, fmt.Errorf("failed to set JSON body: %w", err)
 	}
 
-	// Send request
-	if err := client.Send(); err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-
-	// Check status code
-	statusCode := client.GetHttpCode()
-	if statusCode != 200 {
+	// Send request and decode the response
+	response, resp, err := http_tools.Do[ChatResponse](client)
+	if err != nil {
+		var httpErr *http_tools.HTTPError
+		if !errors.As(err, &httpErr) {
+			if resp != nil {
+				return nil, fmt.Errorf("failed to parse response: %w", err)
+			}
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
 		var errorResp ErrorResponse
-		if err := client.LoadBody(&errorResp); err != nil {
-			return nil, fmt.Errorf("API request failed with status %d: %s", statusCode, client.GetBodyString())
+		if err := httpErr.Decode(&errorResp); err != nil || errorResp.Error.Message == "" {
+			return nil, fmt.Errorf("API request failed with status %d: %s", httpErr.StatusCode, httpErr.Body)
 		}
-		return nil, fmt.Errorf("API error (%d): %s", statusCode, errorResp.Error.Message)
-	}
-
-	// Parse response
-	var response ChatResponse
-	if err := client.LoadBody(&response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, fmt.Errorf("API error (%d): %s", httpErr.StatusCode, errorResp.Error.Message)
 	}
 
 	return &response, nil
 }
 
+// httpClient returns the configured HTTP client, falling back to the package default
+func (c *AIClient) httpClient() *http_tools.Client {
+	if c.config.HTTPClient != nil {
+		return c.config.HTTPClient
+	}
+	return http_tools.DefaultClient()
+}
+
 // SimpleChat sends a simple chat message and returns the response content
 func (c *AIClient) SimpleChat(model, message string) (string, error) {
 	request := &ChatRequest{
@@ -1,6 +1,10 @@
 package ai_tools
 
-import "time"
+import (
+	"time"
+
+	"github.com/otkinlife/go_tools/http_tools"
+)
 
 // ChatMessage represents a single message in the conversation
 type ChatMessage struct {
@@ -61,6 +65,10 @@ type AIConfig struct {
 	APIKey  string        // API key for authentication
 	BaseURL string        // Base URL for the API (default: https://api.openai.com/v1)
 	Timeout time.Duration // Request timeout (default: 30s)
+
+	// HTTPClient is the shared http_tools client used for requests, so proxy,
+	// TLS and connection pool settings can be configured (default: http_tools.DefaultClient())
+	HTTPClient *http_tools.Client
 }
 
 // DefaultConfig returns a default configuration
@@ -6,9 +6,25 @@ import (
 
 type Handler func(params interface{}, results interface{}) error
 
+// CacheAnything 先从管理器的缓存中取结果，取不到时调用handler并把结果写入缓存
+func (cm *CacheManager) CacheAnything(key string, handler Handler, params interface{}, results interface{}, expire time.Duration) error {
+	return cacheAnything(cm.cache, key, handler, params, results, expire)
+}
+
+// CacheAnything 使用全局缓存，需要先调用 Init
+// 新代码请使用 CacheManager.CacheAnything
 func CacheAnything(key string, handler Handler, params interface{}, results interface{}, expire time.Duration) error {
+	return cacheAnything(GlobalCache, key, handler, params, results, expire)
+}
+
+// DeleteKey 删除全局缓存中的key，需要先调用 Init
+func DeleteKey(key string) error {
+	return GlobalCache.Delete(key)
+}
+
+func cacheAnything(cache *Cache, key string, handler Handler, params interface{}, results interface{}, expire time.Duration) error {
 	// 尝试从cache中取结果
-	if err := GlobalCache.LoadDataFromJson(key, results); err == nil && results != nil {
+	if err := cache.LoadDataFromJson(key, results); err == nil && results != nil {
 		return nil
 	}
 	err := handler(params, results)
@@ -16,9 +32,5 @@ func CacheAnything(key string, handler Handler, params interface{}, results inte
 		return err
 	}
 	// 异步写缓存写入缓存
-	return GlobalCache.SetDataWithJsonWithExpiration(key, results, expire)
-}
-
-func DeleteKey(key string) error {
-	return GlobalCache.Delete(key)
+	return cache.SetDataWithJsonWithExpiration(key, results, expire)
 }
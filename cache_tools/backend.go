@@ -0,0 +1,380 @@
+package cache_tools
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Backend 远程缓存存储，作为 TieredCache 的二级缓存
+type Backend interface {
+	// Get 获取key的值，第二个返回值表示是否命中
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set 设置key的值，ttl为0表示永不过期
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete 删除key
+	Delete(ctx context.Context, key string) error
+	// TTL 获取key的剩余过期时间，0表示永不过期，第二个返回值表示key是否存在
+	TTL(ctx context.Context, key string) (time.Duration, bool, error)
+}
+
+// Broadcaster 广播消息，用于通知其他实例失效本地缓存
+type Broadcaster interface {
+	// Publish 向channel发送消息
+	Publish(ctx context.Context, channel string, message []byte) error
+	// Subscribe 订阅channel，收到消息时调用handler，直到ctx取消或连接断开才返回
+	Subscribe(ctx context.Context, channel string, handler func(message []byte)) error
+}
+
+// RESPError 服务端返回的错误
+type RESPError string
+
+func (e RESPError) Error() string {
+	return string(e)
+}
+
+// RESPBackend 基于Redis RESP协议的远程缓存，兼容Redis及其他支持RESP协议的服务
+// 同时实现了 Backend 和 Broadcaster
+type RESPBackend struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+	pool     chan *respConn
+}
+
+// RESPOption RESPBackend 的可选配置
+type RESPOption func(*RESPBackend)
+
+// WithRESPPassword 设置连接密码
+func WithRESPPassword(password string) RESPOption {
+	return func(b *RESPBackend) {
+		b.password = password
+	}
+}
+
+// WithRESPDB 设置数据库编号
+func WithRESPDB(db int) RESPOption {
+	return func(b *RESPBackend) {
+		b.db = db
+	}
+}
+
+// WithRESPTimeout 设置连接和读写超时，默认3秒
+func WithRESPTimeout(timeout time.Duration) RESPOption {
+	return func(b *RESPBackend) {
+		b.timeout = timeout
+	}
+}
+
+// WithRESPPoolSize 设置空闲连接池大小，默认10
+func WithRESPPoolSize(size int) RESPOption {
+	return func(b *RESPBackend) {
+		b.pool = make(chan *respConn, size)
+	}
+}
+
+// NewRESPBackend 创建RESP协议的远程缓存
+// addr: 服务地址，如 "127.0.0.1:6379"
+func NewRESPBackend(addr string, opts ...RESPOption) *RESPBackend {
+	b := &RESPBackend{
+		addr:    addr,
+		timeout: 3 * time.Second,
+		pool:    make(chan *respConn, 10),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Get 获取key的值
+func (b *RESPBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := b.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("resp: unexpected GET reply %T", reply)
+	}
+	return value, true, nil
+}
+
+// Set 设置key的值，ttl为0表示永不过期
+func (b *RESPBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []any{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", max(ttl.Milliseconds(), 1))
+	}
+	_, err := b.do(ctx, args...)
+	return err
+}
+
+// Delete 删除key
+func (b *RESPBackend) Delete(ctx context.Context, key string) error {
+	_, err := b.do(ctx, "DEL", key)
+	return err
+}
+
+// TTL 获取key的剩余过期时间
+func (b *RESPBackend) TTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	reply, err := b.do(ctx, "PTTL", key)
+	if err != nil {
+		return 0, false, err
+	}
+	ms, ok := reply.(int64)
+	if !ok {
+		return 0, false, fmt.Errorf("resp: unexpected PTTL reply %T", reply)
+	}
+	switch {
+	case ms == -2:
+		return 0, false, nil
+	case ms < 0:
+		return 0, true, nil
+	default:
+		return time.Duration(ms) * time.Millisecond, true, nil
+	}
+}
+
+// Publish 向channel发送消息
+func (b *RESPBackend) Publish(ctx context.Context, channel string, message []byte) error {
+	_, err := b.do(ctx, "PUBLISH", channel, message)
+	return err
+}
+
+// Subscribe 订阅channel，使用独立的连接，直到ctx取消或连接断开才返回
+func (b *RESPBackend) Subscribe(ctx context.Context, channel string, handler func(message []byte)) error {
+	conn, err := b.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+
+	// ctx取消时关闭连接，让阻塞的读取返回
+	stop := context.AfterFunc(ctx, func() {
+		conn.close()
+	})
+	defer stop()
+
+	if err := conn.write("SUBSCRIBE", channel); err != nil {
+		return err
+	}
+	// 订阅连接长时间没有消息是正常的，不设置读超时
+	_ = conn.conn.SetDeadline(time.Time{})
+	for {
+		reply, err := conn.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		// 推送的消息格式: ["message", channel, payload]
+		items, ok := reply.([]any)
+		if !ok || len(items) != 3 {
+			continue
+		}
+		if kind, _ := items[0].([]byte); string(kind) != "message" {
+			continue
+		}
+		if payload, ok := items[2].([]byte); ok {
+			handler(payload)
+		}
+	}
+}
+
+// Close 关闭连接池中的空闲连接
+func (b *RESPBackend) Close() error {
+	for {
+		select {
+		case conn := <-b.pool:
+			conn.close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do 从连接池取一个连接执行命令
+func (b *RESPBackend) do(ctx context.Context, args ...any) (any, error) {
+	conn, err := b.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(ctx, b.timeout, args...)
+	var respErr RESPError
+	if err != nil && !errors.As(err, &respErr) {
+		// 网络错误后连接状态未知，直接关闭
+		conn.close()
+		return nil, err
+	}
+	b.put(conn)
+	return reply, err
+}
+
+func (b *RESPBackend) get(ctx context.Context) (*respConn, error) {
+	select {
+	case conn := <-b.pool:
+		return conn, nil
+	default:
+		return b.dial(ctx)
+	}
+}
+
+func (b *RESPBackend) put(conn *respConn) {
+	select {
+	case b.pool <- conn:
+	default:
+		conn.close()
+	}
+}
+
+// dial 建立连接并完成认证和选择数据库
+func (b *RESPBackend) dial(ctx context.Context) (*respConn, error) {
+	dialer := net.Dialer{Timeout: b.timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", b.addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := newRESPConn(netConn)
+	if b.password != "" {
+		if _, err := conn.do(ctx, b.timeout, "AUTH", b.password); err != nil {
+			conn.close()
+			return nil, err
+		}
+	}
+	if b.db != 0 {
+		if _, err := conn.do(ctx, b.timeout, "SELECT", b.db); err != nil {
+			conn.close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// respConn 一个RESP协议连接
+type respConn struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	writer    *bufio.Writer
+	closeOnce sync.Once
+}
+
+func newRESPConn(conn net.Conn) *respConn {
+	return &respConn{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		writer: bufio.NewWriter(conn),
+	}
+}
+
+func (c *respConn) close() {
+	c.closeOnce.Do(func() {
+		_ = c.conn.Close()
+	})
+}
+
+// do 发送命令并读取回复
+func (c *respConn) do(ctx context.Context, timeout time.Duration, args ...any) (any, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.conn.SetDeadline(deadline)
+	if err := c.write(args...); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// write 按RESP协议发送命令: 由多个bulk string组成的数组
+func (c *respConn) write(args ...any) error {
+	c.writer.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		var b []byte
+		switch v := arg.(type) {
+		case string:
+			b = []byte(v)
+		case []byte:
+			b = v
+		case int:
+			b = strconv.AppendInt(nil, int64(v), 10)
+		case int64:
+			b = strconv.AppendInt(nil, v, 10)
+		default:
+			return fmt.Errorf("resp: unsupported argument type %T", arg)
+		}
+		c.writer.WriteString("$" + strconv.Itoa(len(b)) + "\r\n")
+		c.writer.Write(b)
+		c.writer.WriteString("\r\n")
+	}
+	return c.writer.Flush()
+}
+
+// read 读取一个回复
+// 简单字符串返回string，错误返回RESPError，整数返回int64，bulk string返回[]byte，数组返回[]any，空值返回nil
+func (c *respConn) read() (any, error) {
+	return readRESP(c.reader)
+}
+
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("resp: invalid reply line %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, RESPError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("resp: invalid bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("resp: invalid array length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readRESP(r); err != nil {
+				var respErr RESPError
+				if !errors.As(err, &respErr) {
+					return nil, err
+				}
+				items[i] = respErr
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("resp: unknown reply type %q", kind)
+}
@@ -3,38 +3,176 @@ package cache_tools
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
-	"log"
+	"hash/fnv"
+	"iter"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Cache 缓存结构
+// 数据按key的哈希分散到多个分片中，每个分片有独立的锁和淘汰策略；全局的大小和数量使用原子操作统计
 type Cache struct {
-	data     sync.Map      // 缓存的数据，这里使用sync.Map防止并发读写问题
-	size     int64         // 缓存的数据大小，用于做限制，防止内存溢出
-	keyList  []*cacheEntry // 全局的key列表，用于维护key的优先级。LRU淘汰的时候讲从该结构中选取优先级低的key淘汰
-	keyMutex sync.Mutex    // 用于保护keyList的互斥锁
+	shards   []*shard     // 缓存分片，数量是2的幂
+	mask     uint64       // 分片掩码，用于根据哈希值选择分片
+	size     atomic.Int64 // 缓存的数据大小，用于做限制，防止内存溢出
+	count    atomic.Int64 // 缓存的key数量
+	evictMu  sync.Mutex   // 保证同一时间只有一个协程在做淘汰
+	codec    Codec        // 值编解码器，为空时通过反射估算值的大小，需要在使用缓存前设置
+	policyFn PolicyFactory
+	limitCh  chan int                  // 写入后通知watcher检查大小限制，由NewWatcher绑定
+	journal  atomic.Pointer[appendLog] // 追加日志，为空表示未开启
+	logger   Logger
+	keyFn    KeyStrategy  // 生成内部key的策略
+	metrics  cacheMetrics // 命中率、淘汰次数、延迟等运行统计
+	hooks    hookRegistry // 写入、淘汰、过期的回调
 }
 
-type cacheEntry struct {
-	key          interface{}
-	accessCount  int64
-	lastUsedTime time.Time
+// NewCache 创建缓存，默认使用LRU淘汰策略
+func NewCache() *Cache {
+	return NewShardedCache(DefaultShardCount, NewLRUPolicy)
 }
 
-func NewCache() *Cache {
-	return &Cache{
-		keyList: make([]*cacheEntry, 0),
+// NewCacheWithPolicy 创建使用指定淘汰策略的缓存
+func NewCacheWithPolicy(factory PolicyFactory) *Cache {
+	return NewShardedCache(DefaultShardCount, factory)
+}
+
+// NewShardedCache 创建指定分片数量的缓存
+// shardCount 会向上取整到2的幂，小于1时按1处理
+// factory 为每个分片创建淘汰策略
+func NewShardedCache(shardCount int, factory PolicyFactory) *Cache {
+	n := roundShardCount(shardCount)
+	c := &Cache{
+		shards:   make([]*shard, n),
+		mask:     uint64(n - 1),
+		policyFn: factory,
+		logger:   NewStdLogger(LogLevelError),
+		keyFn:    HashedDateKey,
+	}
+	for i := range c.shards {
+		c.shards[i] = newShard(factory())
+	}
+	return c
+}
+
+// roundShardCount 将分片数量向上取整到2的幂
+func roundShardCount(shardCount int) int {
+	n := 1
+	for n < shardCount {
+		n <<= 1
+	}
+	return n
+}
+
+// SetShards 修改分片数量，会向上取整到2的幂，需要在并发读写之前设置
+// 回调、统计、编解码器等配置保持不变，已有的key会重新分配到新的分片
+func (c *Cache) SetShards(shardCount int) {
+	n := roundShardCount(shardCount)
+	if n == len(c.shards) {
+		return
+	}
+	old := c.shards
+	c.shards = make([]*shard, n)
+	c.mask = uint64(n - 1)
+	for i := range c.shards {
+		c.shards[i] = newShard(c.policyFn())
+		c.shards[i].journal = c.journal.Load()
+	}
+	for _, s := range old {
+		s.mu.Lock()
+		for key, item := range s.items {
+			c.getShard(key).set(key, item, nil)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// SetEvictionPolicy 替换淘汰策略，已有的key会加入新策略
+func (c *Cache) SetEvictionPolicy(factory PolicyFactory) {
+	c.policyFn = factory
+	for _, s := range c.shards {
+		s.setPolicy(factory())
+	}
+}
+
+// Len 返回缓存中key的数量
+func (c *Cache) Len() int {
+	return int(c.count.Load())
+}
+
+// Size 返回缓存的数据大小(字节)
+func (c *Cache) Size() int64 {
+	return c.size.Load()
+}
+
+// ShardCount 返回分片数量
+func (c *Cache) ShardCount() int {
+	return len(c.shards)
+}
+
+// SetCodec 设置值编解码器，设置后原生值的大小按编码后的字节数统计
+func (c *Cache) SetCodec(codec Codec) {
+	c.codec = codec
+}
+
+// GetCodec 获取值编解码器
+func (c *Cache) GetCodec() Codec {
+	return c.codec
+}
+
+// SetKeyStrategy 设置生成内部key的策略，默认使用 HashedDateKey，需要在写入数据之前设置
+func (c *Cache) SetKeyStrategy(strategy KeyStrategy) {
+	if strategy == nil {
+		strategy = HashedDateKey
 	}
+	c.keyFn = strategy
+}
+
+// buildKey 根据写入时使用的key生成内部key
+func (c *Cache) buildKey(params string) string {
+	return NewKeyBuilder().SetParams(params).SetStrategy(c.keyFn).Build()
+}
+
+// SetLogger 设置日志，传入nil表示不输出日志，需要在使用缓存前设置
+func (c *Cache) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = NopLogger()
+	}
+	c.logger = logger
+}
+
+// GetLogger 获取日志
+func (c *Cache) GetLogger() Logger {
+	return c.logger
 }
 
 func (c *Cache) Clear() {
-	fmt.Println("clear cache")
-	// 直接赋空值，利用GC回收旧的Cache
-	c.data = sync.Map{}
-	c.size = 0
-	c.keyList = make([]*cacheEntry, 0)
+	c.logger.Infof("clear cache")
+	if journal := c.journal.Load(); journal != nil {
+		journal.logClear()
+	}
+	// 逐个清空分片，清空期间其他分片仍然可以正常读写
+	for _, s := range c.shards {
+		items, size := s.clear()
+		c.size.Add(-size)
+		c.count.Add(-int64(len(items)))
+		if c.hooks.load() == nil {
+			c.metrics.observeEvict(EvictReasonCleared, int64(len(items)))
+			continue
+		}
+		for _, item := range items {
+			c.fireEvict(item, EvictReasonCleared)
+		}
+	}
+}
+
+// getShard 根据key选择分片
+func (c *Cache) getShard(key string) *shard {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return c.shards[h.Sum64()&c.mask]
 }
 
 // LoadDataFromJson 如果存的值是json格式的字符串，可以通过该方法load到data里
@@ -70,45 +208,134 @@ func (c *Cache) SetDataWithJsonWithExpiration(params string, data interface{}, d
 }
 
 func (c *Cache) SetStringWithExpiration(params string, v string, d time.Duration) {
-	key := NewKeyBuilder().SetParams(params).Build()
 	stringItem := NewStringItem()
 	stringItem.SetString(v)
 	stringItem.SetExpiration(time.Now().Add(d))
-	c.data.Store(key, stringItem.GetItem())
-	c.size += int64(len(v))
-	// 更新keyList
-	c.updateKeyList(key)
-	log.Printf("set cache:%s", key)
+	c.setItem(params, stringItem.GetItem())
+}
 
-	// 给watcher发信号，校验是否超出size限制
-	// 安全检查：只有当 LimitCh 已初始化时才发送信号
-	if LimitCh != nil {
-		select {
-		case LimitCh <- 1:
-		default:
-			// channel 已满，跳过此次通知
-		}
+// SetStringWithTags 写入带标签的字符串，可以通过 InvalidateTag 按标签批量删除
+// d 过期时间，0 表示永不过期
+func (c *Cache) SetStringWithTags(params string, v string, d time.Duration, tags ...string) {
+	stringItem := NewStringItem()
+	stringItem.SetString(v)
+	if d > 0 {
+		stringItem.SetExpiration(time.Now().Add(d))
 	}
+	stringItem.SetTags(tags...)
+	c.setItem(params, stringItem.GetItem())
 }
 
 // SetString 写入cache
 // params 用于生成key的因素
 // v 存入cache的值
 func (c *Cache) SetString(params string, v string) {
-	key := NewKeyBuilder().SetParams(params).Build()
 	stringItem := NewStringItem()
 	stringItem.SetString(v)
 	stringItem.SetExpiration(time.Time{})
-	c.data.Store(key, stringItem.GetItem())
-	c.size += int64(len(v))
-	// 更新keyList
-	c.updateKeyList(key)
-	log.Printf("set cache:%s", key)
+	c.setItem(params, stringItem.GetItem())
+}
+
+// SetValue 写入原生 Go 值，不做 JSON 序列化
+// params 用于生成key的因素
+// v 存入cache的值
+// d 过期时间，0 表示永不过期
+func (c *Cache) SetValue(params string, v any, d time.Duration) error {
+	size, err := c.sizeOf(v)
+	if err != nil {
+		return err
+	}
+	c.setValue(params, v, size, d)
+	return nil
+}
+
+// SetValueWithTags 写入带标签的原生 Go 值，可以通过 InvalidateTag 按标签批量删除
+// d 过期时间，0 表示永不过期
+func (c *Cache) SetValueWithTags(params string, v any, d time.Duration, tags ...string) error {
+	size, err := c.sizeOf(v)
+	if err != nil {
+		return err
+	}
+	c.setValue(params, v, size, d, tags...)
+	return nil
+}
+
+// setValue 写入原生 Go 值，大小由调用方计算
+func (c *Cache) setValue(params string, v any, size int64, d time.Duration, tags ...string) {
+	valueItem := NewValueItem()
+	valueItem.SetValue(v, size)
+	if d > 0 {
+		valueItem.SetExpiration(time.Now().Add(d))
+	}
+	valueItem.SetTags(tags...)
+	c.setItem(params, valueItem.GetItem())
+}
+
+// GetValue 获取原生 Go 值
+// params 用于生成key的因素
+// 返回值的第二个参数表示是否命中，GetOrLoad 缓存的加载错误视为未命中
+func (c *Cache) GetValue(params string) (any, bool) {
+	v, ok := c.getValue(params)
+	if !ok {
+		return nil, false
+	}
+	return loadedValue(v)
+}
+
+// getValue 获取缓存中保存的值，GetOrLoad 写入的数据不解包
+func (c *Cache) getValue(params string) (any, bool) {
+	key := c.buildKey(params)
+	item, ok := c.lookup(key)
+	if !ok {
+		return nil, false
+	}
+	valueItem := NewValueItem()
+	if err := valueItem.Load(item); err != nil {
+		return nil, false
+	}
+	return valueItem.GetValue(), true
+}
+
+// sizeOf 计算值的大小，配置了 Codec 时按编码后的长度计算，否则通过反射估算
+func (c *Cache) sizeOf(v any) (int64, error) {
+	if c.codec == nil {
+		return estimateSize(v), nil
+	}
+	switch val := v.(type) {
+	case string:
+		return int64(len(val)), nil
+	case []byte:
+		return int64(len(val)), nil
+	}
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+// setItem 将缓存项写入cache，并更新大小和淘汰策略
+// params 写入时使用的key，会按key策略生成内部key
+func (c *Cache) setItem(params string, item *Item) {
+	start := time.Now()
+	key := c.buildKey(params)
+	item.key = params
+	var record *cacheRecord
+	if c.journal.Load() != nil {
+		record = c.newSetRecord(key, item)
+	}
+	sizeDelta, countDelta := c.getShard(key).set(key, item, record)
+	c.size.Add(sizeDelta)
+	c.count.Add(countDelta)
+	c.metrics.observeSet(time.Since(start))
+	c.logger.Debugf("set cache:%s", key)
+	c.fireSet(item)
+
 	// 给watcher发信号，校验是否超出size限制
-	// 安全检查：只有当 LimitCh 已初始化时才发送信号
-	if LimitCh != nil {
+	// 安全检查：只有当缓存绑定了watcher时才发送信号
+	if c.limitCh != nil {
 		select {
-		case LimitCh <- 1:
+		case c.limitCh <- 1:
 		default:
 			// channel 已满，跳过此次通知
 		}
@@ -118,106 +345,155 @@ func (c *Cache) SetString(params string, v string) {
 // GetString 获取cache
 // params 用于生成key的因素
 func (c *Cache) GetString(params string) (string, error) {
-	key := NewKeyBuilder().SetParams(params).Build()
-	if v, ok := c.data.Load(key); ok {
-		if item, ok := v.(*Item); ok {
-			stringItem := NewStringItem()
-			err := stringItem.Load(item)
-			if err != nil {
-				return "", err
-			}
-			item.IncreaseAccessCount()
-			return stringItem.GetString(), nil
-		}
+	key := c.buildKey(params)
+	item, ok := c.lookup(key)
+	if !ok {
+		return "", nil
 	}
-	return "", nil
+	stringItem := NewStringItem()
+	if err := stringItem.Load(item); err != nil {
+		return "", err
+	}
+	return stringItem.GetString(), nil
 }
 
 func (c *Cache) Delete(params string) error {
 	// 构建缓存键
-	cacheKey := NewKeyBuilder().SetParams(params).Build()
+	cacheKey := c.buildKey(params)
 
-	// 检查缓存中是否存在该键
-	value, ok := c.data.Load(cacheKey)
+	// 删除缓存中的键值对
+	item, ok := c.getShard(cacheKey).remove(cacheKey, nil)
 	if !ok {
 		return nil
 	}
 
-	// 删除缓存中的键值对
-	c.data.Delete(cacheKey)
-
 	// 更新缓存大小
-	if item, ok := value.(*Item); ok {
-		c.size -= item.size
-	}
+	c.size.Add(-item.GetSize())
+	c.count.Add(-1)
 
-	// 更新keyList
-	c.keyMutex.Lock()
-	defer c.keyMutex.Unlock()
-	for i, entry := range c.keyList {
-		if entry.key == cacheKey {
-			c.keyList = append(c.keyList[:i], c.keyList[i+1:]...)
-			break
-		}
-	}
-
-	log.Printf("deleted cache key: %s", params)
+	c.logger.Debugf("deleted cache key: %s", params)
+	c.fireEvict(item, EvictReasonDeleted)
 	return nil
 }
 
-// 更新keyList，将新的key插入到合适的位置
-func (c *Cache) updateKeyList(key interface{}) {
-	c.keyMutex.Lock()
-	defer c.keyMutex.Unlock()
+// InvalidateTag 删除带有tag标签的所有缓存，返回删除的数量
+func (c *Cache) InvalidateTag(tag string) int {
+	removed := 0
+	for _, s := range c.shards {
+		removed += c.removed(s.removeTag(tag))
+	}
+	return removed
+}
 
-	value, ok := c.data.Load(key)
-	if !ok {
-		return
+// DeletePrefix 删除写入时的key以prefix开头的所有缓存，返回删除的数量
+// 需要遍历所有缓存项，适合低频的批量失效
+func (c *Cache) DeletePrefix(prefix string) int {
+	removed := 0
+	for _, s := range c.shards {
+		removed += c.removed(s.removeIf(func(item *Item) bool {
+			return item.key != "" && strings.HasPrefix(item.key, prefix)
+		}))
 	}
+	return removed
+}
 
-	item := value.(*Item)
-	entry := &cacheEntry{
-		key:          key,
-		accessCount:  item.accessCount,
-		lastUsedTime: item.lastUsedTime,
+// removed 更新批量删除后的统计，返回删除的数量
+func (c *Cache) removed(items map[string]*Item) int {
+	for _, item := range items {
+		c.size.Add(-item.GetSize())
+		c.count.Add(-1)
+		c.fireEvict(item, EvictReasonDeleted)
 	}
+	return len(items)
+}
 
-	// 寻找插入位置
-	insertIndex := -1
-	for i, e := range c.keyList {
-		if e.accessCount < entry.accessCount || (e.accessCount == entry.accessCount && e.lastUsedTime.Before(entry.lastUsedTime)) {
-			insertIndex = i
-			break
+// Keys 遍历所有未过期的key，返回的是写入时使用的key
+// 遍历时逐个分片复制快照，遍历期间的写入不一定可见
+// 只遍历当前key策略下能读取到的数据，HashedDateKey 在之前日期写入的同名key不会重复出现
+func (c *Cache) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for key := range c.All() {
+			if !yield(key) {
+				return
+			}
 		}
 	}
+}
 
-	// 插入新的key
-	if insertIndex == -1 {
-		c.keyList = append(c.keyList, entry)
-	} else {
-		c.keyList = append(c.keyList[:insertIndex], append([]*cacheEntry{entry}, c.keyList[insertIndex:]...)...)
+// All 遍历所有未过期的key和值，不会计入访问次数，GetOrLoad 缓存的加载错误不会遍历到
+// 与 Keys 相同，只遍历当前key策略下能读取到的数据
+func (c *Cache) All() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		now := time.Now()
+		for _, s := range c.shards {
+			for key, item := range s.liveItems(now) {
+				// 内部key随日期等因素变化时，同一个key可能有多份，只返回 GetValue 能读取到的那一份
+				if key != c.buildKey(item.key) {
+					continue
+				}
+				value, ok := loadedValue(item.Get())
+				if !ok {
+					continue
+				}
+				if !yield(item.key, value) {
+					return
+				}
+			}
+		}
 	}
 }
 
-// removeLeastAccessed 根据keyList淘汰缓存数据
-func (c *Cache) removeLeastAccessed() {
-	c.keyMutex.Lock()
-	defer c.keyMutex.Unlock()
-
-	// 淘汰数量为当前缓存的1/3
-	removeCount := len(c.keyList) / 3
+// lookup 读取缓存项，并记录命中率和延迟
+func (c *Cache) lookup(key string) (*Item, bool) {
+	start := time.Now()
+	item, ok := c.getShard(key).get(key)
+	c.metrics.observeGet(ok, time.Since(start))
+	return item, ok
+}
 
-	// 删除最后的n个元素，并更新Cache结构的size值
-	for i := 0; i < removeCount; i++ {
-		entry := c.keyList[len(c.keyList)-1-i]
-		value, ok := c.data.Load(entry.key)
-		if ok {
-			item := value.(*Item)
-			c.size -= item.size
-			c.data.Delete(entry.key)
+// removeExpired 删除所有已过期的key，返回删除的数量
+func (c *Cache) removeExpired(now time.Time) int {
+	removed := 0
+	for _, s := range c.shards {
+		for key, item := range s.collectExpired(now) {
+			c.logger.Debugf("cache expire:%s", key)
+			c.size.Add(-item.GetSize())
+			c.count.Add(-1)
+			c.fireEvict(item, EvictReasonExpired)
+			removed++
 		}
 	}
+	return removed
+}
 
-	// 更新keyList
-	c.keyList = c.keyList[:len(c.keyList)-removeCount]
+// evictUntil 按淘汰策略逐个淘汰key，直到缓存大小不超过maxSize
+// 每个分片由自己的淘汰策略选出候选key，分片之间淘汰最后使用时间最早的候选key
+// 返回淘汰的key数量
+func (c *Cache) evictUntil(maxSize int64) int {
+	c.evictMu.Lock()
+	defer c.evictMu.Unlock()
+
+	count := 0
+	for c.size.Load() > maxSize {
+		// 每个分片按自己的策略选出候选，再按策略的分数和最后使用时间比较
+		var target *shard
+		var best victim
+		for _, s := range c.shards {
+			v, ok := s.peekVictim()
+			if ok && (target == nil || v.before(best)) {
+				target, best = s, v
+			}
+		}
+		if target == nil {
+			break
+		}
+		if key, item, ok := target.evict(); ok {
+			c.size.Add(-item.GetSize())
+			c.count.Add(-1)
+			c.logger.Debugf("cache evict:%s", key)
+			c.fireEvict(item, EvictReasonCapacity)
+			count++
+		}
+	}
+	return count
 }
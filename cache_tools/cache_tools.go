@@ -2,6 +2,8 @@ package cache_tools
 
 import (
 	"fmt"
+	"iter"
+	"sync"
 	"time"
 )
 
@@ -9,43 +11,134 @@ import (
 type CacheManager struct {
 	cache   *Cache
 	watcher *Watcher
+	loads   *loadGroup // 合并GetOrLoad的并发加载
+
+	schedules []scheduleEntry // 定时计划，Init时交给监视器
+	clock     Clock           // 监视器的时间源，为空时使用系统时间
+
+	persist   persistOptions // 持久化配置
+	journal   *appendLog     // 追加日志，未开启时为空
+	stopSave  chan struct{}  // 停止定时快照
+	saveDone  chan struct{}  // 定时快照协程已退出
+	closeOnce sync.Once
 }
 
 // NewCacheManager 创建缓存管理器
 func NewCacheManager() *CacheManager {
 	return &CacheManager{
 		cache: NewCache(),
+		loads: newLoadGroup(),
+	}
+}
+
+// Option 缓存管理器的可选配置
+type Option func(*CacheManager)
+
+// WithEvictionPolicy 设置淘汰策略，默认使用LRU
+// 例如: WithEvictionPolicy(NewLFUPolicy)
+func WithEvictionPolicy(factory PolicyFactory) Option {
+	return func(cm *CacheManager) {
+		cm.cache.SetEvictionPolicy(factory)
+	}
+}
+
+// WithLogger 设置日志，默认只输出错误，传入nil表示不输出日志
+// 例如: WithLogger(NewStdLogger(LogLevelDebug)) 输出每个key的写入、删除和过期
+func WithLogger(logger Logger) Option {
+	return func(cm *CacheManager) {
+		cm.cache.SetLogger(logger)
+	}
+}
+
+// WithKeyStrategy 设置生成内部key的策略，默认使用 HashedDateKey
+// 例如: WithKeyStrategy(RawKey) 直接使用写入时的key，key不会在零点变化
+func WithKeyStrategy(strategy KeyStrategy) Option {
+	return func(cm *CacheManager) {
+		cm.cache.SetKeyStrategy(strategy)
 	}
 }
 
-// InitCacheManager 初始化缓存管理器
+// WithShards 设置缓存分片数量，默认16，会向上取整到2的幂
+// 分片越多并发写入的锁竞争越小，已经注册的回调和设置的编解码器、日志等不受影响
+func WithShards(n int) Option {
+	return func(cm *CacheManager) {
+		cm.cache.SetShards(n)
+	}
+}
+
+// WithSchedule 添加定时计划，可以添加多个
+// 例如: WithSchedule(Every(10*time.Minute), EvictExpired()) 每10分钟清理一次过期缓存
+func WithSchedule(schedule Schedule, action ScheduleAction) Option {
+	return func(cm *CacheManager) {
+		cm.schedules = append(cm.schedules, scheduleEntry{schedule: schedule, action: action})
+	}
+}
+
+// WithClock 设置监视器的时间源，测试时可以传入 NewFakeClock 创建的时间源
+func WithClock(clock Clock) Option {
+	return func(cm *CacheManager) {
+		cm.clock = clock
+	}
+}
+
+// Init 初始化缓存管理器
 // maxSize: 最大缓存大小(字节)
-// clearTime: 定时清理时间，格式: "15:04:05"
-func (cm *CacheManager) Init(maxSize int64, clearTime string) error {
+// clearTime: 定时清空缓存的计划，格式参考 ParseSchedule，例如 "03:00:00"、"0 3 * * *"、"03:00:00;15:00:00"
+// opts: 可选配置，如淘汰策略
+// 重复调用时会先停止之前的监控协程并关闭持久化，定时计划使用本次传入的配置，只有第一次调用时从磁盘恢复数据
+func (cm *CacheManager) Init(maxSize int64, clearTime string, opts ...Option) error {
+	restore := cm.watcher == nil
+	if !restore {
+		if err := cm.Close(); err != nil {
+			return err
+		}
+		cm.closeOnce = sync.Once{}
+	}
+	cm.schedules = nil
+	for _, opt := range opts {
+		opt(cm)
+	}
+
 	cm.watcher = NewWatcher(cm.cache)
 	cm.watcher.SetMaxSize(maxSize)
+	cm.watcher.SetClock(cm.clock)
+	for _, entry := range cm.schedules {
+		cm.watcher.AddSchedule(entry.schedule, entry.action)
+	}
 
 	if clearTime != "" {
-		t, err := time.Parse("15:04:05", clearTime)
+		schedule, err := ParseSchedule(clearTime)
 		if err != nil {
 			return err
 		}
-		cm.watcher.SetClearPlanTime(t.Hour(), t.Minute(), t.Second())
+		cm.watcher.AddSchedule(schedule, ClearAll())
 	}
 
-	// 初始化全局变量
-	GlobalCache = cm.cache
-	GlobalWatcher = cm.watcher
-	LimitCh = make(chan int, 1)
+	// 从快照或追加日志恢复数据
+	if err := cm.openPersistence(maxSize, restore); err != nil {
+		return err
+	}
 
-	// 启动监控协程
-	go cm.watcher.WatchClear()
-	go cm.watcher.WatchLimit()
-	go cm.watcher.WatchExpiration()
+	// 启动监控协程，每个管理器独享自己的监控协程和淘汰信号，互不影响
+	cm.watcher.Start()
 
 	return nil
 }
 
+// Close 停止管理器的监控协程和定时清理计划，可以重复调用
+// 开启了持久化时，会写入最后一次快照并关闭追加日志
+// 关闭后缓存仍然可以读写，但不再自动过期、淘汰和定时清理，也不再持久化
+func (cm *CacheManager) Close() error {
+	if cm.watcher != nil {
+		cm.watcher.Stop()
+	}
+	var err error
+	cm.closeOnce.Do(func() {
+		err = cm.closePersistence()
+	})
+	return err
+}
+
 // SetString 设置字符串缓存
 func (cm *CacheManager) SetString(key, value string) {
 	cm.cache.SetString(key, value)
@@ -56,6 +149,11 @@ func (cm *CacheManager) SetStringWithTTL(key, value string, ttl time.Duration) {
 	cm.cache.SetStringWithExpiration(key, value, ttl)
 }
 
+// SetStringWithTags 设置带标签的字符串缓存，ttl为0表示永不过期
+func (cm *CacheManager) SetStringWithTags(key, value string, ttl time.Duration, tags ...string) {
+	cm.cache.SetStringWithTags(key, value, ttl, tags...)
+}
+
 // GetString 获取字符串缓存
 func (cm *CacheManager) GetString(key string) (string, error) {
 	return cm.cache.GetString(key)
@@ -76,6 +174,31 @@ func (cm *CacheManager) GetJSON(key string, result interface{}) error {
 	return cm.cache.LoadDataFromJson(key, result)
 }
 
+// SetValue 设置原生值缓存，不做序列化
+func (cm *CacheManager) SetValue(key string, value any) error {
+	return cm.cache.SetValue(key, value, 0)
+}
+
+// SetValueWithTTL 设置带过期时间的原生值缓存
+func (cm *CacheManager) SetValueWithTTL(key string, value any, ttl time.Duration) error {
+	return cm.cache.SetValue(key, value, ttl)
+}
+
+// SetValueWithTags 设置带标签的原生值缓存，ttl为0表示永不过期
+func (cm *CacheManager) SetValueWithTags(key string, value any, ttl time.Duration, tags ...string) error {
+	return cm.cache.SetValueWithTags(key, value, ttl, tags...)
+}
+
+// GetValue 获取原生值缓存，第二个返回值表示是否命中
+func (cm *CacheManager) GetValue(key string) (any, bool) {
+	return cm.cache.GetValue(key)
+}
+
+// SetCodec 设置值编解码器，用于大小统计等需要字节表示的场景
+func (cm *CacheManager) SetCodec(codec Codec) {
+	cm.cache.SetCodec(codec)
+}
+
 // Delete 删除缓存
 func (cm *CacheManager) Delete(key string) error {
 	return cm.cache.Delete(key)
@@ -86,27 +209,84 @@ func (cm *CacheManager) Clear() {
 	cm.cache.Clear()
 }
 
+// InvalidateTag 删除带有tag标签的所有缓存，返回删除的数量
+func (cm *CacheManager) InvalidateTag(tag string) int {
+	return cm.cache.InvalidateTag(tag)
+}
+
+// DeletePrefix 删除key以prefix开头的所有缓存，返回删除的数量
+func (cm *CacheManager) DeletePrefix(prefix string) int {
+	return cm.cache.DeletePrefix(prefix)
+}
+
+// Keys 遍历所有未过期的key
+//
+//	for key := range manager.Keys() { ... }
+func (cm *CacheManager) Keys() iter.Seq[string] {
+	return cm.cache.Keys()
+}
+
+// All 遍历所有未过期的key和值，不会计入访问次数
+func (cm *CacheManager) All() iter.Seq2[string, any] {
+	return cm.cache.All()
+}
+
+// OnSet 注册缓存写入后的回调
+func (cm *CacheManager) OnSet(hook SetHook) {
+	cm.cache.OnSet(hook)
+}
+
+// OnEvict 注册缓存项被移除后的回调，包括超出大小限制、过期、删除和清空
+func (cm *CacheManager) OnEvict(hook EvictHook) {
+	cm.cache.OnEvict(hook)
+}
+
+// OnExpire 注册缓存项过期被移除后的回调
+func (cm *CacheManager) OnExpire(hook ExpireHook) {
+	cm.cache.OnExpire(hook)
+}
+
 // Stats 获取缓存统计信息
 func (cm *CacheManager) Stats() CacheStats {
-	return CacheStats{
-		Size:     cm.cache.size,
-		KeyCount: len(cm.cache.keyList),
+	stats := CacheStats{
+		Size:     cm.cache.Size(),
+		KeyCount: cm.cache.Len(),
 	}
+	cm.cache.metrics.fill(&stats)
+	return stats
 }
 
 // CacheStats 缓存统计信息
 type CacheStats struct {
-	Size     int64 // 缓存总大小(字节)
-	KeyCount int   // 缓存key数量
+	Size       int64                 // 缓存总大小(字节)
+	KeyCount   int                   // 缓存key数量
+	Hits       int64                 // 读取命中次数
+	Misses     int64                 // 读取未命中次数
+	Sets       int64                 // 写入次数
+	Evictions  map[EvictReason]int64 // 按原因统计的移除次数
+	GetLatency Histogram             // 读取延迟
+	SetLatency Histogram             // 写入延迟
+}
+
+// HitRate 命中率，没有读取时返回0
+func (s CacheStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
 }
 
 // Global functions for backward compatibility
 var defaultCacheManager *CacheManager
 
-// InitDefault 初始化默认缓存管理器
-func InitDefault(maxSize int64, clearTime string) error {
+// InitDefault 初始化默认缓存管理器，重复调用时会关闭之前的默认管理器
+func InitDefault(maxSize int64, clearTime string, opts ...Option) error {
+	if defaultCacheManager != nil {
+		_ = defaultCacheManager.Close()
+	}
 	defaultCacheManager = NewCacheManager()
-	return defaultCacheManager.Init(maxSize, clearTime)
+	return defaultCacheManager.Init(maxSize, clearTime, opts...)
 }
 
 // Set 设置缓存(使用默认管理器)
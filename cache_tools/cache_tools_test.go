@@ -2,6 +2,7 @@ package cache_tools
 
 import (
 	"fmt"
+	"runtime"
 	"testing"
 	"time"
 )
@@ -287,6 +288,82 @@ func TestDefaultManagerWithTTL(t *testing.T) {
 	}
 }
 
+// 测试多个管理器互相独立
+func TestIndependentManagers(t *testing.T) {
+	small := NewCacheManager()
+	if err := small.Init(20, ""); err != nil {
+		t.Fatalf("Failed to init cache manager: %v", err)
+	}
+	defer small.Close()
+	large := NewCacheManager()
+	if err := large.Init(1024*1024, ""); err != nil {
+		t.Fatalf("Failed to init cache manager: %v", err)
+	}
+	defer large.Close()
+
+	for i := 0; i < 10; i++ {
+		small.SetString(fmt.Sprintf("key_%d", i), "0123456789")
+		large.SetString(fmt.Sprintf("key_%d", i), "0123456789")
+	}
+
+	// small的淘汰信号只会触发small自己的淘汰
+	deadline := time.Now().Add(2 * time.Second)
+	for small.Stats().Size > 20 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if size := small.Stats().Size; size > 20 {
+		t.Errorf("Expected small manager to shrink to 20 bytes, got %d", size)
+	}
+	if count := large.Stats().KeyCount; count != 10 {
+		t.Errorf("Expected large manager to keep 10 keys, got %d", count)
+	}
+
+	// CacheAnything 使用各自管理器的缓存
+	handler := func(params interface{}, results interface{}) error {
+		*(results.(*string)) = params.(string)
+		return nil
+	}
+	var res string
+	if err := small.CacheAnything("anything", handler, "small", &res, time.Minute); err != nil {
+		t.Fatalf("CacheAnything failed: %v", err)
+	}
+	if err := large.CacheAnything("anything", handler, "large", &res, time.Minute); err != nil {
+		t.Fatalf("CacheAnything failed: %v", err)
+	}
+	if res != "large" {
+		t.Errorf("Expected large manager to run its own handler, got %s", res)
+	}
+}
+
+// 测试关闭管理器后监控协程退出
+func TestManagerClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	manager := NewCacheManager()
+	if err := manager.Init(1024*1024, "23:59:59"); err != nil {
+		t.Fatalf("Failed to init cache manager: %v", err)
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Failed to close cache manager: %v", err)
+	}
+	// 重复关闭不应该panic
+	_ = manager.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Expected watcher goroutines to exit, before=%d after=%d", before, after)
+	}
+
+	// 关闭后仍然可以读写
+	manager.SetString("key", "value")
+	if value, _ := manager.GetString("key"); value != "value" {
+		t.Errorf("Expected value after close, got %s", value)
+	}
+}
+
 // 基准测试：字符串设置
 func BenchmarkSetString(b *testing.B) {
 	manager := NewCacheManager()
@@ -0,0 +1,292 @@
+package cache_tools
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Codec 值编解码器
+// 缓存内部保存原生的 Go 值，只有在需要字节表示(大小统计、持久化等)时才会用到 Codec
+type Codec interface {
+	Name() string                       // 编解码器名称
+	Marshal(v any) ([]byte, error)      // 将值编码为字节
+	Unmarshal(data []byte, v any) error // 将字节解码到 v，v 必须是指针
+}
+
+// JSONCodec 基于 encoding/json 的编解码器
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string { return "json" }
+
+func (JSONCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (JSONCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// GobCodec 基于 encoding/gob 的编解码器，适合 Go 服务之间传递结构体
+type GobCodec struct{}
+
+func (GobCodec) Name() string { return "gob" }
+
+func (GobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// BinaryCodec 紧凑的二进制编解码器(类似 msgpack)
+// 支持 bool、整数、浮点数、字符串、[]byte、切片、数组、map、指针以及结构体的导出字段
+// 结构体按字段顺序编码，不写字段名，因此编解码两端的结构体定义必须一致
+type BinaryCodec struct{}
+
+func (BinaryCodec) Name() string { return "binary" }
+
+func (BinaryCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := binaryEncode(&buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (BinaryCodec) Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("binary codec: unmarshal target must be a non-nil pointer")
+	}
+	r := bytes.NewReader(data)
+	if err := binaryDecode(r, rv.Elem()); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("binary codec: %d trailing bytes", r.Len())
+	}
+	return nil
+}
+
+func binaryEncode(buf *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		return errors.New("binary codec: cannot encode nil value")
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.Write(binary.AppendVarint(nil, v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		buf.Write(binary.AppendUvarint(nil, v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v.Float())))
+	case reflect.String:
+		buf.Write(binary.AppendUvarint(nil, uint64(v.Len())))
+		buf.WriteString(v.String())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			buf.Write(binary.AppendUvarint(nil, uint64(v.Len())))
+			buf.Write(v.Bytes())
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		buf.Write(binary.AppendUvarint(nil, uint64(v.Len())))
+		for i := 0; i < v.Len(); i++ {
+			if err := binaryEncode(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		buf.Write(binary.AppendUvarint(nil, uint64(v.Len())))
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := binaryEncode(buf, iter.Key()); err != nil {
+				return err
+			}
+			if err := binaryEncode(buf, iter.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			buf.WriteByte(0)
+			return nil
+		}
+		buf.WriteByte(1)
+		return binaryEncode(buf, v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			if err := binaryEncode(buf, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("binary codec: unsupported kind %s", v.Kind())
+	}
+	return nil
+}
+
+func binaryDecode(r *bytes.Reader, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		v.SetBool(b != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var bits uint64
+		if err := binary.Read(r, binary.BigEndian, &bits); err != nil {
+			return err
+		}
+		v.SetFloat(math.Float64frombits(bits))
+	case reflect.String:
+		b, err := readBinaryBytes(r)
+		if err != nil {
+			return err
+		}
+		v.SetString(string(b))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := readBinaryBytes(r)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		n, err := readBinaryLen(r, binaryEmpty(v.Type().Elem()))
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+		for i := 0; i < n; i++ {
+			if err := binaryDecode(r, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		n, err := readBinaryLen(r, binaryEmpty(v.Type().Elem()))
+		if err != nil {
+			return err
+		}
+		if n != v.Len() {
+			return fmt.Errorf("binary codec: array length mismatch, want %d but get %d", v.Len(), n)
+		}
+		for i := 0; i < n; i++ {
+			if err := binaryDecode(r, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		n, err := readBinaryLen(r, binaryEmpty(v.Type().Key()) && binaryEmpty(v.Type().Elem()))
+		if err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(v.Type(), n)
+		for i := 0; i < n; i++ {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := binaryDecode(r, key); err != nil {
+				return err
+			}
+			val := reflect.New(v.Type().Elem()).Elem()
+			if err := binaryDecode(r, val); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, val)
+		}
+		v.Set(m)
+	case reflect.Pointer:
+		flag, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if flag == 0 {
+			v.SetZero()
+			return nil
+		}
+		elem := reflect.New(v.Type().Elem())
+		if err := binaryDecode(r, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			if err := binaryDecode(r, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("binary codec: unsupported kind %s", v.Kind())
+	}
+	return nil
+}
+
+// readBinaryLen 读取长度，empty 表示元素编码后可能不占字节
+func readBinaryLen(r *bytes.Reader, empty bool) (int, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt || (n > uint64(r.Len()) && !empty) {
+		// 除了编码为空的元素(例如 struct{})，每个元素至少占一个字节，长度不可能超过剩余字节数
+		return 0, fmt.Errorf("binary codec: invalid length %d", n)
+	}
+	return int(n), nil
+}
+
+// binaryEmpty 判断类型编码后是否不占字节，只有没有导出字段(或导出字段都不占字节)的结构体
+func binaryEmpty(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.IsExported() && !binaryEmpty(field.Type) {
+			return false
+		}
+	}
+	return true
+}
+
+func readBinaryBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := readBinaryLen(r, false)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := r.Read(b); err != nil && n > 0 {
+		return nil, err
+	}
+	return b, nil
+}
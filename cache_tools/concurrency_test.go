@@ -0,0 +1,229 @@
+package cache_tools
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// silenceLog 屏蔽缓存写入时的日志，避免高并发测试输出过多
+func silenceLog(tb testing.TB) {
+	log.SetOutput(io.Discard)
+	tb.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+	})
+}
+
+// checkAccounting 校验全局统计和各分片统计一致
+func checkAccounting(t *testing.T, c *Cache) {
+	var size, count int64
+	for _, s := range c.shards {
+		s.mu.Lock()
+		var itemSize int64
+		for _, item := range s.items {
+			itemSize += item.GetSize()
+		}
+		if itemSize != s.size {
+			t.Errorf("shard size %d does not match items size %d", s.size, itemSize)
+		}
+		if s.policy.Len() != len(s.items) {
+			t.Errorf("policy tracks %d keys but shard has %d", s.policy.Len(), len(s.items))
+		}
+		size += s.size
+		count += int64(len(s.items))
+		s.mu.Unlock()
+	}
+	if size != c.Size() {
+		t.Errorf("global size %d does not match shards size %d", c.Size(), size)
+	}
+	if count != int64(c.Len()) {
+		t.Errorf("global count %d does not match shards count %d", c.Len(), count)
+	}
+}
+
+// TestConcurrentAccess 高并发读写、删除、过期、淘汰、清空，配合 go test -race 检查数据竞争
+func TestConcurrentAccess(t *testing.T) {
+	silenceLog(t)
+
+	for _, name := range []string{EvictionLRU, EvictionLFU, EvictionARC} {
+		factory, _ := PolicyFactoryByName(name)
+		cache := NewCacheWithPolicy(factory)
+		watcher := NewWatcher(cache)
+		watcher.SetMaxSize(4 * SizeKB)
+
+		var wg sync.WaitGroup
+		for g := 0; g < 16; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(int64(g)))
+				for i := 0; i < 2000; i++ {
+					key := fmt.Sprintf("key%d", r.Intn(500))
+					switch op := r.Intn(100); {
+					case op < 40:
+						cache.SetString(key, fmt.Sprintf("value%d", i))
+					case op < 50:
+						cache.SetStringWithExpiration(key, "expiring", time.Millisecond)
+					case op < 60:
+						_ = cache.SetValue(key, []int{i, g}, 0)
+					case op < 85:
+						_, _ = cache.GetString(key)
+						_, _ = cache.GetValue(key)
+					case op < 95:
+						_ = cache.Delete(key)
+					case op < 98:
+						watcher.CheckSize()
+					case op < 99:
+						cache.removeExpired(time.Now())
+					default:
+						cache.Clear()
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		checkAccounting(t, cache)
+		watcher.CheckSize()
+		if cache.Size() > 4*SizeKB {
+			t.Errorf("%s: size %d exceeds limit after CheckSize", name, cache.Size())
+		}
+		checkAccounting(t, cache)
+	}
+}
+
+// TestShardCount 测试分片数量向上取整到2的幂
+func TestShardCount(t *testing.T) {
+	for n, want := range map[int]int{0: 1, 1: 1, 3: 4, 16: 16, 17: 32} {
+		if got := NewShardedCache(n, NewLRUPolicy).ShardCount(); got != want {
+			t.Errorf("NewShardedCache(%d) shard count = %d, want %d", n, got, want)
+		}
+	}
+}
+
+// TestWithShardsKeepsConfig 测试修改分片数量不会丢失已经注册的回调、编解码器和数据
+func TestWithShardsKeepsConfig(t *testing.T) {
+	silenceLog(t)
+	manager := NewCacheManager()
+	var sets atomic.Int32
+	manager.OnSet(func(key string, value any) { sets.Add(1) })
+	manager.SetCodec(JSONCodec{})
+	manager.SetString("before", "value")
+	if err := manager.Init(1024*1024, "", WithShards(64)); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	manager.SetString("after", "value")
+	if got := manager.cache.ShardCount(); got != 64 {
+		t.Errorf("Expected 64 shards, got %d", got)
+	}
+	if sets.Load() != 2 {
+		t.Errorf("Expected hook kept, got %d calls", sets.Load())
+	}
+	if _, ok := manager.cache.GetCodec().(JSONCodec); !ok {
+		t.Errorf("Expected codec kept, got %T", manager.cache.GetCodec())
+	}
+	if v, err := manager.GetString("before"); err != nil || v != "value" {
+		t.Errorf("Expected existing key moved to new shards, got %q (%v)", v, err)
+	}
+	checkAccounting(t, manager.cache)
+}
+
+// TestInitStopsPreviousWatcher 测试重复调用 Init 时停止之前的监控协程
+func TestInitStopsPreviousWatcher(t *testing.T) {
+	silenceLog(t)
+	manager := NewCacheManager()
+	defer manager.Close()
+	if err := manager.Init(1024*1024, "03:00:00"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	before := runtime.NumGoroutine()
+	for range 10 {
+		if err := manager.Init(1024*1024, "03:00:00"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Expected no leaked watcher goroutines, got %d before and %d after", before, after)
+	}
+	if n := len(manager.watcher.entries()); n != 1 {
+		t.Errorf("Expected 1 schedule, got %d", n)
+	}
+}
+
+func benchmarkParallel(b *testing.B, shardCount int, readPercent int) {
+	silenceLog(b)
+	cache := NewShardedCache(shardCount, NewLRUPolicy)
+	for i := 0; i < 1000; i++ {
+		cache.SetString(fmt.Sprintf("key%d", i), "value")
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			key := fmt.Sprintf("key%d", r.Intn(1000))
+			if r.Intn(100) < readPercent {
+				_, _ = cache.GetString(key)
+			} else {
+				cache.SetString(key, "value")
+			}
+		}
+	})
+}
+
+// 基准测试：并发写入
+func BenchmarkParallelSet(b *testing.B) {
+	for _, n := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", n), func(b *testing.B) {
+			benchmarkParallel(b, n, 0)
+		})
+	}
+}
+
+// 基准测试：并发读取
+func BenchmarkParallelGet(b *testing.B) {
+	for _, n := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", n), func(b *testing.B) {
+			benchmarkParallel(b, n, 100)
+		})
+	}
+}
+
+// 基准测试：并发读多写少
+func BenchmarkParallelMixed(b *testing.B) {
+	for _, n := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", n), func(b *testing.B) {
+			benchmarkParallel(b, n, 90)
+		})
+	}
+}
+
+// 基准测试：超出大小限制时的并发写入和淘汰
+func BenchmarkParallelSetWithEviction(b *testing.B) {
+	silenceLog(b)
+	cache := NewCache()
+	watcher := NewWatcher(cache)
+	watcher.SetMaxSize(64 * SizeKB)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			cache.SetString(fmt.Sprintf("key%d", r.Intn(100000)), "value")
+			if cache.Size() > 64*SizeKB {
+				watcher.CheckSize()
+			}
+		}
+	})
+}
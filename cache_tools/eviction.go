@@ -0,0 +1,385 @@
+package cache_tools
+
+import (
+	"container/list"
+	"fmt"
+)
+
+const (
+	EvictionLRU = "lru" // 最近最少使用
+	EvictionLFU = "lfu" // 最不经常使用
+	EvictionARC = "arc" // 自适应替换缓存
+)
+
+// EvictionPolicy 淘汰策略
+// 策略只维护key的优先级，真正的数据删除由Cache完成；所有方法都要求O(1)复杂度
+// 策略本身不是并发安全的，由Cache负责加锁
+type EvictionPolicy interface {
+	OnAdd(key string)      // key被写入(新增或覆盖)
+	OnAccess(key string)   // key被读取
+	OnRemove(key string)   // key被删除或过期，key不存在时应直接忽略
+	Evict() (string, bool) // 选出下一个要淘汰的key并从策略中移除，没有可淘汰的key时返回false
+	Peek() (string, bool)  // 返回下一个要淘汰的key，但不从策略中移除
+	Len() int              // 策略中跟踪的key数量
+	Reset()                // 清空策略
+}
+
+// EvictionScorer 淘汰策略可选实现的接口，用于在多个分片之间选择淘汰对象
+// 每个分片先由自己的策略选出候选key，再比较候选key的分数，分数小的先淘汰，分数相同时淘汰最久未使用的
+// 没有实现该接口时所有key的分数相同，分片之间按最近使用时间选择
+type EvictionScorer interface {
+	Score(key string) int64
+}
+
+// PolicyFactory 创建淘汰策略的函数
+type PolicyFactory func() EvictionPolicy
+
+// PolicyFactoryByName 根据名称获取淘汰策略，名称为空时默认使用LRU
+func PolicyFactoryByName(name string) (PolicyFactory, error) {
+	switch name {
+	case "", EvictionLRU:
+		return NewLRUPolicy, nil
+	case EvictionLFU:
+		return NewLFUPolicy, nil
+	case EvictionARC:
+		return NewARCPolicy, nil
+	}
+	return nil, fmt.Errorf("unknown eviction policy: %s", name)
+}
+
+// LRUPolicy 最近最少使用淘汰策略，基于双向链表+map实现
+type LRUPolicy struct {
+	ll    *list.List // 头部是最近使用的key，尾部是最久未使用的key
+	items map[string]*list.Element
+}
+
+// NewLRUPolicy 创建LRU淘汰策略
+func NewLRUPolicy() EvictionPolicy {
+	return &LRUPolicy{
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (p *LRUPolicy) OnAdd(key string) {
+	if e, ok := p.items[key]; ok {
+		p.ll.MoveToFront(e)
+		return
+	}
+	p.items[key] = p.ll.PushFront(key)
+}
+
+func (p *LRUPolicy) OnAccess(key string) {
+	if e, ok := p.items[key]; ok {
+		p.ll.MoveToFront(e)
+	}
+}
+
+func (p *LRUPolicy) OnRemove(key string) {
+	if e, ok := p.items[key]; ok {
+		p.ll.Remove(e)
+		delete(p.items, key)
+	}
+}
+
+func (p *LRUPolicy) Evict() (string, bool) {
+	e := p.ll.Back()
+	if e == nil {
+		return "", false
+	}
+	key := p.ll.Remove(e).(string)
+	delete(p.items, key)
+	return key, true
+}
+
+func (p *LRUPolicy) Peek() (string, bool) {
+	e := p.ll.Back()
+	if e == nil {
+		return "", false
+	}
+	return e.Value.(string), true
+}
+
+func (p *LRUPolicy) Len() int {
+	return len(p.items)
+}
+
+func (p *LRUPolicy) Reset() {
+	p.ll.Init()
+	p.items = make(map[string]*list.Element)
+}
+
+// LFUPolicy 最不经常使用淘汰策略
+// 按访问频率分桶，桶内按最近使用排序，访问频率相同时淘汰最久未使用的key
+type LFUPolicy struct {
+	freqs *list.List // 频率桶链表，按频率从小到大排列，元素类型为*lfuBucket
+	items map[string]*lfuEntry
+}
+
+type lfuBucket struct {
+	freq int64
+	keys *list.List // 头部是最近使用的key
+}
+
+type lfuEntry struct {
+	bucket *list.Element // 所在的频率桶
+	elem   *list.Element // 在桶内的位置
+}
+
+// NewLFUPolicy 创建LFU淘汰策略
+func NewLFUPolicy() EvictionPolicy {
+	return &LFUPolicy{
+		freqs: list.New(),
+		items: make(map[string]*lfuEntry),
+	}
+}
+
+func (p *LFUPolicy) OnAdd(key string) {
+	if _, ok := p.items[key]; ok {
+		p.OnAccess(key)
+		return
+	}
+	front := p.freqs.Front()
+	if front == nil || front.Value.(*lfuBucket).freq != 1 {
+		front = p.freqs.PushFront(&lfuBucket{freq: 1, keys: list.New()})
+	}
+	p.items[key] = &lfuEntry{
+		bucket: front,
+		elem:   front.Value.(*lfuBucket).keys.PushFront(key),
+	}
+}
+
+func (p *LFUPolicy) OnAccess(key string) {
+	entry, ok := p.items[key]
+	if !ok {
+		return
+	}
+	current := entry.bucket.Value.(*lfuBucket)
+	next := entry.bucket.Next()
+	if next == nil || next.Value.(*lfuBucket).freq != current.freq+1 {
+		next = p.freqs.InsertAfter(&lfuBucket{freq: current.freq + 1, keys: list.New()}, entry.bucket)
+	}
+	current.keys.Remove(entry.elem)
+	if current.keys.Len() == 0 {
+		p.freqs.Remove(entry.bucket)
+	}
+	entry.bucket = next
+	entry.elem = next.Value.(*lfuBucket).keys.PushFront(key)
+}
+
+func (p *LFUPolicy) OnRemove(key string) {
+	entry, ok := p.items[key]
+	if !ok {
+		return
+	}
+	p.removeEntry(entry)
+	delete(p.items, key)
+}
+
+func (p *LFUPolicy) Evict() (string, bool) {
+	key, ok := p.Peek()
+	if ok {
+		p.OnRemove(key)
+	}
+	return key, ok
+}
+
+func (p *LFUPolicy) Peek() (string, bool) {
+	front := p.freqs.Front()
+	if front == nil {
+		return "", false
+	}
+	return front.Value.(*lfuBucket).keys.Back().Value.(string), true
+}
+
+// Score 返回key的访问频率，访问越少越先淘汰
+func (p *LFUPolicy) Score(key string) int64 {
+	entry, ok := p.items[key]
+	if !ok {
+		return 0
+	}
+	return entry.bucket.Value.(*lfuBucket).freq
+}
+
+func (p *LFUPolicy) Len() int {
+	return len(p.items)
+}
+
+func (p *LFUPolicy) Reset() {
+	p.freqs.Init()
+	p.items = make(map[string]*lfuEntry)
+}
+
+func (p *LFUPolicy) removeEntry(entry *lfuEntry) {
+	bucket := entry.bucket.Value.(*lfuBucket)
+	bucket.keys.Remove(entry.elem)
+	if bucket.keys.Len() == 0 {
+		p.freqs.Remove(entry.bucket)
+	}
+}
+
+// ARCPolicy 自适应替换缓存(Adaptive Replacement Cache)淘汰策略
+// t1保存只访问过一次的key，t2保存访问过多次的key；b1、b2分别记录最近从t1、t2淘汰的key(只保存key，不保存数据)
+// 命中b1说明应该给t1更多空间，命中b2说明应该给t2更多空间，p是t1的目标大小
+// 本缓存按字节限制大小而不是按条数，因此容量c取当前常驻的key数量
+type ARCPolicy struct {
+	p              int
+	t1, t2, b1, b2 *arcList
+}
+
+// NewARCPolicy 创建ARC淘汰策略
+func NewARCPolicy() EvictionPolicy {
+	return &ARCPolicy{
+		t1: newARCList(),
+		t2: newARCList(),
+		b1: newARCList(),
+		b2: newARCList(),
+	}
+}
+
+func (p *ARCPolicy) OnAdd(key string) {
+	switch {
+	case p.t1.contains(key) || p.t2.contains(key):
+		p.OnAccess(key)
+	case p.b1.contains(key):
+		// 最近刚从t1淘汰又被写入，增大t1的目标大小
+		p.p = min(p.p+max(p.b2.len()/p.b1.len(), 1), p.capacity())
+		p.b1.remove(key)
+		p.t2.pushFront(key)
+	case p.b2.contains(key):
+		// 最近刚从t2淘汰又被写入，减小t1的目标大小
+		p.p = max(p.p-max(p.b1.len()/p.b2.len(), 1), 0)
+		p.b2.remove(key)
+		p.t2.pushFront(key)
+	default:
+		p.t1.pushFront(key)
+	}
+}
+
+func (p *ARCPolicy) OnAccess(key string) {
+	if p.t1.contains(key) {
+		p.t1.remove(key)
+		p.t2.pushFront(key)
+		return
+	}
+	p.t2.moveToFront(key)
+}
+
+func (p *ARCPolicy) OnRemove(key string) {
+	p.t1.remove(key)
+	p.t2.remove(key)
+}
+
+func (p *ARCPolicy) Evict() (string, bool) {
+	var key string
+	if p.evictFromT1() {
+		key = p.t1.popBack()
+		p.b1.pushFront(key)
+	} else if p.t2.len() > 0 {
+		key = p.t2.popBack()
+		p.b2.pushFront(key)
+	} else {
+		return "", false
+	}
+	// 历史记录最多保留和常驻key数量相同的条数
+	for p.b1.len() > p.capacity() {
+		p.b1.popBack()
+	}
+	for p.b2.len() > p.capacity() {
+		p.b2.popBack()
+	}
+	return key, true
+}
+
+func (p *ARCPolicy) Peek() (string, bool) {
+	if p.evictFromT1() {
+		return p.t1.back(), true
+	}
+	if p.t2.len() > 0 {
+		return p.t2.back(), true
+	}
+	return "", false
+}
+
+// Score 只访问过一次的key(t1)为0，访问过多次的key(t2)为1，先淘汰只访问过一次的key
+func (p *ARCPolicy) Score(key string) int64 {
+	if p.t2.contains(key) {
+		return 1
+	}
+	return 0
+}
+
+// evictFromT1 t1超过目标大小(或者t2为空)时从t1淘汰，否则从t2淘汰
+func (p *ARCPolicy) evictFromT1() bool {
+	return p.t1.len() > 0 && (p.t1.len() > p.p || p.t2.len() == 0)
+}
+
+func (p *ARCPolicy) Len() int {
+	return p.t1.len() + p.t2.len()
+}
+
+func (p *ARCPolicy) Reset() {
+	p.p = 0
+	p.t1.reset()
+	p.t2.reset()
+	p.b1.reset()
+	p.b2.reset()
+}
+
+func (p *ARCPolicy) capacity() int {
+	return max(p.Len(), 1)
+}
+
+// arcList ARC内部使用的LRU链表
+type arcList struct {
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+func newARCList() *arcList {
+	return &arcList{
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (l *arcList) len() int {
+	return l.ll.Len()
+}
+
+func (l *arcList) contains(key string) bool {
+	_, ok := l.items[key]
+	return ok
+}
+
+func (l *arcList) pushFront(key string) {
+	l.items[key] = l.ll.PushFront(key)
+}
+
+func (l *arcList) moveToFront(key string) {
+	if e, ok := l.items[key]; ok {
+		l.ll.MoveToFront(e)
+	}
+}
+
+func (l *arcList) remove(key string) {
+	if e, ok := l.items[key]; ok {
+		l.ll.Remove(e)
+		delete(l.items, key)
+	}
+}
+
+func (l *arcList) back() string {
+	return l.ll.Back().Value.(string)
+}
+
+func (l *arcList) popBack() string {
+	key := l.ll.Remove(l.ll.Back()).(string)
+	delete(l.items, key)
+	return key
+}
+
+func (l *arcList) reset() {
+	l.ll.Init()
+	l.items = make(map[string]*list.Element)
+}
@@ -0,0 +1,147 @@
+package cache_tools
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func evictAll(p EvictionPolicy) []string {
+	keys := make([]string, 0)
+	for {
+		key, ok := p.Evict()
+		if !ok {
+			return keys
+		}
+		keys = append(keys, key)
+	}
+}
+
+func TestLRUPolicy(t *testing.T) {
+	p := NewLRUPolicy()
+	p.OnAdd("a")
+	p.OnAdd("b")
+	p.OnAdd("c")
+	p.OnAccess("a")
+	p.OnRemove("b")
+	p.OnRemove("not_exist")
+
+	if p.Len() != 2 {
+		t.Fatalf("Expected 2 keys, got %d", p.Len())
+	}
+	if got := fmt.Sprint(evictAll(p)); got != "[c a]" {
+		t.Errorf("Expected eviction order [c a], got %s", got)
+	}
+}
+
+func TestLFUPolicy(t *testing.T) {
+	p := NewLFUPolicy()
+	p.OnAdd("a")
+	p.OnAdd("b")
+	p.OnAdd("c")
+	p.OnAccess("a")
+	p.OnAccess("a")
+	p.OnAccess("c")
+
+	// b访问1次，c访问2次，a访问3次
+	if got := fmt.Sprint(evictAll(p)); got != "[b c a]" {
+		t.Errorf("Expected eviction order [b c a], got %s", got)
+	}
+
+	// 访问次数相同时淘汰最久未使用的
+	p.OnAdd("x")
+	p.OnAdd("y")
+	if key, _ := p.Evict(); key != "x" {
+		t.Errorf("Expected x to be evicted, got %s", key)
+	}
+}
+
+func TestARCPolicy(t *testing.T) {
+	p := NewARCPolicy()
+	for i := 0; i < 4; i++ {
+		p.OnAdd(fmt.Sprintf("k%d", i))
+	}
+	// k0被再次访问，进入t2
+	p.OnAccess("k0")
+
+	// 只访问过一次的key优先淘汰
+	if key, _ := p.Evict(); key != "k1" {
+		t.Fatalf("Expected k1 to be evicted, got %s", key)
+	}
+	// k1在b1中，再次写入会直接进入t2，并增大t1的目标大小
+	p.OnAdd("k1")
+	if p.Len() != 4 {
+		t.Fatalf("Expected 4 keys, got %d", p.Len())
+	}
+	arc := p.(*ARCPolicy)
+	if arc.p != 1 || !arc.t2.contains("k1") {
+		t.Errorf("Expected ghost hit to adapt target size, p=%d", arc.p)
+	}
+
+	// t1超过目标大小时从t1淘汰，否则从t2淘汰
+	if got := fmt.Sprint(evictAll(p)); got != "[k2 k0 k1 k3]" {
+		t.Errorf("Expected eviction order [k2 k0 k1 k3], got %s", got)
+	}
+}
+
+// 测试多个分片时按策略的分数比较候选，LFU不会退化为跨分片的LRU
+func TestEvictAcrossShardsByScore(t *testing.T) {
+	for _, factory := range []PolicyFactory{NewLFUPolicy, NewARCPolicy} {
+		cache := NewShardedCache(16, factory)
+		cache.SetKeyStrategy(RawKey)
+		hot, cold := "hot", "cold"
+		for i := 0; cache.getShard(hot) == cache.getShard(cold); i++ {
+			cold = fmt.Sprintf("cold%d", i)
+		}
+
+		cache.SetString(hot, "value")
+		for i := 0; i < 5; i++ {
+			cache.GetString(hot)
+		}
+		time.Sleep(time.Millisecond)
+		// cold 最近才写入，但只使用过一次
+		cache.SetString(cold, "value")
+
+		cache.evictUntil(5)
+		if _, err := cache.GetString(hot); err != nil {
+			t.Errorf("%T: expected frequently used key to survive, got %v", factory(), err)
+		}
+		if v, _ := cache.GetString(cold); v != "" {
+			t.Errorf("%T: expected single use key evicted", factory())
+		}
+	}
+}
+
+// 测试超出大小限制时只淘汰到满足限制为止
+func TestEvictUntilMaxSize(t *testing.T) {
+	for _, name := range []string{EvictionLRU, EvictionLFU, EvictionARC} {
+		factory, err := PolicyFactoryByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cache := NewCacheWithPolicy(factory)
+		watcher := NewWatcher(cache)
+		watcher.SetMaxSize(50)
+
+		// 10个key，每个value 10字节，共100字节
+		for i := 0; i < 10; i++ {
+			cache.SetString(fmt.Sprintf("key%d", i), fmt.Sprintf("value%05d", i))
+		}
+		// 热点key
+		for i := 0; i < 3; i++ {
+			cache.GetString("key0")
+		}
+		watcher.CheckSize()
+
+		if cache.Size() != 50 || cache.Len() != 5 {
+			t.Errorf("%s: expected 5 keys with 50 bytes, got %d keys with %d bytes", name, cache.Len(), cache.Size())
+		}
+		if value, _ := cache.GetString("key0"); value == "" {
+			t.Errorf("%s: expected hot key to survive eviction", name)
+		}
+	}
+
+	if _, err := PolicyFactoryByName("fifo"); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
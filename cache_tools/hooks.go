@@ -0,0 +1,102 @@
+package cache_tools
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SetHook 缓存写入后的回调
+// key 为写入时使用的key，value 为写入的值
+type SetHook func(key string, value any)
+
+// EvictHook 缓存项被移除后的回调，reason 为移除的原因
+type EvictHook func(key string, value any, reason EvictReason)
+
+// ExpireHook 缓存项过期被移除后的回调
+type ExpireHook func(key string, value any)
+
+// cacheHooks 注册的回调，写入时复制，读取时不加锁
+type cacheHooks struct {
+	onSet    []SetHook
+	onEvict  []EvictHook
+	onExpire []ExpireHook
+}
+
+// hookRegistry 管理回调的注册
+type hookRegistry struct {
+	mu    sync.Mutex
+	hooks atomic.Pointer[cacheHooks]
+}
+
+// load 返回当前注册的回调，没有注册时返回nil
+func (r *hookRegistry) load() *cacheHooks {
+	return r.hooks.Load()
+}
+
+// update 复制当前的回调并修改
+func (r *hookRegistry) update(fn func(h *cacheHooks)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := &cacheHooks{}
+	if current := r.hooks.Load(); current != nil {
+		*next = *current
+		next.onSet = append([]SetHook(nil), current.onSet...)
+		next.onEvict = append([]EvictHook(nil), current.onEvict...)
+		next.onExpire = append([]ExpireHook(nil), current.onExpire...)
+	}
+	fn(next)
+	r.hooks.Store(next)
+}
+
+// OnSet 注册缓存写入后的回调
+// 回调在写入的协程中同步执行，耗时的操作请在回调中另起协程
+func (c *Cache) OnSet(hook SetHook) {
+	c.hooks.update(func(h *cacheHooks) {
+		h.onSet = append(h.onSet, hook)
+	})
+}
+
+// OnEvict 注册缓存项被移除后的回调，包括超出大小限制、过期、删除和清空
+func (c *Cache) OnEvict(hook EvictHook) {
+	c.hooks.update(func(h *cacheHooks) {
+		h.onEvict = append(h.onEvict, hook)
+	})
+}
+
+// OnExpire 注册缓存项过期被移除后的回调
+func (c *Cache) OnExpire(hook ExpireHook) {
+	c.hooks.update(func(h *cacheHooks) {
+		h.onExpire = append(h.onExpire, hook)
+	})
+}
+
+// fireSet 执行写入回调，回调使用写入时的key
+func (c *Cache) fireSet(item *Item) {
+	h := c.hooks.load()
+	if h == nil {
+		return
+	}
+	value, _ := loadedValue(item.Get())
+	for _, hook := range h.onSet {
+		hook(item.key, value)
+	}
+}
+
+// fireEvict 记录移除统计并执行回调
+func (c *Cache) fireEvict(item *Item, reason EvictReason) {
+	c.metrics.observeEvict(reason, 1)
+	h := c.hooks.load()
+	if h == nil {
+		return
+	}
+	key := item.key
+	value, _ := loadedValue(item.Get())
+	if reason == EvictReasonExpired {
+		for _, hook := range h.onExpire {
+			hook(key, value)
+		}
+	}
+	for _, hook := range h.onEvict {
+		hook(key, value, reason)
+	}
+}
@@ -1,41 +1,47 @@
 package cache_tools
 
-import (
-	"time"
-)
-
+// 以下全局变量只用于兼容旧的 Init/CacheAnything 接口
+// 新代码请使用 NewCacheManager 创建独立的缓存实例
 var GlobalCache *Cache     // 全局缓存
 var GlobalWatcher *Watcher // 全局缓存监控
-var LimitCh chan int       // 监听是否触发LRU的channel
+var LimitCh chan int       // 全局缓存监控的淘汰信号channel，仅供读取
 
 type GetSwitch func() bool
 
 type Config struct {
 	MaxSize  int64
-	PlanTime string //定时清理缓存的时间，格式: HH:MM:SS
+	PlanTime string //定时清理缓存的计划，格式: HH:MM:SS，也支持cron表达式，参考 ParseSchedule
+	Eviction string //淘汰策略: lru(默认)、lfu、arc
+	Logger   Logger //日志，为空时只输出错误
 }
 
-// Init 显示调用
+// Init 显示调用，初始化全局缓存
+// 重复调用时会停止之前的全局监控协程
 func Init(c Config) error {
-	GlobalCache = NewCache()
-	GlobalWatcher = NewWatcher(GlobalCache)
-	GlobalWatcher.SetMaxSize(c.MaxSize)
-	LimitCh = make(chan int, 1)
+	factory, err := PolicyFactoryByName(c.Eviction)
+	if err != nil {
+		return err
+	}
 	//每天7点清理
-	t, err := time.Parse("15:04:05", c.PlanTime)
+	schedule, err := ParseSchedule(c.PlanTime)
 	if err != nil {
 		return err
 	}
-	GlobalWatcher.SetClearPlanTime(t.Hour(), t.Minute(), t.Second())
-
-	// 启动协程定时清理缓存
-	go GlobalWatcher.WatchClear()
+	if GlobalWatcher != nil {
+		GlobalWatcher.Stop()
+	}
 
-	// 启动协程监听是否需要LRU
-	go GlobalWatcher.WatchLimit()
+	GlobalCache = NewCacheWithPolicy(factory)
+	if c.Logger != nil {
+		GlobalCache.SetLogger(c.Logger)
+	}
+	GlobalWatcher = NewWatcher(GlobalCache)
+	GlobalWatcher.SetMaxSize(c.MaxSize)
+	GlobalWatcher.AddSchedule(schedule, ClearAll())
+	LimitCh = GlobalWatcher.limitCh
 
-	// 启动协程点听是否过期
-	go GlobalWatcher.WatchExpiration()
+	// 启动协程定时清理缓存、监听是否需要淘汰、监听是否过期
+	GlobalWatcher.Start()
 
 	return nil
 }
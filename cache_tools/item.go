@@ -1,6 +1,7 @@
 package cache_tools
 
 import (
+	"sync/atomic"
 	"time"
 )
 
@@ -10,8 +11,10 @@ type Item struct {
 	valueType    string      // 缓存项值的类型
 	lastUsedTime time.Time   // 缓存项最后一次使用的时间
 	size         int64       // 缓存项的大小（字节）
-	accessCount  int64       // 缓存项的访问次数
+	accessCount  int64       // 缓存项的访问次数，使用原子操作读写
 	expiration   time.Time   // 缓存项的过期时间
+	key          string      // 写入时使用的key，用于按前缀删除和遍历
+	tags         []string    // 缓存项的标签，用于按标签批量删除
 }
 
 // NewItem 创建一个带有默认字段值的新缓存项
@@ -74,5 +77,30 @@ func (i *Item) SetSize(s int64) {
 
 // IncreaseAccessCount 增加缓存项的访问次数
 func (i *Item) IncreaseAccessCount() {
-	i.accessCount++
+	atomic.AddInt64(&i.accessCount, 1)
+}
+
+// GetAccessCount 返回缓存项的访问次数
+func (i *Item) GetAccessCount() int64 {
+	return atomic.LoadInt64(&i.accessCount)
+}
+
+// IsExpired 判断缓存项在t时刻是否已过期，没有设置过期时间的永不过期
+func (i *Item) IsExpired(t time.Time) bool {
+	return !i.expiration.IsZero() && i.expiration.Before(t)
+}
+
+// GetKey 返回写入时使用的key
+func (i *Item) GetKey() string {
+	return i.key
+}
+
+// GetTags 返回缓存项的标签
+func (i *Item) GetTags() []string {
+	return i.tags
+}
+
+// SetTags 设置缓存项的标签，需要在写入缓存之前设置
+func (i *Item) SetTags(tags ...string) {
+	i.tags = tags
 }
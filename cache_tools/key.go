@@ -7,10 +7,31 @@ import (
 	"time"
 )
 
+// KeyStrategy 根据写入时使用的key生成缓存内部使用的key
+type KeyStrategy func(params string) string
+
+// RawKey 直接使用写入时的key
+func RawKey(params string) string {
+	return params
+}
+
+// HashedKey 使用key的md5值，适合key很长的场景
+func HashedKey(params string) string {
+	md5Hash := md5.Sum([]byte(params))
+	return hex.EncodeToString(md5Hash[:])
+}
+
+// HashedDateKey 使用key的md5值加上当天的日期，格式: md5_YYYYMMDD
+// 每天零点所有key都会变化，相当于每天清空一次缓存，这是缓存默认的策略
+func HashedDateKey(params string) string {
+	return fmt.Sprintf("%s_%s", HashedKey(params), time.Now().Format("20060102"))
+}
+
 type KeyBuilder struct {
 	Params         string //需要参与编码的参数，是一个json字符串
 	Key            string
 	LastUpdateTime time.Time
+	Strategy       KeyStrategy //生成key的策略，为空时使用 HashedDateKey
 }
 
 func NewKeyBuilder() *KeyBuilder {
@@ -22,14 +43,21 @@ func (k *KeyBuilder) SetParams(params string) *KeyBuilder {
 	return k
 }
 
+// SetStrategy 设置生成key的策略
+func (k *KeyBuilder) SetStrategy(strategy KeyStrategy) *KeyBuilder {
+	k.Strategy = strategy
+	return k
+}
+
 func (k *KeyBuilder) Build() string {
 	if k.Params == "" {
 		return ""
 	}
-	md5Hash := md5.Sum([]byte(k.Params))
-	md5Str := hex.EncodeToString(md5Hash[:])
+	strategy := k.Strategy
+	if strategy == nil {
+		strategy = HashedDateKey
+	}
 	k.LastUpdateTime = time.Now()
-	timeStr := k.LastUpdateTime.Format("20060102")
-	k.Key = fmt.Sprintf("%s_%s", md5Str, timeStr)
+	k.Key = strategy(k.Params)
 	return k.Key
 }
@@ -0,0 +1,169 @@
+package cache_tools
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试key策略
+func TestKeyStrategy(t *testing.T) {
+	if got := NewKeyBuilder().SetParams("user:42").SetStrategy(RawKey).Build(); got != "user:42" {
+		t.Errorf("Expected raw key, got %s", got)
+	}
+	hashed := NewKeyBuilder().SetParams("user:42").SetStrategy(HashedKey).Build()
+	if len(hashed) != 32 {
+		t.Errorf("Expected md5 hex key, got %s", hashed)
+	}
+	// 默认策略保持原来的格式
+	if got := NewKeyBuilder().SetParams("user:42").Build(); got != hashed+"_"+time.Now().Format("20060102") {
+		t.Errorf("Expected hashed key with date, got %s", got)
+	}
+
+	manager := NewCacheManager()
+	if err := manager.Init(1024*1024, "", WithKeyStrategy(RawKey)); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	manager.SetString("user:42", "Alice")
+	if _, ok := manager.cache.getShard("user:42").items["user:42"]; !ok {
+		t.Error("Expected raw key to be used internally")
+	}
+}
+
+// 测试按标签删除
+func TestInvalidateTag(t *testing.T) {
+	silenceLog(t)
+	manager := newLoaderTestManager(t)
+
+	manager.SetStringWithTags("user:42:profile", "p", 0, "user:42")
+	if err := manager.SetValueWithTags("user:42:orders", []int{1, 2}, time.Hour, "user:42", "orders"); err != nil {
+		t.Fatal(err)
+	}
+	manager.SetStringWithTags("user:7:profile", "p", 0, "user:7")
+	// 重新写入时旧的标签被替换
+	manager.SetStringWithTags("user:7:orders", "o", 0, "user:42")
+	manager.SetStringWithTags("user:7:orders", "o", 0, "user:7")
+
+	if n := manager.InvalidateTag("user:42"); n != 2 {
+		t.Errorf("Expected 2 keys removed, got %d", n)
+	}
+	if v, _ := manager.GetString("user:42:profile"); v != "" {
+		t.Error("Expected tagged key to be removed")
+	}
+	if _, ok := manager.GetValue("user:42:orders"); ok {
+		t.Error("Expected tagged value to be removed")
+	}
+	if v, _ := manager.GetString("user:7:orders"); v != "o" {
+		t.Error("Expected retagged key to be kept")
+	}
+	if n := manager.InvalidateTag("user:42"); n != 0 {
+		t.Errorf("Expected tag index to be empty, got %d", n)
+	}
+	if stats := manager.Stats(); stats.KeyCount != 2 || stats.Evictions[EvictReasonDeleted] != 2 {
+		t.Errorf("Unexpected stats after invalidation: %+v", stats)
+	}
+	checkAccounting(t, manager.cache)
+}
+
+// 测试按前缀删除和遍历
+func TestDeletePrefixAndKeys(t *testing.T) {
+	silenceLog(t)
+	manager := newLoaderTestManager(t)
+
+	users := NewTypedCache[int, string](manager, "user")
+	orders := NewTypedCache[int, string](manager, "order")
+	for i := 0; i < 3; i++ {
+		_ = users.Set(i, "u")
+		_ = orders.Set(i, "o")
+	}
+	manager.SetStringWithTTL("expired", "v", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	keys := slices.Sorted(manager.Keys())
+	want := []string{"order:0", "order:1", "order:2", "user:0", "user:1", "user:2"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("Expected keys %v, got %v", want, keys)
+	}
+
+	if n := users.DeleteAll(); n != 3 {
+		t.Errorf("Expected 3 keys removed, got %d", n)
+	}
+	all := maps.Collect(manager.All())
+	if len(all) != 3 || all["order:1"] != "o" {
+		t.Errorf("Expected only orders left, got %v", all)
+	}
+
+	// 提前结束遍历
+	count := 0
+	for range manager.Keys() {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Expected iteration to stop after first key, got %d", count)
+	}
+	checkAccounting(t, manager.cache)
+}
+
+// 测试内部key随日期变化时，同一个key在不同日期写入的数据只遍历当前能读取的一份
+func TestKeysAcrossDays(t *testing.T) {
+	silenceLog(t)
+	day := "day1"
+	manager := NewCacheManager()
+	if err := manager.Init(1024*1024, "", WithKeyStrategy(func(params string) string {
+		return day + ":" + params
+	})); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	manager.SetString("user", "old")
+	manager.SetString("yesterday", "v")
+	day = "day2"
+	manager.SetString("user", "new")
+
+	if n := manager.Stats().KeyCount; n != 3 {
+		t.Fatalf("Expected 3 internal keys, got %d", n)
+	}
+	all := maps.Collect(manager.All())
+	if len(all) != 1 || all["user"] != "new" {
+		t.Errorf("Expected only current day entries, got %v", all)
+	}
+	if keys := slices.Collect(manager.Keys()); !slices.Equal(keys, []string{"user"}) {
+		t.Errorf("Expected [user], got %v", keys)
+	}
+}
+
+// 测试快照保存原始key和标签，key策略变化后仍然可以恢复
+func TestSnapshotKeepsKeysAndTags(t *testing.T) {
+	silenceLog(t)
+	source := newLoaderTestManager(t)
+	source.SetStringWithTags("user:1", "Alice", 0, "team:a")
+
+	var buf bytes.Buffer
+	if err := source.SaveSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	target := NewCacheManager()
+	if err := target.Init(1024*1024, "", WithKeyStrategy(RawKey)); err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	if err := target.LoadSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := target.GetString("user:1"); v != "Alice" {
+		t.Errorf("Expected Alice, got %q", v)
+	}
+	if keys := slices.Collect(target.Keys()); len(keys) != 1 || !strings.HasPrefix(keys[0], "user:") {
+		t.Errorf("Expected original key, got %v", keys)
+	}
+	if n := target.InvalidateTag("team:a"); n != 1 {
+		t.Errorf("Expected restored tag to be indexed, got %d", n)
+	}
+}
@@ -0,0 +1,235 @@
+package cache_tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Loader 缓存未命中时加载数据的函数
+type Loader func(ctx context.Context) (any, error)
+
+// LoadOption GetOrLoad 的可选配置
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	negativeTTL time.Duration // 加载失败时错误的缓存时间，0表示不缓存错误
+	staleTTL    time.Duration // 过期后仍可返回旧值的时间窗口，0表示不返回旧值
+	tags        []string      // 写入缓存时的标签
+}
+
+// WithNegativeTTL 缓存加载失败的错误，在ttl内再次获取时直接返回该错误，避免反复调用失败的loader
+func WithNegativeTTL(ttl time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithStaleWhileRevalidate 缓存过期后的window时间内，先返回旧值，同时由一个协程在后台刷新
+func WithStaleWhileRevalidate(window time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.staleTTL = window
+	}
+}
+
+// WithTags 加载成功后写入缓存时带上标签，之后可以通过 InvalidateTag 批量删除
+func WithTags(tags ...string) LoadOption {
+	return func(o *loadOptions) {
+		o.tags = tags
+	}
+}
+
+// loadEntry GetOrLoad 写入缓存的数据
+type loadEntry struct {
+	value      any
+	err        error
+	freshUntil time.Time // 在此之前数据是新鲜的，零值表示永不过期
+}
+
+func (e *loadEntry) isFresh(now time.Time) bool {
+	return e.freshUntil.IsZero() || now.Before(e.freshUntil)
+}
+
+// loadedValue 返回 GetOrLoad 写入的数据中加载的值，其他值原样返回，缓存的加载错误返回false
+func loadedValue(v any) (any, bool) {
+	if entry, ok := v.(*loadEntry); ok {
+		return entry.value, entry.err == nil
+	}
+	return v, true
+}
+
+// GetOrLoad 获取缓存，未命中时调用loader加载并写入缓存
+// 同一个key的并发加载会被合并，loader只会执行一次，其余调用等待并共享结果
+// ctx取消时当前调用立即返回ctx的错误；所有等待的调用都取消后，传给loader的ctx也会被取消
+// 从快照恢复的数据不知道加载时的类型，会重新调用loader，需要直接使用恢复的数据时使用 GetOrLoadAs
+// ttl: 缓存时间，0表示永不过期
+func (cm *CacheManager) GetOrLoad(ctx context.Context, key string, loader Loader, ttl time.Duration, opts ...LoadOption) (any, error) {
+	options := &loadOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if v, ok := cm.cache.getValue(key); ok {
+		entry, ok := v.(*loadEntry)
+		if !ok {
+			if _, ok := v.(*EncodedValue); !ok {
+				// 通过其他接口写入的原生值，直接返回
+				return v, nil
+			}
+			// 从快照恢复的数据不知道原来的类型，重新加载
+			return cm.loads.do(ctx, key, cm.loadFunc(key, loader, ttl, options))
+		}
+		if entry.isFresh(time.Now()) {
+			return entry.value, entry.err
+		}
+		if options.staleTTL > 0 && entry.err == nil {
+			// 返回旧值，后台刷新
+			if !cm.loads.loading(key) {
+				go func() {
+					_, _ = cm.loads.do(context.WithoutCancel(ctx), key, cm.loadFunc(key, loader, ttl, options))
+				}()
+			}
+			return entry.value, nil
+		}
+	}
+
+	return cm.loads.do(ctx, key, cm.loadFunc(key, loader, ttl, options))
+}
+
+// GetOrLoadAs 类型安全的 GetOrLoad，从快照恢复的数据解码为V后返回，不需要重新加载
+func GetOrLoadAs[V any](ctx context.Context, manager *CacheManager, key string, loader func(ctx context.Context) (V, error), ttl time.Duration, opts ...LoadOption) (V, error) {
+	var zero V
+	if v, ok := manager.cache.getValue(key); ok {
+		if encoded, ok := v.(*EncodedValue); ok {
+			if result, ok := resolveValue[V](manager.cache, key, encoded); ok {
+				return result, nil
+			}
+		}
+	}
+	v, err := manager.GetOrLoad(ctx, key, func(ctx context.Context) (any, error) {
+		return loader(ctx)
+	}, ttl, opts...)
+	if err != nil {
+		return zero, err
+	}
+	result, ok := resolveValue[V](manager.cache, key, v)
+	if !ok {
+		return zero, fmt.Errorf("cache value of key %s is %T, not %T", key, v, zero)
+	}
+	return result, nil
+}
+
+// loadFunc 包装loader，加载成功后写入缓存
+func (cm *CacheManager) loadFunc(key string, loader Loader, ttl time.Duration, options *loadOptions) Loader {
+	return func(ctx context.Context) (any, error) {
+		value, err := loader(ctx)
+		now := time.Now()
+		if err != nil {
+			if options.negativeTTL > 0 && ctx.Err() == nil {
+				entry := &loadEntry{err: err, freshUntil: now.Add(options.negativeTTL)}
+				cm.cache.setValue(key, entry, 0, options.negativeTTL)
+			}
+			return nil, err
+		}
+
+		size, sizeErr := cm.cache.sizeOf(value)
+		if sizeErr != nil {
+			return value, nil
+		}
+		entry := &loadEntry{value: value}
+		expire := time.Duration(0)
+		if ttl > 0 {
+			entry.freshUntil = now.Add(ttl)
+			expire = ttl + options.staleTTL
+		}
+		cm.cache.setValue(key, entry, size, expire, options.tags...)
+		return value, nil
+	}
+}
+
+// loadGroup 合并同一个key的并发加载
+type loadGroup struct {
+	mu    sync.Mutex
+	calls map[string]*loadCall
+}
+
+type loadCall struct {
+	done    chan struct{}
+	value   any
+	err     error
+	waiters int                // 正在等待结果的调用数量
+	cancel  context.CancelFunc // 取消loader的ctx
+}
+
+func newLoadGroup() *loadGroup {
+	return &loadGroup{
+		calls: make(map[string]*loadCall),
+	}
+}
+
+// do 执行加载，同一个key同一时间只有一个loader在运行
+func (g *loadGroup) do(ctx context.Context, key string, loader Loader) (any, error) {
+	g.mu.Lock()
+	call, ok := g.calls[key]
+	if ok {
+		call.waiters++
+		g.mu.Unlock()
+		return g.wait(ctx, key, call)
+	}
+
+	// loader不跟随某一个调用方的取消，只有所有调用方都放弃等待时才取消
+	loadCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	call = &loadCall{
+		done:    make(chan struct{}),
+		waiters: 1,
+		cancel:  cancel,
+	}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				call.err = fmt.Errorf("cache loader panic: %v", r)
+			}
+			g.mu.Lock()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+			cancel()
+			close(call.done)
+		}()
+		call.value, call.err = loader(loadCtx)
+	}()
+
+	return g.wait(ctx, key, call)
+}
+
+// loading 判断key是否正在加载
+func (g *loadGroup) loading(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.calls[key]
+	return ok
+}
+
+func (g *loadGroup) wait(ctx context.Context, key string, call *loadCall) (any, error) {
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// 没有人等待结果了，取消loader，之后的调用重新发起加载
+			call.cancel()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
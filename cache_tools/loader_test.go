@@ -0,0 +1,217 @@
+package cache_tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newLoaderTestManager(t *testing.T) *CacheManager {
+	manager := NewCacheManager()
+	if err := manager.Init(1024*1024, ""); err != nil {
+		t.Fatalf("Failed to init cache manager: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = manager.Close()
+	})
+	return manager
+}
+
+// 测试并发获取同一个冷key时loader只执行一次
+func TestGetOrLoadDeduplicates(t *testing.T) {
+	silenceLog(t)
+	manager := newLoaderTestManager(t)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 500)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := GetOrLoadAs(context.Background(), manager, "cold", loader, time.Minute)
+			if err != nil {
+				t.Errorf("GetOrLoad failed: %v", err)
+			}
+			results[i] = v
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected loader to run once, ran %d times", n)
+	}
+	for _, v := range results {
+		if v != 42 {
+			t.Fatalf("Expected 42, got %d", v)
+		}
+	}
+
+	// 之后直接命中缓存
+	if v, _ := GetOrLoadAs(context.Background(), manager, "cold", loader, time.Minute); v != 42 || calls.Load() != 1 {
+		t.Errorf("Expected cache hit, got %d with %d calls", v, calls.Load())
+	}
+}
+
+// 测试ctx取消
+func TestGetOrLoadContextCancel(t *testing.T) {
+	manager := newLoaderTestManager(t)
+
+	loaderCanceled := make(chan struct{})
+	loader := func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		close(loaderCanceled)
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := manager.GetOrLoad(ctx, "slow", loader, time.Minute)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+
+	// 唯一的调用方放弃后loader的ctx也被取消
+	select {
+	case <-loaderCanceled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected loader context to be canceled")
+	}
+}
+
+// 测试缓存错误
+func TestGetOrLoadNegativeTTL(t *testing.T) {
+	manager := newLoaderTestManager(t)
+
+	var calls atomic.Int32
+	errBackend := errors.New("backend down")
+	loader := func(ctx context.Context) (any, error) {
+		calls.Add(1)
+		return nil, errBackend
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := manager.GetOrLoad(context.Background(), "broken", loader, time.Minute, WithNegativeTTL(100*time.Millisecond))
+		if !errors.Is(err, errBackend) {
+			t.Fatalf("Expected backend error, got %v", err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected error to be cached, loader ran %d times", n)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	_, _ = manager.GetOrLoad(context.Background(), "broken", loader, time.Minute, WithNegativeTTL(100*time.Millisecond))
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Expected loader to run again after negative ttl, ran %d times", n)
+	}
+}
+
+// 测试过期后返回旧值并在后台刷新
+func TestGetOrLoadStaleWhileRevalidate(t *testing.T) {
+	silenceLog(t)
+	manager := newLoaderTestManager(t)
+
+	var version atomic.Int32
+	refreshed := make(chan struct{}, 10)
+	loader := func(ctx context.Context) (any, error) {
+		v := version.Add(1)
+		if v > 1 {
+			refreshed <- struct{}{}
+		}
+		return v, nil
+	}
+	opt := WithStaleWhileRevalidate(time.Minute)
+
+	if v, _ := manager.GetOrLoad(context.Background(), "swr", loader, 50*time.Millisecond, opt); v != int32(1) {
+		t.Fatalf("Expected version 1, got %v", v)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// 已过期但在窗口内，立即返回旧值
+	if v, _ := manager.GetOrLoad(context.Background(), "swr", loader, 50*time.Millisecond, opt); v != int32(1) {
+		t.Fatalf("Expected stale version 1, got %v", v)
+	}
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected background refresh")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if v, _ := manager.GetOrLoad(context.Background(), "swr", loader, 50*time.Millisecond, opt); v == int32(2) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("Expected refreshed version 2")
+}
+
+// 测试 GetOrLoad 写入的数据通过 GetValue、All 读取时返回加载的值，快照恢复后解码为原来的类型
+func TestGetOrLoadValueVisibility(t *testing.T) {
+	silenceLog(t)
+	manager := newLoaderTestManager(t)
+	ctx := context.Background()
+
+	if _, err := manager.GetOrLoad(ctx, "user", func(ctx context.Context) (any, error) {
+		return &typedTestUser{ID: 7, Name: "Bob"}, nil
+	}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = manager.GetOrLoad(ctx, "failed", func(ctx context.Context) (any, error) {
+		return nil, errors.New("boom")
+	}, time.Hour, WithNegativeTTL(time.Hour))
+
+	if v, ok := manager.GetValue("user"); !ok || v.(*typedTestUser).Name != "Bob" {
+		t.Errorf("Expected loaded value, got %T %v", v, ok)
+	}
+	if v, ok := manager.GetValue("failed"); ok {
+		t.Errorf("Expected cached error not returned as value, got %v", v)
+	}
+	for key, v := range manager.All() {
+		if _, ok := v.(*typedTestUser); !ok || key != "user" {
+			t.Errorf("Unexpected entry %s %T", key, v)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := manager.SaveSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	restored := newLoaderTestManager(t)
+	if err := restored.LoadSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	user, err := GetOrLoadAs(ctx, restored, "user", func(ctx context.Context) (*typedTestUser, error) {
+		return nil, errors.New("unexpected load")
+	}, time.Hour)
+	if err != nil || user.Name != "Bob" {
+		t.Errorf("Expected restored value decoded, got %+v %v", user, err)
+	}
+
+	// 非泛型接口不知道原来的类型，重新加载
+	buf.Reset()
+	_ = manager.SaveSnapshot(&buf)
+	restored = newLoaderTestManager(t)
+	_ = restored.LoadSnapshot(&buf)
+	v, err := restored.GetOrLoad(ctx, "user", func(ctx context.Context) (any, error) {
+		return &typedTestUser{ID: 7, Name: "Reloaded"}, nil
+	}, time.Hour)
+	if u, ok := v.(*typedTestUser); err != nil || !ok || u.Name != "Reloaded" {
+		t.Errorf("Expected restored value reloaded, got %T %v", v, err)
+	}
+}
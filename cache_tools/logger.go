@@ -0,0 +1,58 @@
+package cache_tools
+
+import (
+	"fmt"
+	"log"
+)
+
+// Logger 缓存使用的日志接口，logrus.Logger、logrus.Entry 可以直接使用
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// LogLevel 日志级别
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota // 输出写入、删除、过期等每个key的操作
+	LogLevelInfo                  // 输出清空等整个缓存的操作
+	LogLevelError                 // 只输出错误
+	LogLevelOff                   // 不输出
+)
+
+// stdLogger 使用标准库log输出日志
+type stdLogger struct {
+	level LogLevel
+}
+
+// NewStdLogger 创建使用标准库log输出的日志，低于level的日志不输出
+// 缓存默认使用 NewStdLogger(LogLevelError)，只输出错误
+func NewStdLogger(level LogLevel) Logger {
+	return &stdLogger{level: level}
+}
+
+// NopLogger 不输出任何日志
+func NopLogger() Logger {
+	return &stdLogger{level: LogLevelOff}
+}
+
+func (l *stdLogger) Debugf(format string, args ...any) {
+	l.output(LogLevelDebug, "DEBUG", format, args...)
+}
+
+func (l *stdLogger) Infof(format string, args ...any) {
+	l.output(LogLevelInfo, "INFO", format, args...)
+}
+
+func (l *stdLogger) Errorf(format string, args ...any) {
+	l.output(LogLevelError, "ERROR", format, args...)
+}
+
+func (l *stdLogger) output(level LogLevel, prefix string, format string, args ...any) {
+	if level < l.level {
+		return
+	}
+	_ = log.Output(3, fmt.Sprintf("[cache_tools] "+prefix+" "+format, args...))
+}
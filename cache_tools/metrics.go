@@ -0,0 +1,117 @@
+package cache_tools
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// EvictReason 缓存项被移除的原因
+type EvictReason string
+
+const (
+	EvictReasonCapacity EvictReason = "capacity" // 超出大小限制被淘汰
+	EvictReasonExpired  EvictReason = "expired"  // 过期
+	EvictReasonDeleted  EvictReason = "deleted"  // 被主动删除
+	EvictReasonCleared  EvictReason = "cleared"  // 清空缓存
+)
+
+// evictReasons 所有的移除原因，用于按固定顺序输出统计
+var evictReasons = []EvictReason{EvictReasonCapacity, EvictReasonExpired, EvictReasonDeleted, EvictReasonCleared}
+
+// latencyBuckets 延迟直方图的桶上限，单位秒
+var latencyBuckets = [...]float64{
+	0.000001, 0.0000025, 0.000005, 0.00001, 0.000025, 0.00005,
+	0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01,
+}
+
+// histogram 并发安全的延迟直方图
+type histogram struct {
+	counts [len(latencyBuckets) + 1]atomic.Int64 // 最后一个桶是 +Inf
+	sum    atomic.Int64                          // 纳秒
+}
+
+func (h *histogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	i := 0
+	for i < len(latencyBuckets) && seconds > latencyBuckets[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+func (h *histogram) snapshot() Histogram {
+	snapshot := Histogram{
+		Bounds:  append([]float64(nil), latencyBuckets[:]...),
+		Buckets: make([]int64, len(latencyBuckets)+1),
+		Sum:     time.Duration(h.sum.Load()),
+	}
+	for i := range snapshot.Buckets {
+		n := h.counts[i].Load()
+		snapshot.Buckets[i] = n
+		snapshot.Count += n
+	}
+	return snapshot
+}
+
+// Histogram 延迟直方图的统计结果
+type Histogram struct {
+	Bounds  []float64     // 每个桶的上限，单位秒
+	Buckets []int64       // 每个桶的数量(不累加)，比 Bounds 多一个，最后一个是超出所有上限的数量
+	Count   int64         // 总次数
+	Sum     time.Duration // 总耗时
+}
+
+// Mean 平均耗时
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// cacheMetrics 缓存的运行统计
+type cacheMetrics struct {
+	hits       atomic.Int64
+	misses     atomic.Int64
+	sets       atomic.Int64
+	evictions  [4]atomic.Int64 // 与 evictReasons 对应
+	getLatency histogram
+	setLatency histogram
+}
+
+func (m *cacheMetrics) observeGet(hit bool, d time.Duration) {
+	if hit {
+		m.hits.Add(1)
+	} else {
+		m.misses.Add(1)
+	}
+	m.getLatency.observe(d)
+}
+
+func (m *cacheMetrics) observeSet(d time.Duration) {
+	m.sets.Add(1)
+	m.setLatency.observe(d)
+}
+
+func (m *cacheMetrics) observeEvict(reason EvictReason, n int64) {
+	for i, r := range evictReasons {
+		if r == reason {
+			m.evictions[i].Add(n)
+			return
+		}
+	}
+}
+
+// fill 将统计结果写入stats
+func (m *cacheMetrics) fill(stats *CacheStats) {
+	stats.Hits = m.hits.Load()
+	stats.Misses = m.misses.Load()
+	stats.Sets = m.sets.Load()
+	stats.Evictions = make(map[EvictReason]int64, len(evictReasons))
+	for i, reason := range evictReasons {
+		stats.Evictions[reason] = m.evictions[i].Load()
+	}
+	stats.GetLatency = m.getLatency.snapshot()
+	stats.SetLatency = m.setLatency.snapshot()
+}
@@ -0,0 +1,146 @@
+package cache_tools
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 测试命中率和按原因统计的移除次数
+func TestCacheStatsCounters(t *testing.T) {
+	manager := newLoaderTestManager(t)
+
+	manager.SetString("a", "1")
+	manager.SetString("b", "2")
+	_, _ = manager.GetString("a")
+	_, _ = manager.GetString("missing")
+	_, _ = manager.GetValue("missing")
+	_ = manager.Delete("a")
+	manager.SetStringWithTTL("c", "3", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	manager.cache.removeExpired(time.Now())
+	manager.Clear()
+
+	stats := manager.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Sets != 3 {
+		t.Errorf("Unexpected counters: hits=%d misses=%d sets=%d", stats.Hits, stats.Misses, stats.Sets)
+	}
+	if rate := stats.HitRate(); rate < 0.33 || rate > 0.34 {
+		t.Errorf("Expected hit rate 1/3, got %v", rate)
+	}
+	want := map[EvictReason]int64{
+		EvictReasonDeleted:  1,
+		EvictReasonExpired:  1,
+		EvictReasonCleared:  1,
+		EvictReasonCapacity: 0,
+	}
+	for reason, n := range want {
+		if stats.Evictions[reason] != n {
+			t.Errorf("Expected %d evictions for %s, got %d", n, reason, stats.Evictions[reason])
+		}
+	}
+	if stats.GetLatency.Count != 3 || stats.SetLatency.Count != 3 {
+		t.Errorf("Expected 3 get and 3 set observations, got %d and %d", stats.GetLatency.Count, stats.SetLatency.Count)
+	}
+	if len(stats.GetLatency.Buckets) != len(stats.GetLatency.Bounds)+1 {
+		t.Errorf("Expected one more bucket than bounds")
+	}
+}
+
+// 测试写入、淘汰、过期回调
+func TestCacheHooks(t *testing.T) {
+	cache := NewCache()
+	cache.SetLogger(nil)
+	watcher := NewWatcher(cache)
+	watcher.SetMaxSize(10)
+
+	var mu sync.Mutex
+	var events []string
+	record := func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, fmt.Sprintf(format, args...))
+	}
+	cache.OnSet(func(key string, value any) {
+		record("set %v", value)
+	})
+	cache.OnEvict(func(key string, value any, reason EvictReason) {
+		record("evict %v %s", value, reason)
+	})
+	cache.OnExpire(func(key string, value any) {
+		record("expire %v", value)
+	})
+
+	cache.SetString("k1", "aaaaaaaa")
+	time.Sleep(time.Millisecond)
+	cache.SetString("k2", "bbbbbbbb")
+	watcher.CheckSize()
+	cache.SetStringWithExpiration("k3", "c", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	cache.removeExpired(time.Now())
+	_ = cache.Delete("k2")
+
+	want := []string{
+		"set aaaaaaaa",
+		"set bbbbbbbb",
+		"evict aaaaaaaa capacity",
+		"set c",
+		"expire c",
+		"evict c expired",
+		"evict bbbbbbbb deleted",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("Expected events %v, got %v", want, events)
+	}
+}
+
+// 测试日志级别
+func TestStdLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	silenceLog(t)
+	log.SetOutput(&buf)
+
+	logger := NewStdLogger(LogLevelInfo)
+	logger.Debugf("debug %d", 1)
+	logger.Infof("info %d", 2)
+	logger.Errorf("error %d", 3)
+	NopLogger().Errorf("nop")
+
+	out := buf.String()
+	if strings.Contains(out, "debug 1") || strings.Contains(out, "nop") {
+		t.Errorf("Expected debug and nop logs to be dropped, got %q", out)
+	}
+	if !strings.Contains(out, "INFO info 2") || !strings.Contains(out, "ERROR error 3") {
+		t.Errorf("Expected info and error logs, got %q", out)
+	}
+}
+
+// 测试Prometheus文本格式输出
+func TestPrometheusHandler(t *testing.T) {
+	manager := newLoaderTestManager(t)
+	manager.SetString("a", "1")
+	_, _ = manager.GetString("a")
+
+	recorder := httptest.NewRecorder()
+	PrometheusHandler(map[string]*CacheManager{"users": manager}).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"# TYPE cache_hits_total counter",
+		`cache_hits_total{cache="users"} 1`,
+		`cache_keys{cache="users"} 1`,
+		`cache_evictions_total{cache="users",reason="capacity"} 0`,
+		"# TYPE cache_get_duration_seconds histogram",
+		`cache_get_duration_seconds_bucket{cache="users",le="+Inf"} 1`,
+		`cache_get_duration_seconds_count{cache="users"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected line %q in output:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,716 @@
+package cache_tools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// snapshotMagic 快照文件头
+const snapshotMagic = "GTCACHE1"
+
+// maxRecordSize 单条记录的最大长度，超过则认为文件损坏
+const maxRecordSize = 1 << 30
+
+// maxReplayAccess 恢复时最多回放的访问次数，用于恢复LFU等策略的访问频率
+const maxReplayAccess = 32
+
+const (
+	recordSet    byte = 1
+	recordDelete byte = 2
+	recordClear  byte = 3
+)
+
+var errCorruptRecord = errors.New("cache snapshot: corrupt record")
+
+// EncodedValue 从快照恢复、尚未解码的原生值
+// 通过 TypedCache、GetValue[V] 等泛型接口读取时会自动解码并替换为原生值；
+// 通过非泛型接口读取时返回该类型，可以调用 Decode 手动解码
+type EncodedValue struct {
+	data  []byte
+	codec Codec
+}
+
+// Decode 将编码值解码到v，v必须是指针
+func (e *EncodedValue) Decode(v any) error {
+	return e.codec.Unmarshal(e.data, v)
+}
+
+// Bytes 返回编码后的字节
+func (e *EncodedValue) Bytes() []byte {
+	return e.data
+}
+
+// Codec 返回编码时使用的编解码器
+func (e *EncodedValue) Codec() Codec {
+	return e.codec
+}
+
+// cacheRecord 快照和追加日志中的一条记录
+type cacheRecord struct {
+	op          byte
+	key         string
+	valueType   string
+	codec       string
+	data        []byte
+	expiration  time.Time
+	lastUsed    time.Time
+	accessCount int64
+	params      string   // 写入时使用的key
+	tags        []string // 缓存项的标签
+	value       any      // 编码前的缓存值，不写入文件
+}
+
+// SaveSnapshot 将缓存中未过期的数据写入w
+// 字符串直接保存，原生值使用缓存的Codec编码(未设置时使用GobCodec)，无法编码的值会被跳过
+func (c *Cache) SaveSnapshot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	for _, record := range c.snapshotRecords() {
+		if err := writeRecord(bw, record); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// snapshotRecords 收集缓存中未过期的数据并编码
+func (c *Cache) snapshotRecords() []*cacheRecord {
+	now := time.Now()
+	records := make([]*cacheRecord, 0, c.Len())
+	for _, s := range c.shards {
+		for _, record := range s.records(now) {
+			if err := c.encodeRecordValue(record); err != nil {
+				c.logger.Errorf("cache snapshot skip key %s: %v", record.key, err)
+				continue
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// LoadSnapshot 从r读取快照并写入缓存，已过期的数据会被跳过
+// maxSize 大于0时，加载后按淘汰策略淘汰到不超过maxSize，最近使用的数据优先保留
+func (c *Cache) LoadSnapshot(r io.Reader, maxSize int64) error {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return fmt.Errorf("cache snapshot: read header failed: %w", err)
+	}
+	if string(magic) != snapshotMagic {
+		return errors.New("cache snapshot: invalid header")
+	}
+	records := make(map[string]*cacheRecord)
+	if err := readRecords(br, records, nil); err != nil {
+		return err
+	}
+	return c.restore(records, maxSize)
+}
+
+// restore 将记录写入缓存
+func (c *Cache) restore(records map[string]*cacheRecord, maxSize int64) error {
+	now := time.Now()
+	list := make([]*cacheRecord, 0, len(records))
+	for _, record := range records {
+		if !record.expiration.IsZero() && record.expiration.Before(now) {
+			continue
+		}
+		list = append(list, record)
+	}
+	// 按最后使用时间从早到晚写入，恢复淘汰策略中的先后顺序
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].lastUsed.Before(list[j].lastUsed)
+	})
+
+	for _, record := range list {
+		item, err := c.decodeRecord(record)
+		if err != nil {
+			return err
+		}
+		// 有原始key时按当前的key策略重新生成内部key，key策略变化后仍然可以命中
+		key := record.key
+		if record.params != "" {
+			key = c.buildKey(record.params)
+		}
+		sizeDelta, countDelta := c.getShard(key).restore(key, item, min(record.accessCount, maxReplayAccess))
+		c.size.Add(sizeDelta)
+		c.count.Add(countDelta)
+	}
+	if maxSize > 0 {
+		c.evictUntil(maxSize)
+	}
+	return nil
+}
+
+// encodeRecordValue 将记录中的缓存值编码为字节
+func (c *Cache) encodeRecordValue(record *cacheRecord) error {
+	switch record.valueType {
+	case TypeString:
+		str, _ := record.value.(string)
+		record.data = []byte(str)
+		return nil
+	case TypeValue:
+		v := record.value
+		if entry, ok := v.(*loadEntry); ok {
+			if entry.err != nil {
+				return errors.New("load error is not persisted")
+			}
+			v = entry.value
+		}
+		if encoded, ok := v.(*EncodedValue); ok {
+			record.data, record.codec = encoded.data, encoded.codec.Name()
+			return nil
+		}
+		codec := c.recordCodec()
+		data, err := codec.Marshal(v)
+		if err != nil {
+			return err
+		}
+		record.data, record.codec = data, codec.Name()
+		return nil
+	}
+	return fmt.Errorf("unknown value type %s", record.valueType)
+}
+
+// decodeRecord 将记录还原为缓存项
+func (c *Cache) decodeRecord(record *cacheRecord) (*Item, error) {
+	item := NewItem()
+	item.SetValueType(record.valueType)
+	item.SetExpiration(record.expiration)
+	item.lastUsedTime = record.lastUsed
+	item.accessCount = record.accessCount
+	item.SetSize(int64(len(record.data)))
+	item.key = record.params
+	item.SetTags(record.tags...)
+	switch record.valueType {
+	case TypeString:
+		item.Set(string(record.data))
+	case TypeValue:
+		codec, err := c.codecByName(record.codec)
+		if err != nil {
+			return nil, err
+		}
+		item.Set(&EncodedValue{data: record.data, codec: codec})
+	default:
+		return nil, fmt.Errorf("cache snapshot: unknown value type %s", record.valueType)
+	}
+	return item, nil
+}
+
+// recordCodec 编码原生值使用的编解码器
+func (c *Cache) recordCodec() Codec {
+	if c.codec != nil {
+		return c.codec
+	}
+	return GobCodec{}
+}
+
+// codecByName 根据名称查找编解码器，优先使用缓存当前的Codec
+func (c *Cache) codecByName(name string) (Codec, error) {
+	if c.codec != nil && c.codec.Name() == name {
+		return c.codec, nil
+	}
+	for _, codec := range []Codec{JSONCodec{}, GobCodec{}, BinaryCodec{}} {
+		if codec.Name() == name {
+			return codec, nil
+		}
+	}
+	return nil, fmt.Errorf("cache snapshot: unknown codec %s", name)
+}
+
+// replaceEncoded 将已解码的值替换快照中的编码值，之后的读取不再需要解码
+func (c *Cache) replaceEncoded(params string, encoded *EncodedValue, value any) {
+	key := c.buildKey(params)
+	c.getShard(key).replaceEncoded(key, encoded, value)
+}
+
+// resolveValue 将缓存值转换为V，快照恢复的编码值会被解码
+func resolveValue[V any](c *Cache, params string, v any) (V, bool) {
+	if result, ok := v.(V); ok {
+		return result, true
+	}
+	var zero V
+	encoded, ok := v.(*EncodedValue)
+	if !ok {
+		return zero, false
+	}
+	var result V
+	if err := encoded.Decode(&result); err != nil {
+		return zero, false
+	}
+	c.replaceEncoded(params, encoded, result)
+	return result, true
+}
+
+// writeRecord 写入一条记录: 长度(uvarint) + crc32 + 内容
+func writeRecord(w io.Writer, record *cacheRecord) error {
+	var payload bytes.Buffer
+	payload.WriteByte(record.op)
+	writeRecordBytes(&payload, []byte(record.key))
+	writeRecordBytes(&payload, []byte(record.valueType))
+	writeRecordBytes(&payload, []byte(record.codec))
+	writeRecordBytes(&payload, record.data)
+	payload.Write(binary.AppendVarint(nil, unixNano(record.expiration)))
+	payload.Write(binary.AppendVarint(nil, unixNano(record.lastUsed)))
+	payload.Write(binary.AppendVarint(nil, record.accessCount))
+	writeRecordBytes(&payload, []byte(record.params))
+	payload.Write(binary.AppendUvarint(nil, uint64(len(record.tags))))
+	for _, tag := range record.tags {
+		writeRecordBytes(&payload, []byte(tag))
+	}
+
+	header := binary.AppendUvarint(nil, uint64(payload.Len()))
+	header = binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(payload.Bytes()))
+	// 拼接成一次写入，追加日志模式下避免写入半条记录
+	_, err := w.Write(append(header, payload.Bytes()...))
+	return err
+}
+
+// readRecords 读取所有记录，后面的记录覆盖前面的
+// onTornTail 不为空时，末尾不完整或损坏的记录会被忽略并回调onTornTail(追加日志在写入过程中进程退出的情况)
+func readRecords(r *bufio.Reader, records map[string]*cacheRecord, onTornTail func(err error)) error {
+	for {
+		record, err := readRecord(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if onTornTail != nil {
+				onTornTail(err)
+				return nil
+			}
+			return err
+		}
+		switch record.op {
+		case recordSet:
+			records[record.key] = record
+		case recordDelete:
+			delete(records, record.key)
+		case recordClear:
+			clear(records)
+		default:
+			return errCorruptRecord
+		}
+	}
+}
+
+func readRecord(r *bufio.Reader) (*cacheRecord, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errCorruptRecord
+	}
+	if length > maxRecordSize {
+		return nil, errCorruptRecord
+	}
+	var checksum uint32
+	if err := binary.Read(r, binary.BigEndian, &checksum); err != nil {
+		return nil, errCorruptRecord
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, errCorruptRecord
+	}
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, errCorruptRecord
+	}
+
+	p := bytes.NewReader(payload)
+	record := &cacheRecord{}
+	if record.op, err = p.ReadByte(); err != nil {
+		return nil, errCorruptRecord
+	}
+	var key, valueType, codec []byte
+	for _, field := range []*[]byte{&key, &valueType, &codec, &record.data} {
+		if *field, err = readRecordBytes(p); err != nil {
+			return nil, errCorruptRecord
+		}
+	}
+	record.key, record.valueType, record.codec = string(key), string(valueType), string(codec)
+	var expiration, lastUsed int64
+	for _, field := range []*int64{&expiration, &lastUsed, &record.accessCount} {
+		if *field, err = binary.ReadVarint(p); err != nil {
+			return nil, errCorruptRecord
+		}
+	}
+	record.expiration, record.lastUsed = fromUnixNano(expiration), fromUnixNano(lastUsed)
+
+	params, err := readRecordBytes(p)
+	if err != nil {
+		return nil, errCorruptRecord
+	}
+	record.params = string(params)
+	tagCount, err := binary.ReadUvarint(p)
+	if err != nil || tagCount > uint64(p.Len()) {
+		return nil, errCorruptRecord
+	}
+	for i := uint64(0); i < tagCount; i++ {
+		tag, err := readRecordBytes(p)
+		if err != nil {
+			return nil, errCorruptRecord
+		}
+		record.tags = append(record.tags, string(tag))
+	}
+	return record, nil
+}
+
+func writeRecordBytes(buf *bytes.Buffer, b []byte) {
+	buf.Write(binary.AppendUvarint(nil, uint64(len(b))))
+	buf.Write(b)
+}
+
+func readRecordBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.Len()) {
+		return nil, errCorruptRecord
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// appendLog 追加日志，每次写入、删除、清空都追加一条记录，重启时按顺序回放
+// 记录在分片锁内写入，保证日志中的顺序和内存中的修改顺序一致
+type appendLog struct {
+	mu         sync.Mutex
+	compactMu  sync.Mutex // 同一时间只允许一个压缩
+	path       string
+	file       *os.File
+	logger     Logger
+	compacting bool           // 是否正在压缩
+	pending    []*cacheRecord // 压缩期间写入的记录，压缩完成后追加到新日志末尾
+}
+
+func openAppendLog(path string, logger Logger) (*appendLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &appendLog{path: path, file: file, logger: logger}, nil
+}
+
+func (l *appendLog) write(record *cacheRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+	if l.compacting {
+		l.pending = append(l.pending, record)
+	}
+	if err := writeRecord(l.file, record); err != nil {
+		l.logger.Errorf("cache log write failed: %v", err)
+	}
+}
+
+func (l *appendLog) logDelete(key string) {
+	l.write(&cacheRecord{op: recordDelete, key: key})
+}
+
+func (l *appendLog) logClear() {
+	l.write(&cacheRecord{op: recordClear})
+}
+
+// compact 用当前缓存的快照重写日志，去掉被覆盖和删除的记录
+// 压缩期间的写入会同时记录下来，追加到新日志的末尾，不会丢失
+func (l *appendLog) compact(c *Cache) error {
+	l.compactMu.Lock()
+	defer l.compactMu.Unlock()
+
+	l.mu.Lock()
+	l.compacting = true
+	l.pending = nil
+	l.mu.Unlock()
+
+	records := c.snapshotRecords()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pending := l.pending
+	l.compacting = false
+	l.pending = nil
+	if l.file == nil {
+		return nil
+	}
+
+	err := writeFileAtomic(l.path, func(w io.Writer) error {
+		for _, record := range append(records, pending...) {
+			if err := writeRecord(w, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_ = l.file.Close()
+	l.file = file
+	return nil
+}
+
+func (l *appendLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// newSetRecord 为写入的缓存项生成日志记录，需要在缓存项写入分片之前调用
+func (c *Cache) newSetRecord(key string, item *Item) *cacheRecord {
+	record := &cacheRecord{
+		op:          recordSet,
+		key:         key,
+		valueType:   item.GetValueType(),
+		expiration:  item.GetExpiration(),
+		lastUsed:    item.GetLastUsedTime(),
+		accessCount: item.GetAccessCount(),
+		params:      item.GetKey(),
+		tags:        item.GetTags(),
+		value:       item.Get(),
+	}
+	if err := c.encodeRecordValue(record); err != nil {
+		// 写入删除记录，避免回放时恢复这个key之前的值
+		c.logger.Errorf("cache log skip key %s: %v", key, err)
+		return &cacheRecord{op: recordDelete, key: key}
+	}
+	return record
+}
+
+// attachJournal 开启追加日志
+func (c *Cache) attachJournal(journal *appendLog) {
+	c.journal.Store(journal)
+	for _, s := range c.shards {
+		s.mu.Lock()
+		s.journal = journal
+		s.mu.Unlock()
+	}
+}
+
+// replayLog 回放追加日志文件，返回日志文件是否存在
+func (c *Cache) replayLog(path string, maxSize int64) (bool, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	records := make(map[string]*cacheRecord)
+	onTornTail := func(err error) {
+		c.logger.Errorf("cache log: ignore broken tail: %v", err)
+	}
+	if err := readRecords(bufio.NewReader(file), records, onTornTail); err != nil {
+		return true, err
+	}
+	return true, c.restore(records, maxSize)
+}
+
+// saveSnapshotFile 将快照写入文件
+func (c *Cache) saveSnapshotFile(path string) error {
+	return writeFileAtomic(path, c.SaveSnapshot)
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免写入过程中崩溃导致文件损坏
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	bw := bufio.NewWriter(tmp)
+	if err := write(bw); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadSnapshotFile 从文件加载快照，文件不存在时直接返回
+func (c *Cache) loadSnapshotFile(path string, maxSize int64) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return c.LoadSnapshot(file, maxSize)
+}
+
+// persistOptions 缓存管理器的持久化配置
+type persistOptions struct {
+	snapshotPath     string        // 快照文件路径
+	snapshotInterval time.Duration // 定时快照的间隔，0表示只在关闭时写入
+	logPath          string        // 追加日志文件路径
+}
+
+// WithSnapshotFile 开启快照文件持久化
+// Init 时从path加载快照，之后每隔interval写入一次快照，Close 时再写入一次
+// interval 为0时只在 Close 时写入
+func WithSnapshotFile(path string, interval time.Duration) Option {
+	return func(cm *CacheManager) {
+		cm.persist.snapshotPath = path
+		cm.persist.snapshotInterval = interval
+	}
+}
+
+// WithAppendLog 开启追加日志持久化
+// 每次写入、删除、清空都追加到path，Init 时回放日志恢复数据并压缩日志
+// 和 WithSnapshotFile 同时使用时，日志文件存在则只从日志恢复
+func WithAppendLog(path string) Option {
+	return func(cm *CacheManager) {
+		cm.persist.logPath = path
+	}
+}
+
+// SaveSnapshot 将缓存中未过期的数据写入w
+func (cm *CacheManager) SaveSnapshot(w io.Writer) error {
+	return cm.cache.SaveSnapshot(w)
+}
+
+// LoadSnapshot 从r加载快照，已过期的数据会被跳过，加载后的大小不超过 Init 时设置的maxSize
+func (cm *CacheManager) LoadSnapshot(r io.Reader) error {
+	var maxSize int64
+	if cm.watcher != nil {
+		maxSize = cm.watcher.maxSize
+	}
+	return cm.cache.LoadSnapshot(r, maxSize)
+}
+
+// CompactLog 压缩追加日志，去掉被覆盖和删除的记录
+func (cm *CacheManager) CompactLog() error {
+	if cm.journal == nil {
+		return errors.New("append log is not enabled")
+	}
+	return cm.journal.compact(cm.cache)
+}
+
+// openPersistence 开启持久化，restore 为true时先从追加日志或快照恢复数据
+func (cm *CacheManager) openPersistence(maxSize int64, restore bool) error {
+	restored := !restore
+	if path := cm.persist.logPath; path != "" {
+		if restore {
+			found, err := cm.cache.replayLog(path, maxSize)
+			if err != nil {
+				return fmt.Errorf("replay cache log failed: %w", err)
+			}
+			restored = found
+		}
+
+		journal, err := openAppendLog(path, cm.cache.logger)
+		if err != nil {
+			return err
+		}
+		// 回放后立即压缩，去掉日志中过期、被覆盖和被淘汰的记录
+		if err := journal.compact(cm.cache); err != nil {
+			_ = journal.close()
+			return fmt.Errorf("compact cache log failed: %w", err)
+		}
+		cm.journal = journal
+		cm.cache.attachJournal(journal)
+	}
+
+	if path := cm.persist.snapshotPath; path != "" {
+		if !restored {
+			if err := cm.cache.loadSnapshotFile(path, maxSize); err != nil {
+				return fmt.Errorf("load cache snapshot failed: %w", err)
+			}
+		}
+		if cm.persist.snapshotInterval > 0 {
+			cm.stopSave = make(chan struct{})
+			cm.saveDone = make(chan struct{})
+			go cm.saveSnapshotLoop(path, cm.persist.snapshotInterval, cm.stopSave, cm.saveDone)
+		}
+	}
+	return nil
+}
+
+// saveSnapshotLoop 定时写入快照
+func (cm *CacheManager) saveSnapshotLoop(path string, interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := cm.cache.saveSnapshotFile(path); err != nil {
+				cm.cache.logger.Errorf("save cache snapshot failed: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// closePersistence 停止定时快照，写入最后一次快照并关闭追加日志
+func (cm *CacheManager) closePersistence() error {
+	if cm.stopSave != nil {
+		close(cm.stopSave)
+		<-cm.saveDone
+		cm.stopSave, cm.saveDone = nil, nil
+	}
+	var errs []error
+	if path := cm.persist.snapshotPath; path != "" {
+		errs = append(errs, cm.cache.saveSnapshotFile(path))
+	}
+	if cm.journal != nil {
+		cm.cache.attachJournal(nil)
+		errs = append(errs, cm.journal.close())
+		cm.journal = nil
+	}
+	return errors.Join(errs...)
+}
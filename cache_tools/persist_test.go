@@ -0,0 +1,317 @@
+package cache_tools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newPersistTestManager(t *testing.T, maxSize int64, opts ...Option) *CacheManager {
+	manager := NewCacheManager()
+	if err := manager.Init(maxSize, "", opts...); err != nil {
+		t.Fatalf("Failed to init cache manager: %v", err)
+	}
+	return manager
+}
+
+// 测试快照的保存和加载
+func TestSnapshotRoundTrip(t *testing.T) {
+	silenceLog(t)
+	source := newPersistTestManager(t, 1024*1024)
+	defer source.Close()
+
+	source.SetString("name", "Alice")
+	source.SetStringWithTTL("ttl", "value", time.Hour)
+	source.SetStringWithTTL("expired", "value", 50*time.Millisecond)
+	user := &typedTestUser{ID: 7, Name: "Bob", Tags: []string{"ops"}}
+	if err := SetValue(source, "user", user, 0); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		_, _ = source.GetString("name")
+	}
+	nameKey := NewKeyBuilder().SetParams("name").Build()
+	accessCount := source.cache.getShard(nameKey).items[nameKey].GetAccessCount()
+	time.Sleep(100 * time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := source.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	target := newPersistTestManager(t, 1024*1024)
+	defer target.Close()
+	if err := target.LoadSnapshot(&buf); err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+
+	if v, err := target.GetString("name"); err != nil || v != "Alice" {
+		t.Errorf("Expected Alice, got %q (%v)", v, err)
+	}
+	if v, _ := target.GetString("expired"); v != "" {
+		t.Error("Expected expired key to be skipped")
+	}
+
+	key := NewKeyBuilder().SetParams("ttl").Build()
+	item, ok := target.cache.getShard(key).get(key)
+	if !ok {
+		t.Fatal("Expected ttl key to be restored")
+	}
+	if remaining := time.Until(item.GetExpiration()); remaining < 50*time.Minute || remaining > time.Hour {
+		t.Errorf("Expected expiration to be preserved, remaining %v", remaining)
+	}
+
+	item, _ = target.cache.getShard(nameKey).get(nameKey)
+	// 恢复后又读取了两次
+	if item.GetAccessCount() != accessCount+2 {
+		t.Errorf("Expected access count %d, got %d", accessCount+2, item.GetAccessCount())
+	}
+
+	// 原生值恢复为编码值，泛型接口读取时解码
+	restored, ok := GetValue[*typedTestUser](target, "user")
+	if !ok || restored.ID != 7 || restored.Name != "Bob" || len(restored.Tags) != 1 {
+		t.Fatalf("Expected restored user, got %+v", restored)
+	}
+	// 指针相同说明编码值已被替换为解码后的值
+	if v, _ := target.GetValue("user"); v != any(restored) {
+		t.Errorf("Expected decoded value to replace encoded value, got %T", v)
+	}
+}
+
+// 测试加载快照时遵守最大缓存限制，优先保留最近使用的数据
+func TestSnapshotRespectsMaxSize(t *testing.T) {
+	silenceLog(t)
+	source := newPersistTestManager(t, 1024*1024)
+	defer source.Close()
+	for _, key := range []string{"k0", "k1", "k2", "k3"} {
+		source.SetString(key, "0123456789")
+		time.Sleep(time.Millisecond)
+	}
+	_, _ = source.GetString("k0")
+
+	var buf bytes.Buffer
+	if err := source.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	target := newPersistTestManager(t, 20)
+	defer target.Close()
+	if err := target.LoadSnapshot(&buf); err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+	if target.Stats().Size > 20 {
+		t.Errorf("Expected size <= 20, got %d", target.Stats().Size)
+	}
+	for _, key := range []string{"k0", "k3"} {
+		if v, _ := target.GetString(key); v == "" {
+			t.Errorf("Expected recently used key %s to be kept", key)
+		}
+	}
+}
+
+// 测试损坏的快照
+func TestSnapshotCorrupt(t *testing.T) {
+	manager := NewCacheManager()
+	if err := manager.LoadSnapshot(bytes.NewReader([]byte("not a snapshot"))); err == nil {
+		t.Error("Expected error for invalid header")
+	}
+
+	source := NewCacheManager()
+	source.SetString("key", "value")
+	var buf bytes.Buffer
+	_ = source.SaveSnapshot(&buf)
+	data := buf.Bytes()
+	data[len(data)-1] ^= 0xff
+	if err := manager.LoadSnapshot(bytes.NewReader(data)); err == nil {
+		t.Error("Expected error for corrupt record")
+	}
+}
+
+// 测试校验和正确但缺少key和标签字段的记录视为损坏，不会恢复成没有原始key的数据
+func TestReadRecordTruncated(t *testing.T) {
+	var full bytes.Buffer
+	if err := writeRecord(&full, &cacheRecord{op: recordSet, key: "k", params: "key", tags: []string{"t"}}); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := readRecord(bufio.NewReader(&full)); err != nil || record.params != "key" || len(record.tags) != 1 {
+		t.Fatalf("Unexpected record %+v %v", record, err)
+	}
+
+	var payload bytes.Buffer
+	payload.WriteByte(recordSet)
+	for _, field := range []string{"k", "string", "json", "v"} {
+		writeRecordBytes(&payload, []byte(field))
+	}
+	for i := 0; i < 3; i++ {
+		payload.Write(binary.AppendVarint(nil, 0))
+	}
+	data := binary.AppendUvarint(nil, uint64(payload.Len()))
+	data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(payload.Bytes()))
+	data = append(data, payload.Bytes()...)
+	if _, err := readRecord(bufio.NewReader(bytes.NewReader(data))); !errors.Is(err, errCorruptRecord) {
+		t.Errorf("Expected errCorruptRecord, got %v", err)
+	}
+}
+
+// 测试定时快照文件
+func TestSnapshotFile(t *testing.T) {
+	silenceLog(t)
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	first := newPersistTestManager(t, 1024*1024, WithSnapshotFile(path, 20*time.Millisecond))
+	first.SetString("key", "value")
+	time.Sleep(100 * time.Millisecond)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected periodic snapshot file: %v", err)
+	}
+	first.SetString("last", "value")
+	if err := first.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	second := newPersistTestManager(t, 1024*1024, WithSnapshotFile(path, 0))
+	defer second.Close()
+	for _, key := range []string{"key", "last"} {
+		if v, err := second.GetString(key); err != nil || v != "value" {
+			t.Errorf("Expected %s to be restored, got %q (%v)", key, v, err)
+		}
+	}
+}
+
+// 测试追加日志的回放和压缩
+func TestAppendLog(t *testing.T) {
+	silenceLog(t)
+	path := filepath.Join(t.TempDir(), "cache.log")
+
+	first := newPersistTestManager(t, 1024*1024, WithAppendLog(path))
+	first.SetString("keep", "v1")
+	first.SetString("keep", "v2")
+	first.SetString("deleted", "value")
+	_ = first.Delete("deleted")
+	if err := SetValue(first, "count", 42, time.Hour); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if err := first.CompactLog(); err != nil {
+		t.Fatalf("Failed to compact log: %v", err)
+	}
+	first.SetString("after", "compact")
+	if err := first.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	second := newPersistTestManager(t, 1024*1024, WithAppendLog(path))
+	if v, err := second.GetString("keep"); err != nil || v != "v2" {
+		t.Errorf("Expected v2, got %q (%v)", v, err)
+	}
+	if v, err := second.GetString("after"); err != nil || v != "compact" {
+		t.Errorf("Expected compact, got %q (%v)", v, err)
+	}
+	if v, _ := second.GetString("deleted"); v != "" {
+		t.Error("Expected deleted key to stay deleted")
+	}
+	if v, ok := GetValue[int](second, "count"); !ok || v != 42 {
+		t.Errorf("Expected 42, got %d (%v)", v, ok)
+	}
+	second.Clear()
+	_ = second.Close()
+
+	third := newPersistTestManager(t, 1024*1024, WithAppendLog(path))
+	defer third.Close()
+	if third.Stats().KeyCount != 0 {
+		t.Errorf("Expected empty cache after clear, got %d keys", third.Stats().KeyCount)
+	}
+}
+
+// 测试值无法编码时追加删除记录，回放时不会恢复之前的值
+func TestAppendLogUnencodableValue(t *testing.T) {
+	silenceLog(t)
+	path := filepath.Join(t.TempDir(), "cache.log")
+
+	first := newPersistTestManager(t, 1024*1024, WithAppendLog(path))
+	if err := SetValue(first, "key", 1, time.Hour); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if err := first.SetValueWithTTL("key", make(chan int), time.Hour); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	_ = first.Close()
+
+	second := newPersistTestManager(t, 1024*1024, WithAppendLog(path))
+	defer second.Close()
+	if v, ok := second.GetValue("key"); ok {
+		t.Errorf("Expected stale value not restored, got %v", v)
+	}
+}
+
+// 测试重复调用 Init 时关闭之前的持久化，不重复恢复数据，Close 后再次 Init 仍然会写入快照
+func TestInitTwiceWithPersistence(t *testing.T) {
+	silenceLog(t)
+	dir := t.TempDir()
+	snapshot, logPath := filepath.Join(dir, "cache.snapshot"), filepath.Join(dir, "cache.log")
+	opts := []Option{WithSnapshotFile(snapshot, 5*time.Millisecond), WithAppendLog(logPath)}
+
+	manager := newPersistTestManager(t, 1024*1024, opts...)
+	manager.SetString("first", "1")
+	if err := manager.Init(1024*1024, "", opts...); err != nil {
+		t.Fatal(err)
+	}
+	manager.SetString("second", "2")
+	time.Sleep(20 * time.Millisecond)
+	if n := manager.Stats().KeyCount; n != 2 {
+		t.Errorf("Expected 2 keys, got %d", n)
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Init(1024*1024, "", WithSnapshotFile(snapshot, 0)); err != nil {
+		t.Fatal(err)
+	}
+	manager.SetString("third", "3")
+	if err := manager.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newPersistTestManager(t, 1024*1024, WithSnapshotFile(snapshot, 0))
+	defer restored.Close()
+	for _, key := range []string{"first", "second", "third"} {
+		if _, err := restored.GetString(key); err != nil {
+			t.Errorf("Expected %s restored: %v", key, err)
+		}
+	}
+
+	replayed := newPersistTestManager(t, 1024*1024, WithAppendLog(logPath))
+	defer replayed.Close()
+	if v, err := replayed.GetString("second"); err != nil || v != "2" {
+		t.Errorf("Expected second in append log, got %q (%v)", v, err)
+	}
+}
+
+// 测试追加日志末尾写了一半的记录被忽略
+func TestAppendLogTornTail(t *testing.T) {
+	silenceLog(t)
+	path := filepath.Join(t.TempDir(), "cache.log")
+
+	first := newPersistTestManager(t, 1024*1024, WithAppendLog(path))
+	first.SetString("key", "value")
+	_ = first.Close()
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.Write([]byte{0x20, 0x01, 0x02})
+	_ = file.Close()
+
+	second := newPersistTestManager(t, 1024*1024, WithAppendLog(path))
+	defer second.Close()
+	if v, err := second.GetString("key"); err != nil || v != "value" {
+		t.Errorf("Expected value, got %q (%v)", v, err)
+	}
+}
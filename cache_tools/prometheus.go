@@ -0,0 +1,102 @@
+package cache_tools
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WritePrometheus 以Prometheus文本格式输出缓存的统计信息
+// managers 的key作为指标的cache标签，用于区分多个缓存实例
+func WritePrometheus(w io.Writer, managers map[string]*CacheManager) error {
+	names := make([]string, 0, len(managers))
+	for name := range managers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	stats := make([]CacheStats, len(names))
+	for i, name := range names {
+		stats[i] = managers[name].Stats()
+	}
+
+	bw := bufio.NewWriter(w)
+	simple := func(name, kind, help string, value func(s CacheStats) float64) {
+		writeMetricHeader(bw, name, kind, help)
+		for i, cache := range names {
+			writeMetric(bw, name, cacheLabel(cache), value(stats[i]))
+		}
+	}
+
+	simple("cache_size_bytes", "gauge", "Current size of cached data in bytes.", func(s CacheStats) float64 {
+		return float64(s.Size)
+	})
+	simple("cache_keys", "gauge", "Current number of cached keys.", func(s CacheStats) float64 {
+		return float64(s.KeyCount)
+	})
+	simple("cache_hits_total", "counter", "Total number of cache hits.", func(s CacheStats) float64 {
+		return float64(s.Hits)
+	})
+	simple("cache_misses_total", "counter", "Total number of cache misses.", func(s CacheStats) float64 {
+		return float64(s.Misses)
+	})
+	simple("cache_sets_total", "counter", "Total number of cache writes.", func(s CacheStats) float64 {
+		return float64(s.Sets)
+	})
+
+	writeMetricHeader(bw, "cache_evictions_total", "counter", "Total number of removed cache items by reason.")
+	for i, cache := range names {
+		for _, reason := range evictReasons {
+			labels := cacheLabel(cache) + `,reason="` + string(reason) + `"`
+			writeMetric(bw, "cache_evictions_total", labels, float64(stats[i].Evictions[reason]))
+		}
+	}
+
+	writeMetricHeader(bw, "cache_get_duration_seconds", "histogram", "Latency of cache reads.")
+	for i, cache := range names {
+		writeHistogram(bw, "cache_get_duration_seconds", cacheLabel(cache), stats[i].GetLatency)
+	}
+	writeMetricHeader(bw, "cache_set_duration_seconds", "histogram", "Latency of cache writes.")
+	for i, cache := range names {
+		writeHistogram(bw, "cache_set_duration_seconds", cacheLabel(cache), stats[i].SetLatency)
+	}
+	return bw.Flush()
+}
+
+// PrometheusHandler 返回输出缓存统计信息的 http.Handler，可以直接挂载到 /metrics
+func PrometheusHandler(managers map[string]*CacheManager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WritePrometheus(w, managers)
+	})
+}
+
+func writeMetricHeader(w *bufio.Writer, name, kind, help string) {
+	w.WriteString("# HELP " + name + " " + help + "\n")
+	w.WriteString("# TYPE " + name + " " + kind + "\n")
+}
+
+func writeMetric(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name + "{" + labels + "} " + strconv.FormatFloat(value, 'g', -1, 64) + "\n")
+}
+
+// writeHistogram 输出直方图，Prometheus的桶是累加的
+func writeHistogram(w *bufio.Writer, name, labels string, h Histogram) {
+	var cumulative int64
+	for i, bound := range h.Bounds {
+		cumulative += h.Buckets[i]
+		le := strconv.FormatFloat(bound, 'g', -1, 64)
+		writeMetric(w, name+"_bucket", labels+`,le="`+le+`"`, float64(cumulative))
+	}
+	writeMetric(w, name+"_bucket", labels+`,le="+Inf"`, float64(h.Count))
+	writeMetric(w, name+"_sum", labels, h.Sum.Seconds())
+	writeMetric(w, name+"_count", labels, float64(h.Count))
+}
+
+// cacheLabel 生成cache标签，转义标签值中的特殊字符
+func cacheLabel(name string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(name)
+	return `cache="` + escaped + `"`
+}
@@ -0,0 +1,306 @@
+package cache_tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 定时计划
+type Schedule interface {
+	// Next 返回after之后的下一次执行时间，零值表示不再执行
+	Next(after time.Time) time.Time
+}
+
+// Daily 每天在指定的时间执行，按当地时间计算，夏令时切换的当天也不会提前或推迟
+func Daily(hour, min, sec int) Schedule {
+	return dailySchedule{hour: hour, min: min, sec: sec}
+}
+
+type dailySchedule struct {
+	hour, min, sec int
+}
+
+func (s dailySchedule) Next(after time.Time) time.Time {
+	next := time.Date(after.Year(), after.Month(), after.Day(), s.hour, s.min, s.sec, 0, after.Location())
+	if !next.After(after) {
+		next = time.Date(after.Year(), after.Month(), after.Day()+1, s.hour, s.min, s.sec, 0, after.Location())
+	}
+	return next
+}
+
+// Every 每隔d执行一次
+func Every(d time.Duration) Schedule {
+	return intervalSchedule(d)
+}
+
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(after time.Time) time.Time {
+	if s <= 0 {
+		return time.Time{}
+	}
+	return after.Add(time.Duration(s))
+}
+
+// Windows 组合多个计划，取其中最早的下一次执行时间
+// 例如每天3点和15点各执行一次: Windows(Daily(3, 0, 0), Daily(15, 0, 0))
+func Windows(schedules ...Schedule) Schedule {
+	return windowsSchedule(schedules)
+}
+
+type windowsSchedule []Schedule
+
+func (s windowsSchedule) Next(after time.Time) time.Time {
+	var next time.Time
+	for _, schedule := range s {
+		t := schedule.Next(after)
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next
+}
+
+// ParseSchedule 解析定时计划，支持以下格式，多个计划用";"分隔:
+//
+//	"03:00:00"       每天3点，兼容原来的 PlanTime 格式
+//	"@every 30m"     每隔30分钟
+//	"@hourly"        每小时，另外支持 @daily、@midnight、@weekly、@monthly、@yearly、@annually
+//	"0 3 * * *"      cron表达式: 分 时 日 月 周
+//	"30 0 3 * * 1-5" 带秒的cron表达式: 秒 分 时 日 月 周
+func ParseSchedule(spec string) (Schedule, error) {
+	parts := strings.Split(spec, ";")
+	schedules := make([]Schedule, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		schedule, err := parseOneSchedule(part)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	switch len(schedules) {
+	case 0:
+		return nil, fmt.Errorf("empty schedule %q", spec)
+	case 1:
+		return schedules[0], nil
+	}
+	return Windows(schedules...), nil
+}
+
+func parseOneSchedule(spec string) (Schedule, error) {
+	if t, err := time.Parse("15:04:05", spec); err == nil {
+		return Daily(t.Hour(), t.Minute(), t.Second()), nil
+	}
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("invalid interval %q", spec)
+		}
+		return Every(d), nil
+	}
+	return Cron(spec)
+}
+
+// cronDescriptors 预定义的cron表达式
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Cron 解析cron表达式，支持5个字段(分 时 日 月 周)或6个字段(秒 分 时 日 月 周)
+// 每个字段支持 *、数字、范围(1-5)、步长(*/15、1-30/5)和列表(1,15,30)，月和周支持英文缩写(JAN、MON)
+// 日和周同时指定时，满足任意一个即执行
+func Cron(expr string) (Schedule, error) {
+	if descriptor, ok := cronDescriptors[expr]; ok {
+		expr = descriptor
+	}
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron expression %q should have 5 or 6 fields", expr)
+	}
+
+	s := &cronSchedule{}
+	var err error
+	specs := []struct {
+		target   *uint64
+		min, max int
+		names    []string
+	}{
+		{&s.second, 0, 59, nil},
+		{&s.minute, 0, 59, nil},
+		{&s.hour, 0, 23, nil},
+		{&s.dom, 1, 31, nil},
+		{&s.month, 1, 12, monthNames},
+		{&s.dow, 0, 7, weekdayNames},
+	}
+	for i, spec := range specs {
+		if *spec.target, err = parseCronField(fields[i], spec.min, spec.max, spec.names); err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+	}
+	// 周日可以写成0或7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = fields[3] == "*" || fields[3] == "?"
+	s.dowAny = fields[5] == "*" || fields[5] == "?"
+	return s, nil
+}
+
+var monthNames = []string{"", "JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+var weekdayNames = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// parseCronField 解析cron的一个字段，返回允许的值的位图
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+
+		start, end := min, max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = parseCronValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(bounds[1], names); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := parseCronValue(rangePart, names)
+			if err != nil {
+				return 0, err
+			}
+			start = v
+			// "5/10" 表示从5开始每10个执行一次
+			if step == 1 {
+				end = v
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("value out of range in %q", part)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, names []string) (int, error) {
+	for i, name := range names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// cronSchedule 解析后的cron表达式，每个字段是允许的值的位图
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	domAny, dowAny                        bool
+}
+
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), after.Second()+1, 0, loc)
+	// 最多向后查找5年，避免 "0 0 30 2 *" 这种永远不会满足的表达式死循环
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+		case s.second&(1<<uint(t.Second())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second()+1, 0, loc)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// ScheduleAction 定时计划触发时执行的操作
+type ScheduleAction func(cache *Cache, now time.Time)
+
+// ClearAll 清空整个缓存，原来的 PlanTime 使用的就是这个操作
+func ClearAll() ScheduleAction {
+	return func(cache *Cache, now time.Time) {
+		cache.Clear()
+	}
+}
+
+// ClearPrefix 删除key以prefix开头的缓存，用于清理某个命名空间
+func ClearPrefix(prefix string) ScheduleAction {
+	return func(cache *Cache, now time.Time) {
+		cache.DeletePrefix(prefix)
+	}
+}
+
+// ClearTag 删除带有tag标签的缓存
+func ClearTag(tag string) ScheduleAction {
+	return func(cache *Cache, now time.Time) {
+		cache.InvalidateTag(tag)
+	}
+}
+
+// EvictExpired 只删除已过期的缓存
+func EvictExpired() ScheduleAction {
+	return func(cache *Cache, now time.Time) {
+		cache.removeExpired(now)
+	}
+}
+
+// ShrinkTo 按淘汰策略淘汰缓存，直到缓存大小不超过size
+func ShrinkTo(size int64) ScheduleAction {
+	return func(cache *Cache, now time.Time) {
+		cache.evictUntil(size)
+	}
+}
@@ -0,0 +1,217 @@
+package cache_tools
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试cron表达式计算下一次执行时间
+func TestCronNext(t *testing.T) {
+	base := time.Date(2024, 1, 31, 10, 30, 15, 0, time.UTC) // 周三
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC)},
+		{"30 0 3 * * *", time.Date(2024, 2, 1, 3, 0, 30, 0, time.UTC)},
+		{"0 9 * * MON-FRI", time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		// 日和周都指定时满足任意一个即可
+		{"0 0 13 * FRI", time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		s, err := Cron(tc.expr)
+		if err != nil {
+			t.Errorf("Cron(%q): %v", tc.expr, err)
+			continue
+		}
+		if got := s.Next(base); !got.Equal(tc.want) {
+			t.Errorf("Cron(%q).Next = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+
+	never, err := Cron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := never.Next(base); !got.IsZero() {
+		t.Errorf("Expected no next time for Feb 30, got %v", got)
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "* * * * FOO"} {
+		if _, err := Cron(expr); err == nil {
+			t.Errorf("Expected error for %q", expr)
+		}
+	}
+}
+
+// 测试夏令时切换当天的执行时间
+func TestScheduleDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	// 2024-03-10 凌晨2点时钟拨快到3点，当天只有23小时
+	before := time.Date(2024, 3, 9, 4, 0, 0, 0, loc)
+	next := Daily(3, 0, 0).Next(before)
+	if want := time.Date(2024, 3, 10, 3, 0, 0, 0, loc); !next.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, next)
+	}
+	if next.Sub(before) != 22*time.Hour {
+		t.Errorf("Expected 22h until next run across DST, got %v", next.Sub(before))
+	}
+
+	cron, err := Cron("0 3 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cron.Next(next); !got.Equal(time.Date(2024, 3, 11, 3, 0, 0, 0, loc)) {
+		t.Errorf("Expected next day at 03:00, got %v", got)
+	}
+}
+
+// 测试解析计划字符串和多个时间窗口
+func TestParseSchedule(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"07:00:00", time.Date(2024, 1, 2, 7, 0, 0, 0, time.UTC)},
+		{"@every 90m", base.Add(90 * time.Minute)},
+		{"03:00:00; 15:00:00", time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC)},
+		{"0 13 * * *;@every 30m", base.Add(30 * time.Minute)},
+	}
+	for _, tc := range cases {
+		s, err := ParseSchedule(tc.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tc.spec, err)
+			continue
+		}
+		if got := s.Next(base); !got.Equal(tc.want) {
+			t.Errorf("ParseSchedule(%q).Next = %v, want %v", tc.spec, got, tc.want)
+		}
+	}
+	for _, spec := range []string{"", ";", "@every -1s", "25:00"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+// 测试使用FakeClock驱动定时计划，不需要真实等待
+func TestWatcherScheduleWithFakeClock(t *testing.T) {
+	silenceLog(t)
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager := NewCacheManager()
+	err := manager.Init(1024*1024, "",
+		WithKeyStrategy(RawKey),
+		WithClock(clock),
+		WithSchedule(Every(time.Hour), ClearPrefix("session:")),
+		WithSchedule(Daily(3, 0, 0), ClearTag("report")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	manager.SetString("session:1", "s")
+	manager.SetString("user:1", "u")
+	manager.SetStringWithTags("daily", "d", 0, "report")
+
+	// 等待定时计划和过期检查的定时器都创建好
+	clock.WaitForTimers(2)
+	clock.Advance(time.Hour)
+	waitFor(t, func() bool {
+		v, _ := manager.GetString("session:1")
+		return v == ""
+	})
+	if v, _ := manager.GetString("user:1"); v != "u" {
+		t.Error("Expected keys outside the prefix to be kept")
+	}
+	if v, _ := manager.GetString("daily"); v != "d" {
+		t.Error("Expected tagged key to be kept before 03:00")
+	}
+
+	manager.SetString("session:2", "s")
+	clock.WaitForTimers(2)
+	clock.Advance(2 * time.Hour)
+	waitFor(t, func() bool {
+		v, _ := manager.GetString("daily")
+		return v == ""
+	})
+	if v, _ := manager.GetString("session:2"); v != "" {
+		t.Error("Expected prefix to be cleared again")
+	}
+}
+
+// 测试过期检查使用时间源的时间
+func TestWatcherExpirationWithFakeClock(t *testing.T) {
+	silenceLog(t)
+	clock := NewFakeClock(time.Now())
+	cache := NewCache()
+	cache.SetLogger(nil)
+	watcher := NewWatcher(cache)
+	watcher.SetClock(clock)
+	watcher.AddSchedule(Every(time.Minute), ShrinkTo(0))
+	watcher.Start()
+	defer watcher.Stop()
+
+	cache.SetStringWithExpiration("k", "v", time.Hour)
+	clock.WaitForTimers(2)
+	clock.Advance(time.Second)
+	// 缓存项还没有到真实的过期时间，过期检查只会用时间源的时间判断
+	if cache.Size() == 0 {
+		t.Fatal("Expected item to be kept")
+	}
+	clock.Advance(time.Minute)
+	waitFor(t, func() bool { return cache.Size() == 0 })
+}
+
+// 测试时钟跳过多次计划时间后只补执行一次
+func TestWatcherScheduleCatchUp(t *testing.T) {
+	silenceLog(t)
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewCache()
+	cache.SetLogger(nil)
+	watcher := NewWatcher(cache)
+	watcher.SetClock(clock)
+	var runs atomic.Int32
+	watcher.AddSchedule(Every(5*time.Minute), func(cache *Cache, now time.Time) { runs.Add(1) })
+	watcher.Start()
+	defer watcher.Stop()
+
+	clock.WaitForTimers(2)
+	clock.Advance(24 * time.Hour)
+	waitFor(t, func() bool { return runs.Load() == 1 })
+	clock.WaitForTimers(2)
+	time.Sleep(10 * time.Millisecond)
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("Expected missed runs to run once, got %d", n)
+	}
+
+	clock.Advance(5 * time.Minute)
+	waitFor(t, func() bool { return runs.Load() == 2 })
+}
+
+// 测试 Init 兼容原来的时间格式并支持新的计划格式
+func TestInitScheduleSpec(t *testing.T) {
+	for _, spec := range []string{"07:00:00", "0 7 * * *", "07:00:00;19:00:00"} {
+		manager := NewCacheManager()
+		if err := manager.Init(1024, spec); err != nil {
+			t.Errorf("Init(%q): %v", spec, err)
+		}
+		manager.Close()
+	}
+	err := NewCacheManager().Init(1024, "7 o'clock")
+	if err == nil || !strings.Contains(err.Error(), "fields") {
+		t.Errorf("Expected parse error, got %v", err)
+	}
+}
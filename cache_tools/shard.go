@@ -0,0 +1,310 @@
+package cache_tools
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultShardCount 默认分片数量
+const DefaultShardCount = 16
+
+// shard 缓存分片
+// 每个分片有独立的锁、大小统计和淘汰策略，不同分片之间的读写互不阻塞
+type shard struct {
+	mu      sync.Mutex
+	items   map[string]*Item
+	size    int64                          // 分片内数据大小
+	policy  EvictionPolicy                 // 分片内的淘汰策略
+	journal *appendLog                     // 追加日志，为空表示未开启
+	tags    map[string]map[string]struct{} // 标签到key的索引
+}
+
+func newShard(policy EvictionPolicy) *shard {
+	return &shard{
+		items:  make(map[string]*Item),
+		policy: policy,
+	}
+}
+
+// set 写入缓存项，返回大小和key数量的变化量
+// record 不为空时在锁内写入追加日志
+func (s *shard) set(key string, item *Item, record *cacheRecord) (sizeDelta int64, countDelta int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.journal != nil && record != nil {
+		s.journal.write(record)
+	}
+
+	sizeDelta = item.GetSize()
+	countDelta = 1
+	if old, ok := s.items[key]; ok {
+		sizeDelta -= old.GetSize()
+		countDelta = 0
+		s.unindexTags(key, old)
+	}
+	s.items[key] = item
+	s.size += sizeDelta
+	s.policy.OnAdd(key)
+	s.indexTags(key, item)
+	return sizeDelta, countDelta
+}
+
+// get 读取缓存项并记录一次访问
+func (s *shard) get(key string) (*Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	item.IncreaseAccessCount()
+	item.UpdateLastUsedTime()
+	s.policy.OnAccess(key)
+	return item, true
+}
+
+// remove 删除缓存项，item不为空时只有当前值仍是item才删除
+func (s *shard) remove(key string, item *Item) (*Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.items[key]
+	if !ok || (item != nil && current != item) {
+		return nil, false
+	}
+	s.removeLocked(key, current)
+	return current, true
+}
+
+func (s *shard) removeLocked(key string, item *Item) {
+	delete(s.items, key)
+	s.size -= item.GetSize()
+	s.policy.OnRemove(key)
+	s.unindexTags(key, item)
+	if s.journal != nil && !item.IsExpired(time.Now()) {
+		s.journal.logDelete(key)
+	}
+}
+
+// victim 分片的淘汰候选
+type victim struct {
+	score    int64     // EvictionScorer 返回的分数
+	lastUsed time.Time // 最后使用时间
+}
+
+// before 是否比other更应该被淘汰
+func (v victim) before(other victim) bool {
+	if v.score != other.score {
+		return v.score < other.score
+	}
+	return v.lastUsed.Before(other.lastUsed)
+}
+
+// peekVictim 返回淘汰策略选出的下一个候选key的分数和最后使用时间
+func (s *shard) peekVictim() (victim, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.policy.Peek()
+	if !ok {
+		return victim{}, false
+	}
+	v := victim{lastUsed: s.items[key].GetLastUsedTime()}
+	if scorer, ok := s.policy.(EvictionScorer); ok {
+		v.score = scorer.Score(key)
+	}
+	return v, true
+}
+
+// evict 按淘汰策略淘汰一个key
+func (s *shard) evict() (string, *Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.policy.Evict()
+	if !ok {
+		return "", nil, false
+	}
+	item := s.items[key]
+	delete(s.items, key)
+	s.size -= item.GetSize()
+	s.unindexTags(key, item)
+	if s.journal != nil {
+		s.journal.logDelete(key)
+	}
+	return key, item, true
+}
+
+// collectExpired 删除所有在now之前过期的缓存项
+func (s *shard) collectExpired(now time.Time) map[string]*Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired map[string]*Item
+	for key, item := range s.items {
+		if item.IsExpired(now) {
+			if expired == nil {
+				expired = make(map[string]*Item)
+			}
+			expired[key] = item
+			s.removeLocked(key, item)
+		}
+	}
+	return expired
+}
+
+// clear 清空分片，返回清空前的缓存项和大小
+func (s *shard) clear() (items map[string]*Item, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, size = s.items, s.size
+	s.items = make(map[string]*Item)
+	s.size = 0
+	s.tags = nil
+	s.policy.Reset()
+	return items, size
+}
+
+// setPolicy 替换淘汰策略，已有的key加入新策略
+func (s *shard) setPolicy(policy EvictionPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.items {
+		policy.OnAdd(key)
+	}
+	s.policy = policy
+}
+
+// records 导出分片中在now时刻未过期的数据，用于快照
+func (s *shard) records(now time.Time) []*cacheRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]*cacheRecord, 0, len(s.items))
+	for key, item := range s.items {
+		if item.IsExpired(now) {
+			continue
+		}
+		records = append(records, &cacheRecord{
+			op:          recordSet,
+			key:         key,
+			valueType:   item.GetValueType(),
+			expiration:  item.GetExpiration(),
+			lastUsed:    item.GetLastUsedTime(),
+			accessCount: item.GetAccessCount(),
+			params:      item.GetKey(),
+			tags:        item.GetTags(),
+			value:       item.Get(),
+		})
+	}
+	return records
+}
+
+// restore 写入从快照恢复的缓存项，并回放accessCount次访问以恢复访问频率
+func (s *shard) restore(key string, item *Item, accessCount int64) (sizeDelta int64, countDelta int64) {
+	sizeDelta, countDelta = s.set(key, item, nil)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := int64(0); i < accessCount; i++ {
+		s.policy.OnAccess(key)
+	}
+	return sizeDelta, countDelta
+}
+
+// replaceEncoded 用解码后的值替换编码值，缓存项的其他属性保持不变
+func (s *shard) replaceEncoded(key string, encoded *EncodedValue, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[key]
+	if !ok {
+		return
+	}
+	if current, ok := item.value.(*EncodedValue); !ok || current != encoded {
+		return
+	}
+	// 其他协程可能正在读取旧的缓存项，所以替换为新的缓存项而不是直接修改
+	decoded := *item
+	decoded.value = value
+	s.items[key] = &decoded
+}
+
+// removeTag 删除带有tag标签的所有缓存项
+func (s *shard) removeTag(tag string) map[string]*Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := s.tags[tag]
+	if len(keys) == 0 {
+		return nil
+	}
+	removed := make(map[string]*Item, len(keys))
+	for key := range keys {
+		removed[key] = s.items[key]
+	}
+	for key, item := range removed {
+		s.removeLocked(key, item)
+	}
+	return removed
+}
+
+// removeIf 删除所有满足条件的缓存项
+func (s *shard) removeIf(match func(item *Item) bool) map[string]*Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed map[string]*Item
+	for key, item := range s.items {
+		if match(item) {
+			if removed == nil {
+				removed = make(map[string]*Item)
+			}
+			removed[key] = item
+			s.removeLocked(key, item)
+		}
+	}
+	return removed
+}
+
+// liveItems 返回分片中在now时刻未过期的缓存项，key为内部key，不记录访问
+func (s *shard) liveItems(now time.Time) map[string]*Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := make(map[string]*Item, len(s.items))
+	for key, item := range s.items {
+		if !item.IsExpired(now) {
+			items[key] = item
+		}
+	}
+	return items
+}
+
+func (s *shard) indexTags(key string, item *Item) {
+	for _, tag := range item.GetTags() {
+		if s.tags == nil {
+			s.tags = make(map[string]map[string]struct{})
+		}
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			s.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+func (s *shard) unindexTags(key string, item *Item) {
+	for _, tag := range item.GetTags() {
+		keys := s.tags[tag]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
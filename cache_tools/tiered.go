@@ -0,0 +1,208 @@
+package cache_tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultInvalidationChannel 默认的缓存失效广播channel
+const DefaultInvalidationChannel = "cache_tools:invalidate"
+
+// TieredCache 两级缓存: 进程内的 CacheManager 作为一级缓存，远程 Backend 作为二级缓存
+// 写入同时写两级缓存，一级缓存未命中时读取二级缓存并回填一级缓存
+// 远程存储同时实现了 Broadcaster 时，写入和删除会广播给其他实例，其他实例收到后删除自己的一级缓存
+type TieredCache struct {
+	local    *CacheManager
+	remote   Backend
+	codec    Codec
+	localTTL time.Duration // 一级缓存的最长缓存时间，0表示跟随二级缓存
+	channel  string
+	id       string // 实例ID，忽略自己发出的广播
+
+	broadcaster Broadcaster
+	cancel      context.CancelFunc
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// TieredOption TieredCache 的可选配置
+type TieredOption func(*TieredCache)
+
+// WithTieredCodec 设置写入二级缓存时的编解码器，默认使用 JSONCodec，方便其他语言读取
+func WithTieredCodec(codec Codec) TieredOption {
+	return func(t *TieredCache) {
+		t.codec = codec
+	}
+}
+
+// WithLocalTTL 设置一级缓存的最长缓存时间，用于限制没有收到失效广播时数据不一致的时间
+func WithLocalTTL(ttl time.Duration) TieredOption {
+	return func(t *TieredCache) {
+		t.localTTL = ttl
+	}
+}
+
+// WithInvalidationChannel 设置失效广播的channel，同一组实例需要使用相同的channel
+func WithInvalidationChannel(channel string) TieredOption {
+	return func(t *TieredCache) {
+		t.channel = channel
+	}
+}
+
+// WithBroadcaster 设置失效广播，默认使用远程存储自身(如果实现了 Broadcaster)
+// 传入nil表示不广播
+func WithBroadcaster(broadcaster Broadcaster) TieredOption {
+	return func(t *TieredCache) {
+		t.broadcaster = broadcaster
+	}
+}
+
+// NewTieredCache 创建两级缓存，开启了广播时会启动一个协程订阅失效消息，使用完后需要调用 Close
+func NewTieredCache(local *CacheManager, remote Backend, opts ...TieredOption) *TieredCache {
+	t := &TieredCache{
+		local:   local,
+		remote:  remote,
+		codec:   JSONCodec{},
+		channel: DefaultInvalidationChannel,
+		id:      uuid.NewString(),
+	}
+	if broadcaster, ok := remote.(Broadcaster); ok {
+		t.broadcaster = broadcaster
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.broadcaster != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.cancel = cancel
+		t.done = make(chan struct{})
+		go t.subscribe(ctx)
+	}
+	return t
+}
+
+// Set 写入两级缓存，ttl为0表示永不过期
+func (t *TieredCache) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
+	data, err := t.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := t.remote.Set(ctx, key, data, ttl); err != nil {
+		return err
+	}
+	t.publish(ctx, key)
+	return t.local.SetValueWithTTL(key, value, t.capLocalTTL(ttl))
+}
+
+// Get 读取缓存到result，result必须是指针，第一个返回值表示是否命中
+// 一级缓存未命中时读取二级缓存，并按二级缓存的剩余过期时间回填一级缓存
+func (t *TieredCache) Get(ctx context.Context, key string, result any) (bool, error) {
+	target := reflect.ValueOf(result)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return false, fmt.Errorf("result must be a non-nil pointer, got %T", result)
+	}
+
+	if v, ok := t.local.GetValue(key); ok {
+		value := reflect.ValueOf(v)
+		if value.IsValid() && value.Type().AssignableTo(target.Elem().Type()) {
+			target.Elem().Set(value)
+			return true, nil
+		}
+	}
+
+	data, ok, err := t.remote.Get(ctx, key)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := t.codec.Unmarshal(data, result); err != nil {
+		return false, err
+	}
+
+	ttl, ok, err := t.remote.TTL(ctx, key)
+	if err != nil || !ok {
+		// 读取之后key已被删除或过期，不回填一级缓存
+		return true, nil
+	}
+	if err := t.local.SetValueWithTTL(key, target.Elem().Interface(), t.capLocalTTL(ttl)); err != nil {
+		t.local.cache.logger.Errorf("tiered cache fill local failed: %v", err)
+	}
+	return true, nil
+}
+
+// Delete 删除两级缓存，并通知其他实例删除一级缓存
+func (t *TieredCache) Delete(ctx context.Context, key string) error {
+	if err := t.remote.Delete(ctx, key); err != nil {
+		return err
+	}
+	t.publish(ctx, key)
+	return t.local.Delete(key)
+}
+
+// Invalidate 只删除本实例的一级缓存
+func (t *TieredCache) Invalidate(key string) error {
+	return t.local.Delete(key)
+}
+
+// Close 停止订阅失效广播，不会关闭一级缓存和远程存储
+func (t *TieredCache) Close() error {
+	t.closeOnce.Do(func() {
+		if t.cancel != nil {
+			t.cancel()
+			<-t.done
+		}
+	})
+	return nil
+}
+
+// capLocalTTL 一级缓存的过期时间不超过 localTTL
+func (t *TieredCache) capLocalTTL(ttl time.Duration) time.Duration {
+	if t.localTTL > 0 && (ttl == 0 || ttl > t.localTTL) {
+		return t.localTTL
+	}
+	return ttl
+}
+
+// publish 广播失效消息，格式: 实例ID + "\n" + key
+func (t *TieredCache) publish(ctx context.Context, key string) {
+	if t.broadcaster == nil {
+		return
+	}
+	message := append([]byte(t.id+"\n"), key...)
+	if err := t.broadcaster.Publish(ctx, t.channel, message); err != nil {
+		// 广播失败时其他实例的一级缓存最多在 localTTL 后过期
+		t.local.cache.logger.Errorf("tiered cache publish invalidation failed: %v", err)
+	}
+}
+
+// subscribe 订阅失效广播，连接断开后自动重连
+func (t *TieredCache) subscribe(ctx context.Context) {
+	defer close(t.done)
+	for {
+		err := t.broadcaster.Subscribe(ctx, t.channel, t.onInvalidate)
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+		t.local.cache.logger.Errorf("tiered cache subscribe failed: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (t *TieredCache) onInvalidate(message []byte) {
+	id, key, ok := bytes.Cut(message, []byte("\n"))
+	if !ok || string(id) == t.id {
+		return
+	}
+	_ = t.local.Delete(string(key))
+}
@@ -0,0 +1,273 @@
+package cache_tools
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// respTestServer 进程内的RESP服务，实现测试用到的命令
+type respTestServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	data     map[string][]byte
+	expires  map[string]time.Time
+	subs     map[string][]*respTestSubscriber
+}
+
+type respTestSubscriber struct {
+	mu     sync.Mutex
+	writer *bufio.Writer
+}
+
+func (s *respTestSubscriber) send(reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writer.WriteString(reply)
+	s.writer.Flush()
+}
+
+func newRESPTestServer(t *testing.T) *respTestServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &respTestServer{
+		listener: listener,
+		data:     make(map[string][]byte),
+		expires:  make(map[string]time.Time),
+		subs:     make(map[string][]*respTestSubscriber),
+	}
+	go s.serve()
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	return s
+}
+
+func (s *respTestServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *respTestServer) subscribers(channel string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs[channel])
+}
+
+func (s *respTestServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *respTestServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	sub := &respTestSubscriber{writer: bufio.NewWriter(conn)}
+	for {
+		reply, err := readRESP(reader)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			b, _ := item.([]byte)
+			args[i] = string(b)
+		}
+		if len(args) == 0 {
+			return
+		}
+		sub.send(s.exec(args, sub))
+	}
+}
+
+func bulk(b []byte) string {
+	if b == nil {
+		return "$-1\r\n"
+	}
+	return "$" + strconv.Itoa(len(b)) + "\r\n" + string(b) + "\r\n"
+}
+
+func (s *respTestServer) exec(args []string, sub *respTestSubscriber) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := ""
+	if len(args) > 1 {
+		key = args[1]
+		if expire, ok := s.expires[key]; ok && time.Now().After(expire) {
+			delete(s.data, key)
+			delete(s.expires, key)
+		}
+	}
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		return bulk(s.data[key])
+	case "SET":
+		s.data[key] = []byte(args[2])
+		delete(s.expires, key)
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			s.expires[key] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := s.data[key]
+		delete(s.data, key)
+		delete(s.expires, key)
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "PTTL":
+		if _, ok := s.data[key]; !ok {
+			return ":-2\r\n"
+		}
+		expire, ok := s.expires[key]
+		if !ok {
+			return ":-1\r\n"
+		}
+		return ":" + strconv.FormatInt(time.Until(expire).Milliseconds(), 10) + "\r\n"
+	case "PUBLISH":
+		subs := s.subs[key]
+		for _, other := range subs {
+			go other.send("*3\r\n" + bulk([]byte("message")) + bulk([]byte(key)) + bulk([]byte(args[2])))
+		}
+		return ":" + strconv.Itoa(len(subs)) + "\r\n"
+	case "SUBSCRIBE":
+		s.subs[key] = append(s.subs[key], sub)
+		return "*3\r\n" + bulk([]byte("subscribe")) + bulk([]byte(key)) + ":1\r\n"
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+// 测试RESP协议的基本命令
+func TestRESPBackend(t *testing.T) {
+	server := newRESPTestServer(t)
+	backend := NewRESPBackend(server.addr())
+	defer backend.Close()
+	ctx := context.Background()
+
+	if _, ok, err := backend.Get(ctx, "missing"); err != nil || ok {
+		t.Fatalf("Expected miss, got ok=%v err=%v", ok, err)
+	}
+	if err := backend.Set(ctx, "key", []byte("value\r\nwith crlf"), time.Minute); err != nil {
+		t.Fatalf("Failed to set: %v", err)
+	}
+	if v, ok, err := backend.Get(ctx, "key"); err != nil || !ok || string(v) != "value\r\nwith crlf" {
+		t.Errorf("Expected value, got %q ok=%v err=%v", v, ok, err)
+	}
+	if ttl, ok, err := backend.TTL(ctx, "key"); err != nil || !ok || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected ttl within a minute, got %v ok=%v err=%v", ttl, ok, err)
+	}
+
+	_ = backend.Set(ctx, "forever", []byte("v"), 0)
+	if ttl, ok, _ := backend.TTL(ctx, "forever"); !ok || ttl != 0 {
+		t.Errorf("Expected no expiration, got %v ok=%v", ttl, ok)
+	}
+
+	if err := backend.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if _, ok, _ := backend.TTL(ctx, "key"); ok {
+		t.Error("Expected key to be deleted")
+	}
+
+	if _, err := backend.do(ctx, "UNKNOWN"); err == nil {
+		t.Error("Expected server error")
+	}
+	// 服务端错误不影响连接继续使用
+	if _, _, err := backend.Get(ctx, "forever"); err != nil {
+		t.Errorf("Expected connection to be reusable, got %v", err)
+	}
+}
+
+// 测试两级缓存的读写和跨实例失效
+func TestTieredCache(t *testing.T) {
+	silenceLog(t)
+	server := newRESPTestServer(t)
+	ctx := context.Background()
+
+	newNode := func() *TieredCache {
+		local := newLoaderTestManager(t)
+		backend := NewRESPBackend(server.addr())
+		tiered := NewTieredCache(local, backend, WithLocalTTL(time.Minute))
+		t.Cleanup(func() {
+			_ = tiered.Close()
+			_ = backend.Close()
+		})
+		return tiered
+	}
+	a, b := newNode(), newNode()
+
+	deadline := time.Now().Add(time.Second)
+	for server.subscribers(DefaultInvalidationChannel) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	user := typedTestUser{ID: 1, Name: "Alice"}
+	if err := a.Set(ctx, "user:1", user, time.Hour); err != nil {
+		t.Fatalf("Failed to set: %v", err)
+	}
+
+	// b的一级缓存未命中，从二级缓存读取并回填
+	var result typedTestUser
+	if ok, err := b.Get(ctx, "user:1", &result); err != nil || !ok || result.Name != "Alice" {
+		t.Fatalf("Expected Alice from remote, got %+v ok=%v err=%v", result, ok, err)
+	}
+	if _, ok := b.local.GetValue("user:1"); !ok {
+		t.Error("Expected local tier to be filled")
+	}
+
+	// a更新后b的一级缓存被删除
+	if err := a.Set(ctx, "user:1", typedTestUser{ID: 1, Name: "Bob"}, time.Hour); err != nil {
+		t.Fatalf("Failed to set: %v", err)
+	}
+	waitFor(t, func() bool {
+		_, ok := b.local.GetValue("user:1")
+		return !ok
+	})
+	if ok, _ := b.Get(ctx, "user:1", &result); !ok || result.Name != "Bob" {
+		t.Errorf("Expected Bob after invalidation, got %+v", result)
+	}
+
+	// 删除后两个实例都未命中
+	if err := b.Delete(ctx, "user:1"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	waitFor(t, func() bool {
+		_, ok := a.local.GetValue("user:1")
+		return !ok
+	})
+	if ok, _ := a.Get(ctx, "user:1", &result); ok {
+		t.Error("Expected miss after delete")
+	}
+
+	if _, err := a.Get(ctx, "user:1", result); err == nil {
+		t.Error("Expected error for non-pointer result")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met within 1s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
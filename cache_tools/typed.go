@@ -0,0 +1,75 @@
+package cache_tools
+
+import (
+	"fmt"
+	"time"
+)
+
+// TypedCache 类型安全的缓存视图
+// 值以原生 Go 类型保存在缓存中，读写都不会经过 JSON 序列化
+type TypedCache[K comparable, V any] struct {
+	manager   *CacheManager
+	namespace string // key前缀，用于区分同一个管理器下的不同TypedCache
+}
+
+// NewTypedCache 基于缓存管理器创建类型安全的缓存
+// namespace: key前缀，可以为空
+func NewTypedCache[K comparable, V any](manager *CacheManager, namespace string) *TypedCache[K, V] {
+	return &TypedCache[K, V]{
+		manager:   manager,
+		namespace: namespace,
+	}
+}
+
+// Set 设置缓存
+func (t *TypedCache[K, V]) Set(key K, value V) error {
+	return t.manager.SetValue(t.buildKey(key), value)
+}
+
+// SetWithTTL 设置带过期时间的缓存
+func (t *TypedCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) error {
+	return t.manager.SetValueWithTTL(t.buildKey(key), value, ttl)
+}
+
+// Get 获取缓存，未命中或者类型不匹配时返回零值和false
+func (t *TypedCache[K, V]) Get(key K) (V, bool) {
+	return GetValue[V](t.manager, t.buildKey(key))
+}
+
+// Delete 删除缓存
+func (t *TypedCache[K, V]) Delete(key K) error {
+	return t.manager.Delete(t.buildKey(key))
+}
+
+// DeleteAll 删除命名空间下的所有缓存，返回删除的数量
+// 没有命名空间时会删除管理器中的所有缓存
+func (t *TypedCache[K, V]) DeleteAll() int {
+	if t.namespace == "" {
+		return t.manager.DeletePrefix("")
+	}
+	return t.manager.DeletePrefix(t.namespace + ":")
+}
+
+func (t *TypedCache[K, V]) buildKey(key K) string {
+	if t.namespace == "" {
+		return fmt.Sprint(key)
+	}
+	return fmt.Sprintf("%s:%v", t.namespace, key)
+}
+
+// GetValue 从缓存管理器中获取指定类型的值
+// 未命中或者类型不匹配时返回零值和false
+func GetValue[V any](manager *CacheManager, key string) (V, bool) {
+	var zero V
+	v, ok := manager.GetValue(key)
+	if !ok {
+		return zero, false
+	}
+	return resolveValue[V](manager.cache, key, v)
+}
+
+// SetValue 向缓存管理器写入指定类型的值
+// ttl 为 0 表示永不过期
+func SetValue[V any](manager *CacheManager, key string, value V, ttl time.Duration) error {
+	return manager.SetValueWithTTL(key, value, ttl)
+}
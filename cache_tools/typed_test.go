@@ -0,0 +1,156 @@
+package cache_tools
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type typedTestUser struct {
+	ID    int
+	Name  string
+	Tags  []string
+	Attrs map[string]float64
+	Boss  *typedTestUser
+}
+
+// 测试类型安全缓存的读写
+func TestTypedCache(t *testing.T) {
+	manager := NewCacheManager()
+	if err := manager.Init(1024*1024, ""); err != nil {
+		t.Fatalf("Failed to init cache manager: %v", err)
+	}
+
+	users := NewTypedCache[int, *typedTestUser](manager, "user")
+	user := &typedTestUser{ID: 42, Name: "Alice", Tags: []string{"admin"}}
+	if err := users.Set(42, user); err != nil {
+		t.Fatalf("Failed to set typed value: %v", err)
+	}
+
+	result, ok := users.Get(42)
+	if !ok {
+		t.Fatal("Expected typed cache hit")
+	}
+	// 存的是原生值，取出来的应该是同一个指针
+	if result != user {
+		t.Errorf("Expected the same pointer, got %+v", result)
+	}
+
+	// 同一个key用不同的类型读取应该未命中
+	if _, ok := GetValue[string](manager, "user:42"); ok {
+		t.Error("Expected miss when reading with mismatched type")
+	}
+
+	if err := users.Delete(42); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if _, ok := users.Get(42); ok {
+		t.Error("Expected miss after delete")
+	}
+}
+
+// 测试类型安全缓存的过期时间
+func TestTypedCacheWithTTL(t *testing.T) {
+	manager := NewCacheManager()
+	if err := manager.Init(1024*1024, ""); err != nil {
+		t.Fatalf("Failed to init cache manager: %v", err)
+	}
+
+	counts := NewTypedCache[string, int](manager, "")
+	if err := counts.SetWithTTL("count", 7, time.Second); err != nil {
+		t.Fatalf("Failed to set typed value: %v", err)
+	}
+	if v, ok := counts.Get("count"); !ok || v != 7 {
+		t.Fatalf("Expected 7, got %d (hit=%v)", v, ok)
+	}
+
+	time.Sleep(2 * time.Second)
+	if _, ok := counts.Get("count"); ok {
+		t.Error("Expected miss after expiration")
+	}
+}
+
+// 测试配置了Codec之后的大小统计
+func TestTypedCacheSizeWithCodec(t *testing.T) {
+	manager := NewCacheManager()
+	if err := manager.Init(1024*1024, ""); err != nil {
+		t.Fatalf("Failed to init cache manager: %v", err)
+	}
+	manager.SetCodec(JSONCodec{})
+
+	if err := SetValue(manager, "nums", []int{1, 2, 3}, 0); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	// 按JSON编码后的长度统计: [1,2,3]
+	if size := manager.Stats().Size; size != 7 {
+		t.Errorf("Expected size 7, got %d", size)
+	}
+}
+
+// 测试各个Codec的编解码
+func TestCodecs(t *testing.T) {
+	original := typedTestUser{
+		ID:    1,
+		Name:  "Bob",
+		Tags:  []string{"a", "b"},
+		Attrs: map[string]float64{"score": 9.5},
+		Boss:  &typedTestUser{ID: 2, Name: "Carol", Tags: []string{"c"}, Attrs: map[string]float64{}},
+	}
+	for _, codec := range []Codec{JSONCodec{}, GobCodec{}, BinaryCodec{}} {
+		data, err := codec.Marshal(original)
+		if err != nil {
+			t.Fatalf("%s marshal failed: %v", codec.Name(), err)
+		}
+		var result typedTestUser
+		if err := codec.Unmarshal(data, &result); err != nil {
+			t.Fatalf("%s unmarshal failed: %v", codec.Name(), err)
+		}
+		// gob 会把空map解码成nil，统一后再比较
+		if result.Boss.Attrs == nil {
+			result.Boss.Attrs = map[string]float64{}
+		}
+		if !reflect.DeepEqual(original, result) {
+			t.Errorf("%s round trip mismatch: %+v != %+v", codec.Name(), original, result)
+		}
+	}
+}
+
+// 测试编码后不占字节的元素，长度可以超过剩余的字节数
+func TestBinaryCodecEmptyElements(t *testing.T) {
+	type empty struct {
+		hidden int
+	}
+	type value struct {
+		Slice  []struct{}
+		Array  [3]struct{}
+		Nested [][2]empty
+		Set    map[struct{}]struct{}
+		Tail   string
+	}
+	original := value{
+		Slice:  make([]struct{}, 5),
+		Nested: [][2]empty{{}, {}},
+		Set:    map[struct{}]struct{}{{}: {}},
+		Tail:   "end",
+	}
+	data, err := BinaryCodec{}.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result value
+	if err := (BinaryCodec{}).Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(original, result) {
+		t.Errorf("Round trip mismatch: %+v != %+v", original, result)
+	}
+
+	var slice []struct{}
+	if err := (BinaryCodec{}).Unmarshal([]byte{7}, &slice); err != nil || len(slice) != 7 {
+		t.Errorf("Expected 7 empty elements, got %d %v", len(slice), err)
+	}
+	var strs []string
+	if err := (BinaryCodec{}).Unmarshal([]byte{7}, &strs); err == nil {
+		t.Error("Expected invalid length error")
+	}
+}
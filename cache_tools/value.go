@@ -0,0 +1,129 @@
+package cache_tools
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ValueItem 保存原生 Go 值的缓存项，读写时不做任何序列化
+type ValueItem struct {
+	*Item
+}
+
+const TypeValue = "value"
+
+func NewValueItem() *ValueItem {
+	item := NewItem()
+	item.SetValueType(TypeValue)
+
+	return &ValueItem{
+		item,
+	}
+}
+
+// SetValue 设置值以及值的大小(字节)
+func (s *ValueItem) SetValue(v any, size int64) {
+	s.SetSize(size)
+	s.UpdateLastUsedTime()
+	s.Set(v)
+}
+
+func (s *ValueItem) GetValue() any {
+	return s.Item.value
+}
+
+func (s *ValueItem) GetItem() *Item {
+	return s.Item
+}
+
+// Load 将item类型加载为ValueItem类型
+func (s *ValueItem) Load(i *Item) error {
+	if i.GetValueType() != TypeValue {
+		return errors.New("load type error, want value but get a " + i.GetValueType())
+	}
+	s.Item = i
+	return nil
+}
+
+// maxSizeDepth 估算大小时最多递归的层数，防止循环引用
+const maxSizeDepth = 8
+
+// estimateSize 通过反射粗略估算一个值占用的内存大小(字节)
+func estimateSize(v any) int64 {
+	switch val := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(val))
+	case []byte:
+		return int64(len(val))
+	}
+	return estimateValueSize(reflect.ValueOf(v), 0)
+}
+
+func estimateValueSize(v reflect.Value, depth int) int64 {
+	if !v.IsValid() {
+		return 0
+	}
+	if depth > maxSizeDepth {
+		return int64(v.Type().Size())
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Type().Size()) + int64(v.Len())
+	case reflect.Slice, reflect.Array:
+		size := int64(v.Type().Size())
+		if v.Kind() == reflect.Array {
+			size = 0
+		}
+		elem := v.Type().Elem()
+		if isFixedSize(elem) {
+			return size + int64(v.Len())*int64(elem.Size())
+		}
+		for i := 0; i < v.Len(); i++ {
+			size += estimateValueSize(v.Index(i), depth+1)
+		}
+		return size
+	case reflect.Map:
+		size := int64(v.Type().Size())
+		iter := v.MapRange()
+		for iter.Next() {
+			size += estimateValueSize(iter.Key(), depth+1) + estimateValueSize(iter.Value(), depth+1)
+		}
+		return size
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return int64(v.Type().Size())
+		}
+		return int64(v.Type().Size()) + estimateValueSize(v.Elem(), depth+1)
+	case reflect.Struct:
+		if isFixedSize(v.Type()) {
+			return int64(v.Type().Size())
+		}
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += estimateValueSize(v.Field(i), depth+1)
+		}
+		return size
+	default:
+		return int64(v.Type().Size())
+	}
+}
+
+// isFixedSize 判断类型是否不包含任何引用(字符串、切片、指针等)
+func isFixedSize(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Pointer, reflect.Interface,
+		reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return false
+	case reflect.Array:
+		return isFixedSize(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !isFixedSize(t.Field(i).Type) {
+				return false
+			}
+		}
+	}
+	return true
+}
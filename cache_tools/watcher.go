@@ -1,7 +1,7 @@
 package cache_tools
 
 import (
-	"log"
+	"sync"
 	"time"
 )
 
@@ -18,24 +18,43 @@ type PlanTime struct {
 }
 
 type Watcher struct {
-	cache    *Cache    // 缓存对象指针
-	maxSize  int64     // 最大缓存限制
-	planTime *PlanTime // 定时清理缓存的时间点
-	time     *time.Ticker
+	cache     *Cache          // 缓存对象指针
+	maxSize   int64           // 最大缓存限制
+	planTime  *PlanTime       // 定时清理缓存的时间点
+	schedules []scheduleEntry // 定时执行的计划
+	clock     Clock           // 时间源，默认使用系统时间
+	limitCh   chan int        // 监听是否触发淘汰的channel，每个监视器独享
+
+	mu     sync.Mutex
+	done   chan struct{} // 关闭后所有监控协程退出
+	closed bool
+}
+
+// scheduleEntry 定时计划和触发时执行的操作
+type scheduleEntry struct {
+	schedule Schedule
+	action   ScheduleAction
 }
 
 // NewWatcher 创建监视器
+// 监视器会接管cache的淘汰信号，需要在写入数据之前创建
 func NewWatcher(cache *Cache) *Watcher {
-	return &Watcher{
+	w := &Watcher{
 		cache:   cache,
 		maxSize: 128 * SizeMB, // 默认128M
+		clock:   SystemClock(),
+		limitCh: make(chan int, 1),
+		done:    make(chan struct{}),
 	}
+	cache.limitCh = w.limitCh
+	return w
 }
 
 func (w *Watcher) SetMaxSize(size int64) {
 	w.maxSize = size
 }
 
+// SetClearPlanTime 设置每天清空缓存的时间点，等同于 AddSchedule(Daily(h, m, s), ClearAll())
 func (w *Watcher) SetClearPlanTime(h, m, s int) {
 	w.planTime = &PlanTime{
 		Hour: h,
@@ -44,78 +63,130 @@ func (w *Watcher) SetClearPlanTime(h, m, s int) {
 	}
 }
 
-// WatchClear 启动定时清理计划
+// AddSchedule 添加定时计划，到达计划时间时执行action，需要在 Start 之前调用
+func (w *Watcher) AddSchedule(schedule Schedule, action ScheduleAction) {
+	w.schedules = append(w.schedules, scheduleEntry{schedule: schedule, action: action})
+}
+
+// SetClock 设置时间源，需要在 Start 之前调用
+func (w *Watcher) SetClock(clock Clock) {
+	if clock == nil {
+		clock = SystemClock()
+	}
+	w.clock = clock
+}
+
+// Start 启动定时清理、大小限制和过期检查的监控协程
+func (w *Watcher) Start() {
+	if len(w.entries()) > 0 {
+		go w.WatchClear()
+	}
+	go w.WatchLimit()
+	go w.WatchExpiration()
+}
+
+// Stop 停止所有监控协程和定时器，可以重复调用
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	w.closed = true
+	close(w.done)
+}
+
+// entries 返回所有定时计划，SetClearPlanTime 设置的时间点作为每天清空缓存的计划
+func (w *Watcher) entries() []scheduleEntry {
+	entries := append([]scheduleEntry(nil), w.schedules...)
+	if w.planTime != nil {
+		entries = append(entries, scheduleEntry{
+			schedule: Daily(w.planTime.Hour, w.planTime.Min, w.planTime.Sec),
+			action:   ClearAll(),
+		})
+	}
+	return entries
+}
+
+// WatchClear 执行定时计划，直到 Stop 被调用或所有计划都不再执行
+// 每次执行后根据本次的计划时间重新计算下一次的时间，不会因为夏令时或执行耗时产生偏移
+// 休眠或时钟跳变错过的多次执行只补执行一次，之后从当前时间继续
 func (w *Watcher) WatchClear() {
-	if w.planTime == nil {
-		log.Println("watch cache error: time plan not config")
+	entries := w.entries()
+	if len(entries) == 0 {
+		w.cache.logger.Errorf("watch cache error: time plan not config")
 		return // 如果没有配置时间计划，直接返回，不启动定时清理
 	}
-	// 计算到下一个执行时间点的时间间隔
-	now := time.Now()
-	executionTime := time.Date(
-		now.Year(),
-		now.Month(),
-		now.Day(),
-		w.planTime.Hour,
-		w.planTime.Min,
-		w.planTime.Sec,
-		0, now.Location(),
-	)
-	duration := executionTime.Sub(now)
-	if duration < 0 {
-		next := now.Add(time.Hour * 24)
-		executionTime = time.Date(
-			next.Year(),
-			next.Month(),
-			next.Day(),
-			w.planTime.Hour,
-			w.planTime.Min,
-			w.planTime.Sec,
-			0, next.Location(),
-		)
-		duration = executionTime.Sub(now)
+	now := w.clock.Now()
+	next := make([]time.Time, len(entries))
+	for i, entry := range entries {
+		next[i] = entry.schedule.Next(now)
 	}
+	for {
+		var earliest time.Time
+		for _, t := range next {
+			if !t.IsZero() && (earliest.IsZero() || t.Before(earliest)) {
+				earliest = t
+			}
+		}
+		if earliest.IsZero() {
+			return
+		}
 
-	// 使用time.AfterFunc()在指定时间间隔后执行Clear()方法
-	time.AfterFunc(duration, func() {
-		w.cache.Clear()
-		// 之后每24小时执行一次
-		w.time = time.NewTicker(24 * time.Hour)
-		for range w.time.C {
-			w.cache.Clear()
+		timer := w.clock.NewTimer(earliest.Sub(w.clock.Now()))
+		select {
+		case <-w.done:
+			timer.Stop()
+			return
+		case <-timer.C():
 		}
-	})
+
+		for i, entry := range entries {
+			if next[i].IsZero() || next[i].After(earliest) {
+				continue
+			}
+			w.cache.logger.Infof("run cache schedule planned at %s", next[i].Format(time.RFC3339))
+			entry.action(w.cache, w.clock.Now())
+			next[i] = entry.schedule.Next(latest(next[i], w.clock.Now()))
+		}
+	}
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
 }
 
 func (w *Watcher) WatchLimit() {
 	for {
 		select {
-		case <-LimitCh:
+		case <-w.limitCh:
 			w.CheckSize()
+		case <-w.done:
+			return
 		}
 	}
 }
 
 func (w *Watcher) WatchExpiration() {
-	ticker := time.NewTicker(1 * time.Second)
-	for range ticker.C {
-		now := time.Now()
-		w.cache.data.Range(func(key, value interface{}) bool {
-			item := value.(*Item)
-			if !item.GetExpiration().IsZero() && item.GetExpiration().Before(now) {
-				log.Printf("cache expire:%s", key)
-				w.cache.data.Delete(key)
-				w.cache.size -= item.GetSize()
-				w.cache.updateKeyList(key)
-			}
-			return true
-		})
+	for {
+		timer := w.clock.NewTimer(1 * time.Second)
+		select {
+		case <-timer.C():
+			w.cache.removeExpired(w.clock.Now())
+		case <-w.done:
+			timer.Stop()
+			return
+		}
 	}
 }
 
 // CheckSize 检查缓存大小是否超过最大值
+// 超过时按淘汰策略逐个淘汰，直到缓存大小不超过最大值
 func (w *Watcher) CheckSize() {
-	if w.cache.size > w.maxSize {
-		w.cache.removeLeastAccessed() // 移除访问次数最少的缓存数据
+	if w.cache.Size() > w.maxSize {
+		w.cache.evictUntil(w.maxSize)
 	}
 }
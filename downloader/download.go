@@ -55,7 +55,9 @@ func DownloadFile(url string, filePath string, chunkCount int) error {
 	}()
 
 	// 获取文件大小和检查是否支持Range请求
-	client := &http.Client{Timeout: 30 * time.Second}
+	// 使用默认客户端，HEAD请求和之后的分块下载复用同一个连接池
+	client := http_tools.DefaultClient().HTTPClient()
+	client.Timeout = 30 * time.Second
 	resp, err := client.Head(url)
 	if err != nil {
 		return fmt.Errorf("failed to send HEAD request: %w", err)
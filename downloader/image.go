@@ -12,6 +12,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/otkinlife/go_tools/http_tools"
 	"github.com/otkinlife/go_tools/img"
 	_ "golang.org/x/image/webp" // 注册 WebP 格式解码器
 )
@@ -38,7 +39,7 @@ func DownloadImage(url, targetDir string) DImgRet {
 	}
 
 	// 发送 HTTP 请求获取图片
-	resp, err := http.Get(url)
+	resp, err := http_tools.DefaultClient().HTTPClient().Get(url)
 	if err != nil {
 		ret.Err = fmt.Errorf("failed to download image: %w", err)
 		return ret
@@ -6,21 +6,21 @@ toolchain go1.24.9
 
 require (
 	github.com/fatih/color v1.16.0
+	github.com/go-ego/gpy v0.42.1
 	github.com/golang-jwt/jwt/v4 v4.5.0
 	github.com/google/uuid v1.6.0
 	github.com/moul/http2curl v1.0.0
 	github.com/mozillazg/go-pinyin v0.20.0
 	github.com/sirupsen/logrus v1.9.3
 	github.com/spf13/cast v1.7.0
+	golang.org/x/image v0.32.0
 )
 
 require (
-	github.com/go-ego/gpy v0.42.1 // indirect
 	github.com/go-ego/gse v0.69.15 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/smartystreets/goconvey v1.8.1 // indirect
 	github.com/vcaesar/cedar v0.20.0 // indirect
-	golang.org/x/image v0.32.0 // indirect
 	golang.org/x/sys v0.14.0 // indirect
 )
@@ -0,0 +1,200 @@
+package http_tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断器打开时请求直接返回的错误
+var ErrCircuitOpen = errors.New("http_tools: circuit breaker is open")
+
+// CircuitState 熔断器状态
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // 正常放行请求
+	CircuitOpen                         // 直接拒绝请求
+	CircuitHalfOpen                     // 放行少量探测请求，成功后关闭熔断
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitBreaker 按host熔断的熔断器，可以在多个客户端之间共享，并发安全
+// 同一个host连续失败达到阈值后打开熔断，经过 openTimeout 后进入半开状态放行探测请求，
+// 探测成功后关闭熔断，探测失败则重新打开
+type CircuitBreaker struct {
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenProbes   int
+	successThreshold int
+	isFailure        func(resp *http.Response, err error) bool
+	now              func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*hostCircuit
+}
+
+// hostCircuit 单个host的熔断状态
+type hostCircuit struct {
+	state      CircuitState
+	generation uint64 // 每次状态变化加一，忽略状态变化之前发出的请求的结果
+	failures   int
+	successes  int
+	probes     int // 半开状态下正在进行的探测请求数量
+	openedAt   time.Time
+}
+
+// NewCircuitBreaker 创建熔断器
+// failureThreshold: 连续失败多少次后打开熔断
+// openTimeout: 熔断打开多久后进入半开状态
+// 默认网络错误和5xx状态码算作失败，半开状态同时只放行1个探测请求，探测成功1次即关闭熔断
+// 请求自身的context被取消或超时导致的错误不计入成功或失败
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		failureThreshold: max(failureThreshold, 1),
+		openTimeout:      openTimeout,
+		halfOpenProbes:   1,
+		successThreshold: 1,
+		isFailure:        defaultIsFailure,
+		now:              time.Now,
+		hosts:            make(map[string]*hostCircuit),
+	}
+}
+
+// SetHalfOpen 设置半开状态同时放行的探测请求数量和关闭熔断需要的成功次数
+func (b *CircuitBreaker) SetHalfOpen(probes, successThreshold int) *CircuitBreaker {
+	b.halfOpenProbes = max(probes, 1)
+	b.successThreshold = max(successThreshold, 1)
+	return b
+}
+
+// SetIsFailure 设置判断请求失败的函数
+func (b *CircuitBreaker) SetIsFailure(isFailure func(resp *http.Response, err error) bool) *CircuitBreaker {
+	b.isFailure = isFailure
+	return b
+}
+
+// State 返回host当前的熔断状态
+func (b *CircuitBreaker) State(host string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.hosts[host]
+	if !ok {
+		return CircuitClosed
+	}
+	b.advance(c)
+	return c.state
+}
+
+// Reset 重置所有host的熔断状态
+func (b *CircuitBreaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.hosts = make(map[string]*hostCircuit)
+}
+
+// allow 判断是否放行请求，放行时返回记录请求结果的函数，ctx为请求的context
+func (b *CircuitBreaker) allow(ctx context.Context, host string) (func(resp *http.Response, err error), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.hosts[host]
+	if !ok {
+		c = &hostCircuit{}
+		b.hosts[host] = c
+	}
+	b.advance(c)
+
+	switch c.state {
+	case CircuitOpen:
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+	case CircuitHalfOpen:
+		if c.probes >= b.halfOpenProbes {
+			return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+		}
+		c.probes++
+	}
+	generation := c.generation
+	return func(resp *http.Response, err error) {
+		if ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+			// 调用方取消了请求，不能说明上游不可用
+			b.release(c, generation)
+			return
+		}
+		b.record(c, generation, b.isFailure(resp, err))
+	}, nil
+}
+
+// release 请求结果不计入统计，半开状态下归还探测名额
+func (b *CircuitBreaker) release(c *hostCircuit, generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c.generation == generation && c.state == CircuitHalfOpen {
+		c.probes--
+	}
+}
+
+// record 记录请求结果并更新状态
+func (b *CircuitBreaker) record(c *hostCircuit, generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c.generation != generation {
+		return
+	}
+	switch c.state {
+	case CircuitClosed:
+		if !failed {
+			c.failures = 0
+			return
+		}
+		c.failures++
+		if c.failures >= b.failureThreshold {
+			b.transition(c, CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		c.probes--
+		if failed {
+			b.transition(c, CircuitOpen)
+			return
+		}
+		c.successes++
+		if c.successes >= b.successThreshold {
+			b.transition(c, CircuitClosed)
+		}
+	}
+}
+
+// advance 熔断打开超过 openTimeout 后进入半开状态
+func (b *CircuitBreaker) advance(c *hostCircuit) {
+	if c.state == CircuitOpen && !b.now().Before(c.openedAt.Add(b.openTimeout)) {
+		b.transition(c, CircuitHalfOpen)
+	}
+}
+
+func (b *CircuitBreaker) transition(c *hostCircuit, state CircuitState) {
+	c.state = state
+	c.generation++
+	c.failures = 0
+	c.successes = 0
+	c.probes = 0
+	if state == CircuitOpen {
+		c.openedAt = b.now()
+	}
+}
+
+func defaultIsFailure(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
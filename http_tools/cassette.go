@@ -0,0 +1,511 @@
+package http_tools
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNoInteraction 回放时没有找到匹配的录制记录
+var ErrNoInteraction = errors.New("http_tools: no recorded interaction matches the request")
+
+// RecorderMode 录制器的工作模式
+type RecorderMode int
+
+const (
+	// ModeReplay 只回放，没有匹配的记录时返回 ErrNoInteraction，不会发送真实请求
+	ModeReplay RecorderMode = iota
+	// ModeRecord 总是发送真实请求并重新录制，保存时覆盖原文件
+	ModeRecord
+	// ModeReplayOrRecord 有匹配的记录时回放，否则发送真实请求并追加录制
+	ModeReplayOrRecord
+)
+
+func (m RecorderMode) String() string {
+	switch m {
+	case ModeReplay:
+		return "replay"
+	case ModeRecord:
+		return "record"
+	case ModeReplayOrRecord:
+		return "replay_or_record"
+	default:
+		return fmt.Sprintf("RecorderMode(%d)", int(m))
+	}
+}
+
+// RecordedRequest 录制的请求
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"-"`
+}
+
+// RecordedResponse 录制的响应
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"-"`
+}
+
+// Interaction 一次请求和响应
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// cassette 录制文件的格式，请求体和响应体是文本时直接保存，否则保存为base64
+type cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+type bodyJSON struct {
+	Body       string `json:"body,omitempty"`
+	BodyBase64 string `json:"body_base64,omitempty"`
+}
+
+func encodeBody(body []byte) bodyJSON {
+	if utf8.Valid(body) {
+		return bodyJSON{Body: string(body)}
+	}
+	return bodyJSON{BodyBase64: base64.StdEncoding.EncodeToString(body)}
+}
+
+func (b bodyJSON) decode() ([]byte, error) {
+	if b.BodyBase64 != "" {
+		return base64.StdEncoding.DecodeString(b.BodyBase64)
+	}
+	return []byte(b.Body), nil
+}
+
+func (r RecordedRequest) MarshalJSON() ([]byte, error) {
+	type plain RecordedRequest
+	return json.Marshal(struct {
+		plain
+		bodyJSON
+	}{plain(r), encodeBody(r.Body)})
+}
+
+func (r *RecordedRequest) UnmarshalJSON(data []byte) error {
+	type plain RecordedRequest
+	var v struct {
+		plain
+		bodyJSON
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	body, err := v.bodyJSON.decode()
+	if err != nil {
+		return err
+	}
+	*r = RecordedRequest(v.plain)
+	r.Body = body
+	return nil
+}
+
+func (r RecordedResponse) MarshalJSON() ([]byte, error) {
+	type plain RecordedResponse
+	return json.Marshal(struct {
+		plain
+		bodyJSON
+	}{plain(r), encodeBody(r.Body)})
+}
+
+func (r *RecordedResponse) UnmarshalJSON(data []byte) error {
+	type plain RecordedResponse
+	var v struct {
+		plain
+		bodyJSON
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	body, err := v.bodyJSON.decode()
+	if err != nil {
+		return err
+	}
+	*r = RecordedResponse(v.plain)
+	r.Body = body
+	return nil
+}
+
+// Matcher 判断请求是否和录制的请求匹配，body 为请求体
+type Matcher func(req *http.Request, body []byte, recorded *RecordedRequest) bool
+
+// MatchMethod 请求方法相同
+func MatchMethod(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return req.Method == recorded.Method
+}
+
+// MatchURL 完整URL相同，查询参数的顺序不影响匹配
+func MatchURL(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return sameURL(req, recorded, true)
+}
+
+// MatchPath 路径和查询参数相同，忽略协议和域名，适合录制和回放的服务地址不同的场景
+func MatchPath(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return sameURL(req, recorded, false)
+}
+
+func sameURL(req *http.Request, recorded *RecordedRequest, withHost bool) bool {
+	u, err := req.URL.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	if withHost && (u.Scheme != req.URL.Scheme || u.Host != req.URL.Host) {
+		return false
+	}
+	return u.Path == req.URL.Path && reflect.DeepEqual(u.Query(), req.URL.Query())
+}
+
+// MatchBody 请求体相同，两边都是JSON时按JSON比较，忽略字段顺序和空白
+func MatchBody(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return sameBody(body, recorded.Body)
+}
+
+// sameBody 比较请求体，两边都是JSON时按JSON比较
+func sameBody(x, y []byte) bool {
+	if bytes.Equal(x, y) {
+		return true
+	}
+	var a, b any
+	if json.Unmarshal(x, &a) != nil || json.Unmarshal(y, &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// MatchHeaders 指定的请求头相同
+func MatchHeaders(names ...string) Matcher {
+	return func(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+		for _, name := range names {
+			if !slices.Equal(req.Header.Values(name), recorded.Header.Values(name)) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// RedactedValue 保存时替换敏感请求头和查询参数的值
+const RedactedValue = "REDACTED"
+
+// Recorder 录制和回放HTTP请求的 RoundTripper，用于测试时不访问真实服务
+// 录制的请求和响应保存为JSON文件，回放时按 Matcher 查找，每条记录只使用一次，按录制的顺序查找
+type Recorder struct {
+	path       string
+	mode       RecorderMode
+	transport  http.RoundTripper
+	matchers   []Matcher
+	redact     []string
+	redactQ    []string
+	beforeSave func(*Interaction)
+	mu         sync.Mutex
+	cassette   cassette
+	used       []bool
+	changed    bool
+}
+
+// NewRecorder 创建录制器，path 为录制文件的路径
+// ModeReplay 时文件必须存在，ModeReplayOrRecord 时文件存在则加载，ModeRecord 时忽略已有的文件
+// 默认按请求方法和URL匹配，发送真实请求使用 DefaultClient 的连接池
+func NewRecorder(path string, mode RecorderMode) (*Recorder, error) {
+	r := &Recorder{
+		path:     path,
+		mode:     mode,
+		matchers: []Matcher{MatchMethod, MatchURL},
+		redact:   []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+		redactQ:  []string{"key", "api_key", "access_token", "token"},
+	}
+	if mode == ModeRecord {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if mode == ModeReplayOrRecord && os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("http_tools: load cassette %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// SetMatchers 设置匹配规则，所有规则都满足时才算匹配
+func (r *Recorder) SetMatchers(matchers ...Matcher) *Recorder {
+	r.matchers = matchers
+	return r
+}
+
+// SetTransport 设置录制时发送真实请求的 RoundTripper
+func (r *Recorder) SetTransport(transport http.RoundTripper) *Recorder {
+	r.transport = transport
+	return r
+}
+
+// SetRedactHeaders 设置保存时需要隐藏的请求头和响应头，默认隐藏 Authorization、Cookie 等
+func (r *Recorder) SetRedactHeaders(names ...string) *Recorder {
+	r.redact = names
+	return r
+}
+
+// SetRedactQuery 设置保存时需要隐藏的查询参数，参数名不区分大小写，默认隐藏 key、api_key、access_token、token
+// 匹配时两边的这些参数都按隐藏后的值比较，回放时参数值不同也能匹配
+func (r *Recorder) SetRedactQuery(names ...string) *Recorder {
+	r.redactQ = names
+	return r
+}
+
+// SetBeforeSave 设置保存前对每条记录的处理，例如去掉响应中的token
+func (r *Recorder) SetBeforeSave(fn func(*Interaction)) *Recorder {
+	r.beforeSave = fn
+	return r
+}
+
+// Mode 返回工作模式
+func (r *Recorder) Mode() RecorderMode {
+	return r.mode
+}
+
+// Interactions 返回已加载和录制的记录
+func (r *Recorder) Interactions() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.cassette.Interactions)
+}
+
+// Client 返回使用该录制器发送请求的客户端
+func (r *Recorder) Client() *Client {
+	return &Client{transport: r}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode != ModeRecord {
+		if interaction := r.match(req, body); interaction != nil {
+			return interaction.Response.toHTTP(req), nil
+		}
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL)
+		}
+	}
+	return r.record(req, body)
+}
+
+// match 按顺序查找第一条未使用的匹配记录
+func (r *Recorder) match(req *http.Request, body []byte) *Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if redacted := redactURL(req.URL.String(), r.redactQ); redacted != req.URL.String() {
+		u, err := url.Parse(redacted)
+		if err == nil {
+			req = req.Clone(req.Context())
+			req.URL = u
+		}
+	}
+	for i, interaction := range r.cassette.Interactions {
+		recorded := interaction.Request
+		recorded.URL = redactURL(recorded.URL, r.redactQ)
+		if r.used[i] || !r.matches(req, body, &recorded) {
+			continue
+		}
+		r.used[i] = true
+		return interaction
+	}
+	return nil
+}
+
+func (r *Recorder) matches(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+	for _, matcher := range r.matchers {
+		if !matcher(req, body, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+// record 发送真实请求，读取完整的响应体后录制
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	transport := r.transport
+	if transport == nil {
+		transport = DefaultClient().Transport()
+	}
+	outgoing := req.Clone(req.Context())
+	if req.Body != nil {
+		outgoing.Body = io.NopCloser(bytes.NewReader(body))
+		outgoing.ContentLength = int64(len(body))
+	}
+	resp, err := transport.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: req.Header.Clone(),
+			Body:   body,
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     resp.Header.Clone(),
+			Body:       respBody,
+		},
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.used = append(r.used, true)
+	r.changed = true
+	r.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	resp.ContentLength = int64(len(respBody))
+	return resp, nil
+}
+
+// Save 保存录制的记录，没有新的录制时不写文件
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.changed {
+		return nil
+	}
+
+	out := cassette{Interactions: make([]*Interaction, 0, len(r.cassette.Interactions))}
+	for _, interaction := range r.cassette.Interactions {
+		c := *interaction
+		c.Request.URL = redactURL(c.Request.URL, r.redactQ)
+		c.Request.Header = redactHeader(c.Request.Header, r.redact)
+		c.Response.Header = redactHeader(c.Response.Header, r.redact)
+		if r.beforeSave != nil {
+			r.beforeSave(&c)
+		}
+		out.Interactions = append(out.Interactions, &c)
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	r.changed = false
+	return nil
+}
+
+// Unused 返回还没有被回放的记录，可以用来检查测试是否发出了所有预期的请求
+func (r *Recorder) Unused() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []*Interaction
+	for i, interaction := range r.cassette.Interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+func (r RecordedResponse) toHTTP(req *http.Request) *http.Response {
+	status := r.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode))
+	}
+	header := r.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        status,
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// readRequestBody 读取请求体，之后请求体不能再使用
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return io.ReadAll(req.Body)
+}
+
+// redactURL 替换URL中指定查询参数的值，没有需要隐藏的参数时原样返回
+func redactURL(rawURL string, names []string) string {
+	if len(names) == 0 {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+	query := u.Query()
+	changed := false
+	for key, values := range query {
+		if !slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(name, key) }) {
+			continue
+		}
+		for i := range values {
+			values[i] = RedactedValue
+		}
+		changed = true
+	}
+	if !changed {
+		return rawURL
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func redactHeader(header http.Header, names []string) http.Header {
+	if len(header) == 0 {
+		return header
+	}
+	header = header.Clone()
+	for _, name := range names {
+		if _, ok := header[http.CanonicalHeaderKey(name)]; ok {
+			header.Set(name, RedactedValue)
+		}
+	}
+	return header
+}
@@ -0,0 +1,176 @@
+package http_tools
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 测试保存时隐藏敏感的查询参数，回放时参数值不同也能匹配
+func TestRecorderRedactQuery(t *testing.T) {
+	mock := NewMockServer(t)
+	mock.Expect("GET", "/search").Respond(200, "found")
+	mock.Expect("GET", "/sign").Respond(200, "signed")
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, _ := NewRecorder(path, ModeRecord)
+	for _, target := range []string{"/search?q=go&api_key=secret1&Token=secret2", "/sign?sig=secret3"} {
+		req, _ := recorder.Client().NewRequest("GET", mock.URL()+target)
+		if err := req.Send(); err != nil {
+			t.Fatal(err)
+		}
+		req.Close()
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	mock.Close()
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "secret1") || strings.Contains(string(data), "secret2") || !strings.Contains(string(data), "q=go") {
+		t.Errorf("Expected api_key and token to be redacted:\n%s", data)
+	}
+
+	replay, _ := NewRecorder(path, ModeReplay)
+	replay.SetRedactQuery("api_key", "token", "sig")
+	for target, want := range map[string]string{
+		"/search?api_key=other&q=go&Token=other": "found",
+		"/sign?sig=other":                        "signed",
+	} {
+		req, _ := replay.Client().NewRequest("GET", mock.URL()+target)
+		body, _, err := Do[string](req)
+		if err != nil || body != want {
+			t.Errorf("%s: expected %q, got %q %v", target, want, body, err)
+		}
+	}
+	// 没有隐藏的参数仍然参与匹配
+	req, _ := replay.Client().NewRequest("GET", mock.URL()+"/search?q=rust&api_key=secret1&token=secret2")
+	if err := req.Send(); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("Expected ErrNoInteraction, got %v", err)
+	}
+}
+
+// 测试录制之后可以离线回放，敏感请求头不会保存
+func TestRecorderRecordAndReplay(t *testing.T) {
+	mock := NewMockServer(t)
+	mock.Expect("POST", "/echo").WithJSONBody(map[string]int{"n": 1}).Respond(200, "one")
+	mock.Expect("POST", "/echo").WithJSONBody(map[string]int{"n": 2}).Respond(200, "two")
+	mock.Expect("GET", "/binary").Respond(200, "\xff\xfe\x00")
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, err := NewRecorder(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := recorder.Client()
+	for _, n := range []string{`{"n":1}`, `{"n":2}`} {
+		req, _ := client.NewRequest("POST", mock.URL()+"/echo")
+		req.SetJson(json.RawMessage(n))
+		req.SetHeaders(map[string]string{"Authorization": "Bearer secret"})
+		if err := req.Send(); err != nil {
+			t.Fatal(err)
+		}
+		req.Close()
+	}
+	req, _ := client.NewRequest("GET", mock.URL()+"/binary")
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	req.Close()
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "secret") || !strings.Contains(string(data), RedactedValue) {
+		t.Errorf("Expected Authorization to be redacted:\n%s", data)
+	}
+	mock.Close()
+
+	// 服务已经关闭，只能从文件回放，请求体不同的请求按顺序匹配
+	replay, err := NewRecorder(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay.SetMatchers(MatchMethod, MatchURL, MatchBody)
+	for _, c := range []struct{ method, path, body, want string }{
+		{"POST", "/echo", `{ "n": 2 }`, "two"},
+		{"POST", "/echo", `{"n":1}`, "one"},
+		{"GET", "/binary", "", "\xff\xfe\x00"},
+	} {
+		req, _ := replay.Client().NewRequest(c.method, mock.URL()+c.path)
+		if c.body != "" {
+			req.SetJson(json.RawMessage(c.body))
+		}
+		body, _, err := Do[string](req)
+		if err != nil || body != c.want {
+			t.Errorf("%s %s: expected %q, got %q %v", c.method, c.path, c.want, body, err)
+		}
+	}
+	if len(replay.Unused()) != 0 {
+		t.Errorf("Expected all interactions to be used")
+	}
+
+	// 每条记录只回放一次
+	req, _ = replay.Client().NewRequest("GET", mock.URL()+"/binary")
+	if err := req.Send(); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("Expected ErrNoInteraction, got %v", err)
+	}
+}
+
+// 测试 ModeReplayOrRecord 只录制缺少的请求
+func TestRecorderReplayOrRecord(t *testing.T) {
+	mock := NewMockServer(t)
+	mock.Expect("GET", "/a").Respond(200, "a")
+	mock.Expect("GET", "/b").Respond(200, "b")
+
+	path := filepath.Join(t.TempDir(), "nested", "cassette.json")
+	for i := 0; i < 2; i++ {
+		recorder, err := NewRecorder(path, ModeReplayOrRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []string{"/a", "/b"}[:i+1] {
+			req, _ := recorder.Client().NewRequest("GET", mock.URL()+p)
+			if body, _, err := Do[string](req); err != nil || body != p[1:] {
+				t.Errorf("Expected %s, got %q %v", p[1:], body, err)
+			}
+		}
+		if err := recorder.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder, _ := NewRecorder(path, ModeReplay)
+	if n := len(recorder.Interactions()); n != 2 {
+		t.Errorf("Expected 2 interactions, got %d", n)
+	}
+	if _, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), ModeReplay); !os.IsNotExist(err) {
+		t.Errorf("Expected missing cassette error, got %v", err)
+	}
+}
+
+// 测试请求头匹配和MatchPath忽略域名
+func TestRecorderMatchers(t *testing.T) {
+	recorded := &RecordedRequest{
+		Method: "GET",
+		URL:    "https://api.example.com/v1/items?b=2&a=1",
+		Header: http.Header{"X-Tenant": {"t1"}},
+	}
+	req, _ := http.NewRequest("GET", "http://127.0.0.1:8080/v1/items?a=1&b=2", nil)
+	req.Header.Set("X-Tenant", "t1")
+	if MatchURL(req, nil, recorded) {
+		t.Error("Expected MatchURL to compare host")
+	}
+	if !MatchPath(req, nil, recorded) || !MatchHeaders("X-Tenant")(req, nil, recorded) {
+		t.Error("Expected path and header to match")
+	}
+	req.Header.Set("X-Tenant", "t2")
+	if MatchHeaders("X-Tenant")(req, nil, recorded) {
+		t.Error("Expected different header not to match")
+	}
+}
@@ -1,15 +1,10 @@
 package http_tools
 
 import (
-	"bytes"
-	"fmt"
-	"io"
+	"context"
 	"log"
-	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
+	"time"
 )
 
 type ReqClient struct {
@@ -19,24 +14,25 @@ type ReqClient struct {
 	isPrintCurl bool
 	formFields  map[string]string
 	files       []fileField
-}
+	retry       *RetryPolicy       // 重试策略，为空时不重试
+	breaker     *CircuitBreaker    // 熔断器，为空时不熔断
+	middlewares []Middleware       // 中间件，客户端的中间件在前
+	deadline    time.Time          // 请求的截止时间，为零值时不限制
+	cancel      context.CancelFunc // 取消截止时间的context，在 Close 时调用
 
-type fileField struct {
-	fieldName string
-	filePath  string
+	uploadProgress UploadProgress // 上传进度回调
 }
 
+// NewReqClient 使用默认客户端创建请求，所有请求共享默认客户端的连接池
+// 需要代理、TLS证书等配置时，使用 NewClientBuilder 创建 Client 后调用 Client.NewRequest
 func NewReqClient(method, url string) (*ReqClient, error) {
-	req, err := http.NewRequest(method, url, nil)
-	if err != nil {
-		return nil, err
-	}
-	return &ReqClient{
-		client:     &http.Client{},
-		req:        req,
-		formFields: make(map[string]string),
-		files:      make([]fileField, 0),
-	}, nil
+	return DefaultClient().NewRequest(method, url)
+}
+
+// NewReqClientWithContext 使用默认客户端创建带context的请求
+// context取消或超时后，正在进行的请求、重试等待和文件上传都会中止
+func NewReqClientWithContext(ctx context.Context, method, url string) (*ReqClient, error) {
+	return DefaultClient().NewRequestWithContext(ctx, method, url)
 }
 
 // Send 发送请求
@@ -44,23 +40,21 @@ func NewReqClient(method, url string) (*ReqClient, error) {
 // urlStr: 请求地址
 // return: 错误
 func (r *ReqClient) Send() error {
+	if !r.deadline.IsZero() && r.cancel == nil {
+		ctx, cancel := context.WithDeadline(r.req.Context(), r.deadline)
+		r.req = r.req.WithContext(ctx)
+		r.cancel = cancel
+	}
+
 	// 构建multipart请求体
 	if err := r.buildMultipartRequest(); err != nil {
+		r.release()
 		return err
 	}
 
-	var err error
-	if r.isPrintCurl {
-		// 打印curl命令，使用文件路径格式
-		curlCmd, err := r.ConvertToCurlWithFiles()
-		if err != nil {
-			log.Printf("Failed to generate curl command: %v", err)
-		} else {
-			log.Println(curlCmd)
-		}
-	}
-	resp, err := r.client.Do(r.req)
+	resp, err := r.doWithRetry()
 	if err != nil {
+		r.release()
 		return err
 	}
 	r.response = resp
@@ -75,105 +69,33 @@ func (r *ReqClient) Close() {
 			log.Println(err)
 		}
 	}
+	r.release()
 }
 
-// buildMultipartRequest 构建multipart请求体
-func (r *ReqClient) buildMultipartRequest() error {
-	if len(r.formFields) == 0 && len(r.files) == 0 {
-		return nil
-	}
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	// 添加表单字段
-	for key, value := range r.formFields {
-		err := writer.WriteField(key, value)
-		if err != nil {
-			return err
-		}
-	}
-
-	// 添加文件
-	for _, fileField := range r.files {
-		file, err := os.Open(fileField.filePath)
-		if err != nil {
-			return err
-		}
-
-		part, err := writer.CreateFormFile(fileField.fieldName, filepath.Base(fileField.filePath))
-		if err != nil {
-			_ = file.Close()
-			return err
-		}
-
-		_, err = io.Copy(part, file)
-		_ = file.Close()
-		if err != nil {
-			return err
-		}
-	}
-
-	err := writer.Close()
-	if err != nil {
-		return err
+// release 释放截止时间的context，之后读取响应体会返回错误
+func (r *ReqClient) release() {
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
 	}
+}
 
-	r.req.Body = io.NopCloser(body)
-	r.req.Header.Set("Content-Type", writer.FormDataContentType())
-	return nil
+// Context 返回请求的context
+func (r *ReqClient) Context() context.Context {
+	return r.req.Context()
 }
 
 // ConvertToCurlWithFiles 将请求转换为curl命令，对文件使用@filepath格式
 func (r *ReqClient) ConvertToCurlWithFiles() (string, error) {
-	var parts []string
-
-	// 基础curl命令
-	parts = append(parts, "curl")
-
-	// 请求方法
-	if r.req.Method != "GET" {
-		parts = append(parts, "-X", r.req.Method)
-	}
-
-	// URL
-	parts = append(parts, fmt.Sprintf("'%s'", r.req.URL.String()))
-
-	// 请求头
-	for key, values := range r.req.Header {
-		// 跳过Content-Type，我们会手动设置
-		if key == "Content-Type" {
-			continue
-		}
-		for _, value := range values {
-			parts = append(parts, "-H", fmt.Sprintf("'%s: %s'", key, value))
-		}
-	}
-
-	// 处理表单数据和文件
-	if len(r.formFields) > 0 || len(r.files) > 0 {
-		// 添加表单字段
-		for key, value := range r.formFields {
-			parts = append(parts, "-F", fmt.Sprintf("'%s=%s'", key, value))
-		}
-
-		// 添加文件（使用@filepath格式）
-		for _, file := range r.files {
-			parts = append(parts, "-F", fmt.Sprintf("'%s=@%s'", file.fieldName, file.filePath))
-		}
-	} else if r.req.Body != nil {
-		// 如果有其他类型的请求体，使用--data-raw
-		bodyBytes, err := io.ReadAll(r.req.Body)
-		if err != nil {
-			return "", err
-		}
-		// 重置请求体
-		r.req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	return buildCurlCommand(r.req, r.formFields, r.files)
+}
 
-		if len(bodyBytes) > 0 {
-			parts = append(parts, "--data-raw", fmt.Sprintf("'%s'", string(bodyBytes)))
-		}
-	}
+// ConvertToHTTPie 将请求转换为 HTTPie 命令
+func (r *ReqClient) ConvertToHTTPie() (string, error) {
+	return buildHTTPieCommand(r.req, r.formFields, r.files)
+}
 
-	return strings.Join(parts, " "), nil
+// ConvertToGo 将请求转换为使用 http_tools 发送请求的Go代码
+func (r *ReqClient) ConvertToGo() (string, error) {
+	return buildGoCode(r.req, r.client.Timeout, r.formFields, r.files)
 }
@@ -0,0 +1,120 @@
+package http_tools
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试取消正在进行的请求
+func TestCancelInFlight(t *testing.T) {
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := NewReqClientWithContext(ctx, "GET", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		<-started
+		cancel()
+	}()
+	start := time.Now()
+	err = req.Send()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Expected cancel to abort the request")
+	}
+}
+
+// 测试截止时间覆盖重试
+func TestDeadlineStopsRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	req, _ := NewReqClient("GET", server.URL)
+	req.SetRetryPolicy(NewRetryPolicy(100).SetBackoff(20*time.Millisecond, 20*time.Millisecond).SetJitter(0))
+	req.SetDeadline(time.Now().Add(100 * time.Millisecond))
+	start := time.Now()
+	err := req.Send()
+	defer req.Close()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected retries to stop at the deadline, took %v", elapsed)
+	}
+	if n := calls.Load(); n < 2 || n > 10 {
+		t.Errorf("Unexpected number of attempts %d", n)
+	}
+}
+
+// 测试context取消后不再上传文件
+func TestCancelMultipartUpload(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	tmpFile, err := os.CreateTemp(t.TempDir(), "upload_*.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = tmpFile.Write(make([]byte, 1024))
+	tmpFile.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := NewReqClient("POST", server.URL)
+	req.SetContext(ctx)
+	if err := req.SetFile("file", tmpFile.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Send(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Error("Expected request not to be sent")
+	}
+	if req.Context() != ctx {
+		t.Error("Expected request to use the given context")
+	}
+}
+
+// 测试截止时间在 Close 之前不影响读取响应体
+func TestDeadlineReleasedOnClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	req, _ := NewReqClient("GET", server.URL)
+	req.SetDeadline(time.Now().Add(time.Minute))
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if body := req.GetBodyString(); body != "ok" {
+		t.Errorf("Expected ok, got %q", body)
+	}
+	req.Close()
+	if req.Context().Err() == nil {
+		t.Error("Expected deadline context to be released on Close")
+	}
+}
@@ -0,0 +1,552 @@
+package http_tools
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ParseCurl 使用默认客户端将 curl 命令解析为请求，例如浏览器开发者工具中"复制为 cURL (bash)"的命令
+// 支持 -X、-H、-d/--data-raw/--data-binary/--data-urlencode/--json、-F、-u、-b、-A、-e、-G、-I、-m、--compressed、-k
+// 以及单引号、双引号、$'...'、反斜杠转义和换行续行
+func ParseCurl(command string) (*ReqClient, error) {
+	return DefaultClient().ParseCurl(command)
+}
+
+// ParseCurl 将 curl 命令解析为使用该客户端连接池的请求
+func (c *Client) ParseCurl(command string) (*ReqClient, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+	cmd, err := parseCurlArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return cmd.build(c)
+}
+
+// curlOption curl 选项的名称和是否需要参数
+type curlOption struct {
+	name   string
+	hasArg bool
+}
+
+var curlShortOptions = map[byte]curlOption{
+	'X': {"request", true},
+	'H': {"header", true},
+	'd': {"data", true},
+	'F': {"form", true},
+	'u': {"user", true},
+	'b': {"cookie", true},
+	'A': {"user-agent", true},
+	'e': {"referer", true},
+	'm': {"max-time", true},
+	'o': {"output", true},
+	'w': {"write-out", true},
+	'G': {"get", false},
+	'I': {"head", false},
+	'k': {"insecure", false},
+	's': {"silent", false},
+	'S': {"show-error", false},
+	'v': {"verbose", false},
+	'i': {"include", false},
+	'L': {"location", false},
+	'f': {"fail", false},
+	'N': {"no-buffer", false},
+}
+
+var curlLongOptions = map[string]bool{
+	"request": true, "header": true, "data": true, "data-ascii": true, "data-raw": true,
+	"data-binary": true, "data-urlencode": true, "json": true, "form": true, "form-string": true,
+	"user": true, "cookie": true, "user-agent": true, "referer": true, "max-time": true, "url": true,
+	"output": true, "write-out": true, "connect-timeout": true, "max-redirs": true, "retry": true,
+	"get": false, "head": false, "insecure": false, "compressed": false, "silent": false,
+	"show-error": false, "verbose": false, "include": false, "location": false, "fail": false,
+	"no-buffer": false, "http1.1": false, "http2": false,
+}
+
+// curlCommand 解析后的 curl 参数
+type curlCommand struct {
+	method     string
+	url        string
+	header     http.Header
+	data       []string
+	json       bool
+	forms      []curlForm
+	user       *url.Userinfo
+	get        bool
+	head       bool
+	insecure   bool
+	compressed bool
+	timeout    time.Duration
+}
+
+type curlForm struct {
+	value  string
+	string bool // --form-string，值不解析@和<
+}
+
+// parseCurlArgs 解析 curl 的参数，不支持的选项返回错误，避免参数错位后得到错误的请求
+func parseCurlArgs(args []string) (*curlCommand, error) {
+	cmd := &curlCommand{header: make(http.Header)}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		// 取选项的参数，参数可以紧跟在短选项后面，例如 -XPOST
+		next := func(name, attached string) (string, error) {
+			if attached != "" {
+				return attached, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("http_tools: curl option --%s requires an argument", name)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch {
+		case strings.HasPrefix(arg, "--") && len(arg) > 2:
+			name, attached, _ := strings.Cut(arg[2:], "=")
+			hasArg, ok := curlLongOptions[name]
+			if !ok {
+				return nil, fmt.Errorf("http_tools: unsupported curl option %s", arg)
+			}
+			if !hasArg {
+				attached = ""
+			}
+			value := ""
+			if hasArg {
+				v, err := next(name, attached)
+				if err != nil {
+					return nil, err
+				}
+				value = v
+			}
+			if err := cmd.apply(name, value); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// 短选项可以合并，例如 -sSk
+			for j := 1; j < len(arg); j++ {
+				opt, ok := curlShortOptions[arg[j]]
+				if !ok {
+					return nil, fmt.Errorf("http_tools: unsupported curl option -%c", arg[j])
+				}
+				value := ""
+				if opt.hasArg {
+					v, err := next(opt.name, arg[j+1:])
+					if err != nil {
+						return nil, err
+					}
+					value = v
+					j = len(arg)
+				}
+				if err := cmd.apply(opt.name, value); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			if cmd.url != "" {
+				return nil, fmt.Errorf("http_tools: curl command has more than one URL: %s", arg)
+			}
+			cmd.url = arg
+		}
+	}
+	if cmd.url == "" {
+		return nil, errors.New("http_tools: curl command has no URL")
+	}
+	return cmd, nil
+}
+
+func (cmd *curlCommand) apply(name, value string) error {
+	switch name {
+	case "request":
+		cmd.method = strings.ToUpper(value)
+	case "url":
+		cmd.url = value
+	case "header":
+		key, val, ok := strings.Cut(value, ":")
+		if !ok {
+			// "-H 'X-Empty;'" 表示空的请求头
+			if key, ok = strings.CutSuffix(value, ";"); !ok {
+				return fmt.Errorf("http_tools: invalid curl header %q", value)
+			}
+			cmd.header.Add(strings.TrimSpace(key), "")
+			return nil
+		}
+		// "-H 'Accept:'" 表示不发送该请求头
+		if val = strings.TrimSpace(val); val != "" {
+			cmd.header.Add(strings.TrimSpace(key), val)
+		}
+	case "data", "data-ascii", "data-binary", "data-raw", "json":
+		data := value
+		if name != "data-raw" && strings.HasPrefix(value, "@") {
+			content, err := os.ReadFile(value[1:])
+			if err != nil {
+				return err
+			}
+			data = string(content)
+			// -d 读取文件时去掉换行，--data-binary 保留原样
+			if name == "data" || name == "data-ascii" {
+				data = strings.NewReplacer("\r", "", "\n", "").Replace(data)
+			}
+		}
+		cmd.data = append(cmd.data, data)
+		cmd.json = cmd.json || name == "json"
+	case "data-urlencode":
+		data, err := curlURLEncode(value)
+		if err != nil {
+			return err
+		}
+		cmd.data = append(cmd.data, data)
+	case "form", "form-string":
+		cmd.forms = append(cmd.forms, curlForm{value: value, string: name == "form-string"})
+	case "user":
+		username, password, _ := strings.Cut(value, ":")
+		cmd.user = url.UserPassword(username, password)
+	case "cookie":
+		if !strings.Contains(value, "=") {
+			return fmt.Errorf("http_tools: curl cookie file %q is not supported", value)
+		}
+		if existing := cmd.header.Get("Cookie"); existing != "" {
+			value = existing + "; " + value
+		}
+		cmd.header.Set("Cookie", value)
+	case "user-agent":
+		cmd.header.Set("User-Agent", value)
+	case "referer":
+		cmd.header.Set("Referer", value)
+	case "max-time":
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("http_tools: invalid curl max-time %q", value)
+		}
+		cmd.timeout = time.Duration(seconds * float64(time.Second))
+	case "get":
+		cmd.get = true
+	case "head":
+		cmd.head = true
+	case "insecure":
+		cmd.insecure = true
+	case "compressed":
+		cmd.compressed = true
+	}
+	// 其他选项只影响 curl 的输出，忽略
+	return nil
+}
+
+// curlURLEncode 按 --data-urlencode 的规则编码: content、=content、name=content、@file、name@file
+func curlURLEncode(value string) (string, error) {
+	name, content := "", value
+	if i := strings.IndexAny(value, "=@"); i >= 0 {
+		name, content = value[:i], value[i+1:]
+		if value[i] == '@' {
+			data, err := os.ReadFile(content)
+			if err != nil {
+				return "", err
+			}
+			content = string(data)
+		}
+	}
+	if name == "" {
+		return url.QueryEscape(content), nil
+	}
+	return name + "=" + url.QueryEscape(content), nil
+}
+
+// build 根据解析的参数创建请求
+func (cmd *curlCommand) build(c *Client) (*ReqClient, error) {
+	rawURL := cmd.url
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	sep := "&"
+	if cmd.json {
+		sep = ""
+	}
+	body := strings.Join(cmd.data, sep)
+	if cmd.get && len(cmd.data) > 0 {
+		if strings.Contains(rawURL, "?") {
+			rawURL += "&" + body
+		} else {
+			rawURL += "?" + body
+		}
+		body = ""
+	}
+
+	method := cmd.method
+	if method == "" {
+		switch {
+		case cmd.head:
+			method = http.MethodHead
+		case cmd.get:
+			method = http.MethodGet
+		case len(cmd.data) > 0 || len(cmd.forms) > 0:
+			method = http.MethodPost
+		default:
+			method = http.MethodGet
+		}
+	}
+
+	r, err := c.NewRequest(method, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range cmd.header {
+		r.req.Header[key] = values
+	}
+	// --compressed 时由 Transport 协商 gzip 并自动解压，浏览器复制的 br、zstd 等编码无法解压
+	if cmd.compressed {
+		r.req.Header.Del("Accept-Encoding")
+	}
+	if cmd.user != nil {
+		password, _ := cmd.user.Password()
+		r.req.SetBasicAuth(cmd.user.Username(), password)
+	}
+	if cmd.timeout > 0 {
+		r.SetTimeout(cmd.timeout)
+	}
+	if cmd.insecure {
+		r.client.Transport = insecureTransport(r.client.Transport)
+	}
+
+	if len(cmd.forms) > 0 {
+		if len(cmd.data) > 0 {
+			return nil, errors.New("http_tools: curl command cannot mix -d and -F")
+		}
+		for _, form := range cmd.forms {
+			if err := r.addCurlForm(form); err != nil {
+				return nil, err
+			}
+		}
+		return r, nil
+	}
+	if body != "" || (len(cmd.data) > 0 && !cmd.get) {
+		r.setBodyBytes([]byte(body))
+		if r.req.Header.Get("Content-Type") == "" {
+			if cmd.json {
+				r.req.Header.Set("Content-Type", "application/json")
+			} else {
+				r.req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+		}
+		if cmd.json && r.req.Header.Get("Accept") == "" {
+			r.req.Header.Set("Accept", "application/json")
+		}
+	}
+	return r, nil
+}
+
+// addCurlForm 解析 -F 的值: name=value、name=@file;type=...;filename=...、name=<file
+func (r *ReqClient) addCurlForm(form curlForm) error {
+	name, value, ok := strings.Cut(form.value, "=")
+	if !ok {
+		return fmt.Errorf("http_tools: invalid curl form %q", form.value)
+	}
+	if form.string {
+		r.formFields[name] = value
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(value, "@"):
+		params := strings.Split(value[1:], ";")
+		var opts []FileOption
+		for _, param := range params[1:] {
+			key, val, _ := strings.Cut(param, "=")
+			switch strings.TrimSpace(key) {
+			case "type":
+				opts = append(opts, WithContentType(val))
+			case "filename":
+				opts = append(opts, WithFileName(strings.Trim(val, `"`)))
+			}
+		}
+		if _, err := os.Stat(params[0]); err != nil {
+			return err
+		}
+		return r.SetFile(name, params[0], opts...)
+	case strings.HasPrefix(value, "<"):
+		content, err := os.ReadFile(value[1:])
+		if err != nil {
+			return err
+		}
+		r.formFields[name] = string(content)
+	default:
+		r.formFields[name] = value
+	}
+	return nil
+}
+
+// insecureTransports 缓存跳过证书校验的 Transport，同一个客户端的 -k 请求共享连接池
+var insecureTransports sync.Map
+
+// insecureTransport 返回跳过证书校验的 Transport，不是 *http.Transport 时(例如测试使用的 Recorder)原样返回
+func insecureTransport(rt http.RoundTripper) http.RoundTripper {
+	base, ok := rt.(*http.Transport)
+	if !ok {
+		return rt
+	}
+	if cached, ok := insecureTransports.Load(base); ok {
+		return cached.(*http.Transport)
+	}
+	transport := base.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	actual, _ := insecureTransports.LoadOrStore(base, transport)
+	return actual.(*http.Transport)
+}
+
+// splitShellWords 按 bash 的规则拆分命令行，支持单引号、双引号、$'...'、反斜杠转义和换行续行
+func splitShellWords(s string) ([]string, error) {
+	var (
+		words        []string
+		word         strings.Builder
+		inWord       bool
+		runes        = []rune(s)
+		unterminated = func(quote string) error {
+			return fmt.Errorf("http_tools: unterminated %s in curl command", quote)
+		}
+	)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case ch == '\\':
+			if i+1 >= len(runes) {
+				break
+			}
+			i++
+			// 反斜杠加换行是续行
+			if runes[i] == '\n' {
+				continue
+			}
+			if runes[i] == '\r' && i+1 < len(runes) && runes[i+1] == '\n' {
+				i++
+				continue
+			}
+			word.WriteRune(runes[i])
+			inWord = true
+		case ch == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, unterminated("single quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			i, inWord = end, true
+		case ch == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			n, err := ansiCQuote(runes[i+2:], &word)
+			if err != nil {
+				return nil, err
+			}
+			i, inWord = i+2+n, true
+		case ch == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				// 双引号中只有 \" \\ \$ \` 和续行需要转义
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, unterminated("double quote")
+			}
+			inWord = true
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(ch)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// ansiCQuote 解析 $'...' 的内容，返回包括结束引号在内消耗的字符数
+func ansiCQuote(runes []rune, word *strings.Builder) (int, error) {
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		if ch == '\'' {
+			return i + 1, nil
+		}
+		if ch != '\\' || i+1 >= len(runes) {
+			word.WriteRune(ch)
+			continue
+		}
+		i++
+		switch esc := runes[i]; esc {
+		case 'n':
+			word.WriteByte('\n')
+		case 't':
+			word.WriteByte('\t')
+		case 'r':
+			word.WriteByte('\r')
+		case 'a':
+			word.WriteByte('\a')
+		case 'b':
+			word.WriteByte('\b')
+		case 'f':
+			word.WriteByte('\f')
+		case 'v':
+			word.WriteByte('\v')
+		case 'e', 'E':
+			word.WriteByte(0x1b)
+		case 'x', 'u', 'U':
+			digits := map[rune]int{'x': 2, 'u': 4, 'U': 8}[esc]
+			end := i + 1
+			for end < len(runes) && end-i-1 < digits && isHex(runes[end]) {
+				end++
+			}
+			if end == i+1 {
+				word.WriteRune('\\')
+				word.WriteRune(esc)
+				continue
+			}
+			n, _ := strconv.ParseUint(string(runes[i+1:end]), 16, 32)
+			if esc == 'x' {
+				word.WriteByte(byte(n))
+			} else if utf8.ValidRune(rune(n)) {
+				word.WriteRune(rune(n))
+			}
+			i = end - 1
+		default:
+			// \\ \' \" 等
+			word.WriteRune(esc)
+		}
+	}
+	return 0, errors.New("http_tools: unterminated $' quote in curl command")
+}
+
+func isHex(r rune) bool {
+	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
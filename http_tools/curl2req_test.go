@@ -0,0 +1,185 @@
+package http_tools
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试shell引号和转义
+func TestSplitShellWords(t *testing.T) {
+	cases := []struct {
+		input string
+		want  []string
+	}{
+		{`curl 'a b' "c \"d\" \$e" f\ g`, []string{"curl", "a b", `c "d" $e`, "f g"}},
+		{"curl \\\n  -H 'X: 1' \\\r\n  url", []string{"curl", "-H", "X: 1", "url"}},
+		{`$'{"msg":"it\'s\\n\u4f60\x41"}'`, []string{`{"msg":"it's\n你A"}`}},
+		{`'it'\''s' a""b`, []string{"it's", "ab"}},
+	}
+	for _, c := range cases {
+		got, err := splitShellWords(c.input)
+		if err != nil || !slices.Equal(got, c.want) {
+			t.Errorf("splitShellWords(%q) = %q, %v, want %q", c.input, got, err, c.want)
+		}
+	}
+	for _, input := range []string{`'abc`, `"abc`, `$'abc`} {
+		if _, err := splitShellWords(input); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+// 测试浏览器复制的 curl 命令可以直接发送
+func TestParseCurlBrowserCommand(t *testing.T) {
+	mock := NewMockServer(t)
+	mock.Expect("POST", "/api/items").
+		WithQuery("lang", "zh").
+		WithHeader("Authorization", "Bearer abc").
+		WithHeader("Content-Type", "application/json").
+		WithHeader("Cookie", "sid=1; theme=dark").
+		WithJSONBody(map[string]string{"name": "it's"}).
+		Match(func(r *http.Request, body []byte) bool {
+			// --compressed 时由 Transport 协商 gzip
+			return r.Header.Get("Accept-Encoding") == "gzip"
+		}).
+		Respond(200, "ok")
+
+	command := "curl '" + mock.URL() + "/api/items?lang=zh' \\\n" +
+		"  -H 'authorization: Bearer abc' \\\n" +
+		"  -H 'content-type: application/json' \\\n" +
+		"  -H 'accept-encoding: gzip, deflate, br, zstd' \\\n" +
+		"  -b 'sid=1; theme=dark' \\\n" +
+		"  --data-raw $'{\"name\":\"it\\'s\"}' \\\n" +
+		"  --compressed"
+	req, err := mock.Client().ParseCurl(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _, err := Do[string](req)
+	if err != nil || body != "ok" {
+		t.Errorf("Unexpected response %q %v", body, err)
+	}
+}
+
+// 测试各种选项的解析结果
+func TestParseCurlOptions(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("line1\nline2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		command string
+		check   func(r *ReqClient) bool
+	}{
+		{"curl example.com", func(r *ReqClient) bool {
+			return r.req.Method == "GET" && r.req.URL.String() == "http://example.com"
+		}},
+		{"curl -d a=1 -d b=2 https://x.com", func(r *ReqClient) bool {
+			return r.req.Method == "POST" && bodyOf(r) == "a=1&b=2" &&
+				r.req.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
+		}},
+		{"curl -G -d a=1 --data-urlencode 'q=a b' 'https://x.com/s?x=0'", func(r *ReqClient) bool {
+			return r.req.Method == "GET" && r.req.URL.RawQuery == "x=0&a=1&q=a+b" && r.req.Body == nil
+		}},
+		{"curl -XPUT -d @" + file + " https://x.com", func(r *ReqClient) bool {
+			return r.req.Method == "PUT" && bodyOf(r) == "line1line2"
+		}},
+		{"curl --data-binary @" + file + " https://x.com", func(r *ReqClient) bool {
+			return bodyOf(r) == "line1\nline2"
+		}},
+		{`curl --json '{"a":1}' https://x.com`, func(r *ReqClient) bool {
+			return r.req.Header.Get("Content-Type") == "application/json" && r.req.Header.Get("Accept") == "application/json"
+		}},
+		{"curl -sSLk -u admin:secret -m 1.5 -I https://x.com", func(r *ReqClient) bool {
+			user, pass, _ := r.req.BasicAuth()
+			return r.req.Method == "HEAD" && user == "admin" && pass == "secret" && r.client.Timeout == 1500*time.Millisecond
+		}},
+		{"curl -F name=bob -F 'doc=@" + file + ";type=text/plain;filename=b.txt' -F 'note=<" + file + "' https://x.com", func(r *ReqClient) bool {
+			f := r.files[0]
+			return r.req.Method == "POST" && r.formFields["name"] == "bob" && r.formFields["note"] == "line1\nline2" &&
+				f.fieldName == "doc" && f.filePath == file && f.contentType == "text/plain" && f.fileName == "b.txt"
+		}},
+		{"curl -H 'X-Empty;' -H 'Accept:' -A agent -e https://ref https://x.com", func(r *ReqClient) bool {
+			_, hasEmpty := r.req.Header["X-Empty"]
+			_, hasAccept := r.req.Header["Accept"]
+			return hasEmpty && !hasAccept && r.req.UserAgent() == "agent" && r.req.Referer() == "https://ref"
+		}},
+	}
+	for _, c := range cases {
+		r, err := ParseCurl(c.command)
+		if err != nil {
+			t.Errorf("%s: %v", c.command, err)
+			continue
+		}
+		if !c.check(r) {
+			t.Errorf("%s: unexpected request %s %s %v", c.command, r.req.Method, r.req.URL, r.req.Header)
+		}
+	}
+
+	for _, command := range []string{"curl", "curl --proxy http://p https://x.com", "curl -F 'a=@" + filepath.Join(dir, "missing") + "' https://x.com", "curl a.com b.com"} {
+		if _, err := ParseCurl(command); err == nil {
+			t.Errorf("Expected error for %s", command)
+		}
+	}
+}
+
+func bodyOf(r *ReqClient) string {
+	body, _ := peekBody(r.req)
+	return string(body)
+}
+
+// 测试导出的curl命令可以解析回相同的请求，以及导出 HTTPie 和Go代码
+func TestCurlRoundTripAndExport(t *testing.T) {
+	req, _ := NewReqClient("POST", "https://api.example.com/items?page=1")
+	req.SetHeaders(map[string]string{"Authorization": "Bearer it's", "Content-Type": "application/json"})
+	req.SetBody([]byte(`{"name":"it's"}`))
+
+	curl, err := req.ConvertToCurlWithFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseCurl(curl)
+	if err != nil {
+		t.Fatalf("%s: %v", curl, err)
+	}
+	if parsed.req.URL.String() != req.req.URL.String() || parsed.req.Header.Get("Authorization") != "Bearer it's" ||
+		parsed.req.Header.Get("Content-Type") != "application/json" || bodyOf(parsed) != `{"name":"it's"}` {
+		t.Errorf("Round trip mismatch: %s", curl)
+	}
+
+	httpie, _ := req.ConvertToHTTPie()
+	want := `http --raw '{"name":"it'\''s"}' POST 'https://api.example.com/items?page=1' 'Authorization:Bearer it'\''s' 'Content-Type:application/json'`
+	if httpie != want {
+		t.Errorf("Unexpected HTTPie command:\n%s\nwant:\n%s", httpie, want)
+	}
+
+	code, _ := req.ConvertToGo()
+	for _, s := range []string{
+		`http_tools.NewReqClient("POST", "https://api.example.com/items?page=1")`,
+		`"Authorization": "Bearer it's",`,
+		"cli.SetBody([]byte(\"{\\\"name\\\":\\\"it's\\\"}\"))",
+	} {
+		if !strings.Contains(code, s) {
+			t.Errorf("Expected Go code to contain %s:\n%s", s, code)
+		}
+	}
+
+	form, _ := NewReqClient("POST", "https://api.example.com/upload")
+	form.SetForm(map[string]string{"name": "bob"})
+	_ = form.SetFile("file", "/tmp/a.png", WithContentType("image/png"))
+	httpie, _ = form.ConvertToHTTPie()
+	if httpie != `http --multipart POST 'https://api.example.com/upload' 'name=bob' 'file@/tmp/a.png;type=image/png'` {
+		t.Errorf("Unexpected HTTPie multipart command: %s", httpie)
+	}
+	code, _ = form.ConvertToGo()
+	if !strings.Contains(code, `cli.SetFile("file", "/tmp/a.png", http_tools.WithContentType("image/png"))`) {
+		t.Errorf("Unexpected Go code:\n%s", code)
+	}
+}
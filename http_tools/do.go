@@ -0,0 +1,229 @@
+package http_tools
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// DefaultMaxBodySize Do 默认读取的最大响应体大小
+const DefaultMaxBodySize int64 = 10 << 20
+
+// ErrBodyTooLarge 响应体超过最大大小
+var ErrBodyTooLarge = errors.New("http_tools: response body too large")
+
+// Decoder 将响应体解析到v，v为指向结果的指针
+type Decoder func(data []byte, v any) error
+
+// Response 读取完的响应
+type Response struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+	// CacheStatus 使用 CacheResponses 中间件时表示响应是否来自缓存
+	CacheStatus CacheStatus
+}
+
+// HTTPError 非2xx响应，可以用 errors.As 获取
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte // 响应体，最多 MaxBodySize 字节
+}
+
+// Error 返回状态码和响应体的前512个字节
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http_tools: %s %s: %s: %s", e.Method, e.URL, e.Status, e.Snippet(512))
+}
+
+// Snippet 返回响应体的前n个字节，截断时不会切断UTF-8字符
+func (e *HTTPError) Snippet(n int) string {
+	if len(e.Body) <= n {
+		return string(e.Body)
+	}
+	cut := e.Body[:n]
+	for len(cut) > 0 && !utf8.Valid(cut) {
+		cut = cut[:len(cut)-1]
+	}
+	return string(cut) + "..."
+}
+
+// Decode 按 Content-Type 解析错误响应体，例如解析接口返回的错误信息
+func (e *HTTPError) Decode(v any) error {
+	return decodeBody(e.Header.Get("Content-Type"), e.Body, v, nil)
+}
+
+// DoOption Do 的可选配置
+type DoOption func(*doConfig)
+
+type doConfig struct {
+	maxBodySize int64
+	decoders    map[string]Decoder
+}
+
+// WithMaxBodySize 设置读取的最大响应体大小，超过时返回 ErrBodyTooLarge
+func WithMaxBodySize(n int64) DoOption {
+	return func(c *doConfig) {
+		c.maxBodySize = n
+	}
+}
+
+// WithDecoder 为本次请求设置某个 Content-Type 的解析方法，优先于 RegisterDecoder 注册的解析方法
+func WithDecoder(contentType string, decoder Decoder) DoOption {
+	return func(c *doConfig) {
+		if c.decoders == nil {
+			c.decoders = make(map[string]Decoder)
+		}
+		c.decoders[contentType] = decoder
+	}
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{
+		"application/json":                  json.Unmarshal,
+		"application/xml":                   xml.Unmarshal,
+		"text/xml":                          xml.Unmarshal,
+		"application/x-www-form-urlencoded": decodeForm,
+	}
+)
+
+// RegisterDecoder 注册某个 Content-Type 的解析方法，例如 application/x-protobuf
+func RegisterDecoder(contentType string, decoder Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[contentType] = decoder
+}
+
+// Do 发送请求，读取并按 Content-Type 解析响应体
+// 支持 JSON、XML、表单，以及 RegisterDecoder 注册的类型，没有匹配的类型时按JSON解析
+// T 为 []byte 或 string 时直接返回响应体
+// 非2xx响应返回 *HTTPError，请求失败时 Response 为nil
+// 例如: user, resp, err := http_tools.Do[User](cli)
+func Do[T any](r *ReqClient, opts ...DoOption) (T, *Response, error) {
+	var result T
+	cfg := doConfig{maxBodySize: DefaultMaxBodySize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if err := r.Send(); err != nil {
+		return result, nil, err
+	}
+	defer r.Close()
+
+	body, readErr := readLimited(r.response.Body, cfg.maxBodySize)
+	resp := &Response{
+		StatusCode: r.response.StatusCode,
+		Status:     r.response.Status,
+		Header:     r.response.Header,
+		Body:       body,
+
+		CacheStatus: GetCacheStatus(r.response),
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, resp, &HTTPError{
+			Method:     r.req.Method,
+			URL:        r.req.URL.String(),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     resp.Header,
+			Body:       body,
+		}
+	}
+	if readErr != nil {
+		return result, resp, readErr
+	}
+	if len(body) == 0 {
+		return result, resp, nil
+	}
+	err := decodeBody(resp.Header.Get("Content-Type"), body, &result, cfg.decoders)
+	return result, resp, err
+}
+
+// readLimited 最多读取limit字节，超过时返回已读取的部分和 ErrBodyTooLarge
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return body, err
+	}
+	if int64(len(body)) > limit {
+		return body[:limit], fmt.Errorf("%w: limit %d bytes", ErrBodyTooLarge, limit)
+	}
+	return body, nil
+}
+
+// decodeBody 按 Content-Type 选择解析方法，overrides 优先
+func decodeBody(contentType string, body []byte, v any, overrides map[string]Decoder) error {
+	switch target := v.(type) {
+	case *[]byte:
+		*target = body
+		return nil
+	case *string:
+		*target = string(body)
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	decoder := lookupDecoder(mediaType, overrides)
+	if err := decoder(body, v); err != nil {
+		return fmt.Errorf("http_tools: decode %s response: %w", mediaType, err)
+	}
+	return nil
+}
+
+func lookupDecoder(mediaType string, overrides map[string]Decoder) Decoder {
+	if decoder, ok := overrides[mediaType]; ok {
+		return decoder
+	}
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	if decoder, ok := decoders[mediaType]; ok {
+		return decoder
+	}
+	// application/problem+json、application/atom+xml 等
+	switch {
+	case strings.HasSuffix(mediaType, "+xml"):
+		return decoders["application/xml"]
+	default:
+		return decoders["application/json"]
+	}
+}
+
+// decodeForm 解析表单响应，v 支持 *url.Values、*map[string]string、*map[string][]string
+func decodeForm(data []byte, v any) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	switch target := v.(type) {
+	case *url.Values:
+		*target = values
+	case *map[string][]string:
+		*target = values
+	case *map[string]string:
+		m := make(map[string]string, len(values))
+		for key := range values {
+			m[key] = values.Get(key)
+		}
+		*target = m
+	default:
+		return fmt.Errorf("unsupported form target %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package http_tools
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type doTestUser struct {
+	ID   int    `json:"id" xml:"id"`
+	Name string `json:"name" xml:"name"`
+}
+
+func doTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_, _ = w.Write([]byte(`{"id":1,"name":"alice"}`))
+		case "/problem":
+			w.Header().Set("Content-Type", "application/problem+json")
+			_, _ = w.Write([]byte(`{"id":2,"name":"bob"}`))
+		case "/xml":
+			w.Header().Set("Content-Type", "text/xml")
+			_, _ = w.Write([]byte(`<user><id>3</id><name>carol</name></user>`))
+		case "/form":
+			w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+			_, _ = w.Write([]byte(`token=abc&scope=read&scope=write`))
+		case "/csv":
+			w.Header().Set("Content-Type", "text/csv")
+			_, _ = w.Write([]byte("4,dave"))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/error":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"user not found"}`))
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat("x", 2048)))
+		}
+	}))
+}
+
+// 测试按 Content-Type 解析响应
+func TestDoDecodes(t *testing.T) {
+	server := doTestServer()
+	defer server.Close()
+
+	for _, path := range []string{"/json", "/problem", "/xml"} {
+		req, _ := NewReqClient("GET", server.URL+path)
+		user, resp, err := Do[doTestUser](req)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if user.ID == 0 || user.Name == "" || resp.StatusCode != 200 {
+			t.Errorf("%s: unexpected result %+v", path, user)
+		}
+	}
+
+	req, _ := NewReqClient("GET", server.URL+"/form")
+	values, _, err := Do[url.Values](req)
+	if err != nil || values.Get("token") != "abc" || len(values["scope"]) != 2 {
+		t.Errorf("Unexpected form result %v %v", values, err)
+	}
+
+	req, _ = NewReqClient("GET", server.URL+"/json")
+	raw, _, err := Do[string](req)
+	if err != nil || raw != `{"id":1,"name":"alice"}` {
+		t.Errorf("Expected raw body, got %q %v", raw, err)
+	}
+
+	req, _ = NewReqClient("GET", server.URL+"/empty")
+	user, resp, err := Do[*doTestUser](req)
+	if err != nil || user != nil || resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected empty result for 204, got %v %v", user, err)
+	}
+}
+
+// 测试自定义解析方法
+func TestDoCustomDecoder(t *testing.T) {
+	server := doTestServer()
+	defer server.Close()
+
+	csvDecoder := func(data []byte, v any) error {
+		parts := strings.Split(string(data), ",")
+		user := v.(*doTestUser)
+		user.Name = parts[1]
+		return nil
+	}
+	req, _ := NewReqClient("GET", server.URL+"/csv")
+	user, _, err := Do[doTestUser](req, WithDecoder("text/csv", csvDecoder))
+	if err != nil || user.Name != "dave" {
+		t.Errorf("Expected custom decoder to be used, got %+v %v", user, err)
+	}
+
+	// 没有解析方法时按JSON解析
+	req, _ = NewReqClient("GET", server.URL+"/csv")
+	if _, _, err := Do[doTestUser](req); err == nil || !strings.Contains(err.Error(), "decode text/csv") {
+		t.Errorf("Expected json decode error, got %v", err)
+	}
+}
+
+// 测试非2xx响应和响应体大小限制
+func TestDoErrors(t *testing.T) {
+	server := doTestServer()
+	defer server.Close()
+
+	req, _ := NewReqClient("GET", server.URL+"/error")
+	_, resp, err := Do[doTestUser](req)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Expected HTTPError, got %v", err)
+	}
+	if httpErr.StatusCode != 404 || resp.StatusCode != 404 || !strings.Contains(err.Error(), "user not found") {
+		t.Errorf("Unexpected error %v", err)
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := httpErr.Decode(&body); err != nil || body.Error != "user not found" {
+		t.Errorf("Expected error body to decode, got %+v %v", body, err)
+	}
+
+	req, _ = NewReqClient("GET", server.URL+"/large")
+	_, _, err = Do[string](req, WithMaxBodySize(1024))
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Expected ErrBodyTooLarge, got %v", err)
+	}
+
+	if snippet := (&HTTPError{Body: []byte("你好世界")}).Snippet(4); snippet != "你..." {
+		t.Errorf("Expected snippet to keep whole characters, got %q", snippet)
+	}
+}
+
+// 测试没有响应时读取不会panic
+func TestNoResponse(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	req, _ := NewReqClient("GET", url)
+	if err := req.Send(); err == nil {
+		t.Fatal("Expected connection error")
+	}
+	if code := req.GetHttpCode(); code != 0 {
+		t.Errorf("Expected 0 without response, got %d", code)
+	}
+	if _, err := req.GetBody(); !errors.Is(err, ErrNoResponse) {
+		t.Errorf("Expected ErrNoResponse, got %v", err)
+	}
+	if _, resp, err := Do[string](req); err == nil || resp != nil {
+		t.Errorf("Expected error without response, got %v", err)
+	}
+	req.Close()
+}
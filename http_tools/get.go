@@ -2,25 +2,48 @@ package http_tools
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 )
 
+// ErrNoResponse 请求还没有发送或发送失败时读取响应返回的错误
+var ErrNoResponse = errors.New("http_tools: no response, Send not called or failed")
+
 // GetHttpCode 获取 HTTP 状态码
-// return: HTTP 状态码
+// return: HTTP 状态码，没有响应时返回0
 func (r *ReqClient) GetHttpCode() int {
+	if r.response == nil {
+		return 0
+	}
 	return r.response.StatusCode
 }
 
-// GetResponseHeader 获取响应头
+// GetResponseHeader 获取响应头，没有响应时返回nil
 func (r *ReqClient) GetResponseHeader() http.Header {
+	if r.response == nil {
+		return nil
+	}
 	return r.response.Header
 }
 
+// GetCacheStatus 获取响应的缓存状态，没有使用 CacheResponses 中间件或没有响应时返回 CacheMiss
+func (r *ReqClient) GetCacheStatus() CacheStatus {
+	return GetCacheStatus(r.response)
+}
+
+// IsFromCache 响应是否来自缓存，包括重新验证后使用的缓存
+func (r *ReqClient) IsFromCache() bool {
+	return r.GetCacheStatus() != CacheMiss
+}
+
 // GetBody 获取响应体
 // return: 响应体
 func (r *ReqClient) GetBody() ([]byte, error) {
+	if r.response == nil {
+		return nil, ErrNoResponse
+	}
 	body, err := io.ReadAll(r.response.Body)
 	if err != nil {
 		return nil, err
@@ -29,6 +52,7 @@ func (r *ReqClient) GetBody() ([]byte, error) {
 }
 
 // GetBodyString 获取响应体字符串
+// 读取失败时返回错误信息，需要区分错误时使用 GetBody 或 Do
 // return: 响应体字符串
 func (r *ReqClient) GetBodyString() string {
 	body, err := r.GetBody()
@@ -43,6 +67,9 @@ func (r *ReqClient) GetBodyString() string {
 
 // GetBodyReadCloser 获取响应体读取器
 func (r *ReqClient) GetBodyReadCloser() (io.ReadCloser, error) {
+	if r.response == nil {
+		return nil, ErrNoResponse
+	}
 	return r.response.Body, nil
 }
 
@@ -54,5 +81,5 @@ func (r *ReqClient) LoadBody(data any) error {
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(body, &data)
+	return json.Unmarshal(body, data)
 }
@@ -6,9 +6,29 @@ import (
 	"testing"
 )
 
+// httpbinClient 回放 testdata/httpbin_fixture.json 中的响应，测试不需要访问网络
+// 该文件是按 httpbin.org 的响应格式手写的模拟响应，只包含测试用到的字段，不是真实服务的录制
+// 设置环境变量 HTTP_TOOLS_RECORD=1 时访问 httpbin.org 并用录制结果覆盖该文件
+func httpbinClient(t *testing.T) *Client {
+	mode := ModeReplay
+	if os.Getenv("HTTP_TOOLS_RECORD") != "" {
+		mode = ModeRecord
+	}
+	recorder, err := NewRecorder("testdata/httpbin_fixture.json", mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := recorder.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+	return recorder.Client()
+}
+
 func TestSendGet(t *testing.T) {
 	t.Log("TestSendGet")
-	client, _ := NewReqClient("GET", "https://httpbin.org/json")
+	client, _ := httpbinClient(t).NewRequest("GET", "https://httpbin.org/json")
 	err := client.Send()
 	if err != nil {
 		t.Error(err)
@@ -184,7 +204,7 @@ func TestPrintCurlWithFiles(t *testing.T) {
 	tmpFile.Close()
 
 	// 创建客户端并启用curl打印
-	client, err := NewReqClient("POST", "https://httpbin.org/post")
+	client, err := httpbinClient(t).NewRequest("POST", "https://httpbin.org/post")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,433 @@
+package http_tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otkinlife/go_tools/cache_tools"
+)
+
+// CacheStore HTTP缓存的存储，值为序列化后的响应
+type CacheStore interface {
+	Get(key string) ([]byte, bool)
+	// Set 写入缓存，ttl 为0表示不过期
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(key string)
+}
+
+// cacheManagerStore 使用 cache_tools.CacheManager 作为存储
+type cacheManagerStore struct {
+	manager *cache_tools.CacheManager
+}
+
+// NewCacheManagerStore 使用 cache_tools.CacheManager 保存响应，大小限制、淘汰和持久化都由管理器负责
+func NewCacheManagerStore(manager *cache_tools.CacheManager) CacheStore {
+	return cacheManagerStore{manager: manager}
+}
+
+func (s cacheManagerStore) Get(key string) ([]byte, bool) {
+	value, ok := s.manager.GetValue(key)
+	if !ok {
+		return nil, false
+	}
+	data, ok := value.([]byte)
+	return data, ok
+}
+
+func (s cacheManagerStore) Set(key string, value []byte, ttl time.Duration) {
+	_ = s.manager.SetValueWithTTL(key, value, ttl)
+}
+
+func (s cacheManagerStore) Delete(key string) {
+	_ = s.manager.Delete(key)
+}
+
+// CacheStatus 响应的缓存状态
+type CacheStatus string
+
+const (
+	CacheMiss        CacheStatus = "MISS"        // 没有使用缓存
+	CacheHit         CacheStatus = "HIT"         // 直接使用缓存，没有发送请求
+	CacheRevalidated CacheStatus = "REVALIDATED" // 缓存过期，服务端返回304后使用缓存
+)
+
+// HeaderCacheStatus 经过 HTTPCache 的响应会带上这个响应头，值为 CacheStatus
+const HeaderCacheStatus = "X-Cache-Status"
+
+// HTTPCache GET请求的客户端缓存，遵守 Cache-Control、Expires、ETag 和 Last-Modified
+// 缓存未过期时直接返回缓存，过期后带上 If-None-Match、If-Modified-Since 重新验证，服务端返回304时使用缓存
+// 作为私有缓存使用，带 Authorization 的请求也会缓存，不同用户共享客户端时应该用 SetKeyFunc 区分
+type HTTPCache struct {
+	store       CacheStore
+	keyFunc     func(req *http.Request) string
+	maxBodySize int64
+	staleTTL    time.Duration
+	now         func() time.Time
+}
+
+// NewHTTPCache 创建HTTP缓存
+// 默认按URL缓存，响应体超过 DefaultMaxBodySize 时不缓存，有验证器的响应过期后最多保留24小时用于重新验证
+func NewHTTPCache(store CacheStore) *HTTPCache {
+	return &HTTPCache{
+		store:       store,
+		keyFunc:     func(req *http.Request) string { return "http_tools:" + req.URL.String() },
+		maxBodySize: DefaultMaxBodySize,
+		staleTTL:    24 * time.Hour,
+		now:         time.Now,
+	}
+}
+
+// SetKeyFunc 设置缓存的key，例如在URL之外加上用户ID
+func (c *HTTPCache) SetKeyFunc(fn func(req *http.Request) string) *HTTPCache {
+	c.keyFunc = fn
+	return c
+}
+
+// SetMaxBodySize 设置可以缓存的最大响应体
+func (c *HTTPCache) SetMaxBodySize(n int64) *HTTPCache {
+	c.maxBodySize = n
+	return c
+}
+
+// SetStaleTTL 设置带有 ETag 或 Last-Modified 的响应过期后在存储中保留多久，用于重新验证
+func (c *HTTPCache) SetStaleTTL(d time.Duration) *HTTPCache {
+	c.staleTTL = d
+	return c
+}
+
+// Invalidate 删除请求对应的缓存
+func (c *HTTPCache) Invalidate(req *http.Request) {
+	c.store.Delete(c.key(req))
+}
+
+// CacheResponses 使用HTTP缓存的中间件，应该放在认证等会修改请求头的中间件之后
+func CacheResponses(cache *HTTPCache) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			return cache.roundTrip(req, next)
+		}
+	}
+}
+
+// GetCacheStatus 返回响应的缓存状态，没有使用 HTTPCache 时返回 CacheMiss
+func GetCacheStatus(resp *http.Response) CacheStatus {
+	if resp == nil {
+		return CacheMiss
+	}
+	if status := resp.Header.Get(HeaderCacheStatus); status != "" {
+		return CacheStatus(status)
+	}
+	return CacheMiss
+}
+
+// cacheEntry 缓存的响应
+type cacheEntry struct {
+	StatusCode int               `json:"status_code"`
+	Status     string            `json:"status"`
+	Header     http.Header       `json:"header"`
+	Body       []byte            `json:"body"`
+	Date       time.Time         `json:"date"`     // 响应的 Date，重新验证后更新
+	Received   time.Time         `json:"received"` // 收到响应的本地时间，用于计算年龄，不受服务端时钟偏差影响
+	Vary       map[string]string `json:"vary,omitempty"`
+}
+
+func (c *HTTPCache) key(req *http.Request) string {
+	if req.Method == http.MethodGet {
+		return c.keyFunc(req)
+	}
+	get := req.Clone(req.Context())
+	get.Method = http.MethodGet
+	return c.keyFunc(get)
+}
+
+func (c *HTTPCache) roundTrip(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		resp, err := next(req)
+		// 修改资源的请求成功后删除缓存
+		if err == nil && req.Method != http.MethodHead && req.Method != http.MethodOptions && resp.StatusCode < 400 {
+			c.Invalidate(req)
+		}
+		return resp, err
+	}
+
+	reqCC := parseCacheControl(req.Header.Get("Cache-Control"))
+	if reqCC.has("no-store") {
+		return next(req)
+	}
+
+	key := c.key(req)
+	entry := c.load(key, req)
+	now := c.now()
+	if entry != nil && !reqCC.has("no-cache") && c.fresh(entry, reqCC, now) {
+		return entry.response(req, CacheHit, now), nil
+	}
+	if reqCC.has("only-if-cached") {
+		return &http.Response{
+			Status:     "504 Gateway Timeout",
+			StatusCode: http.StatusGatewayTimeout,
+			Proto:      "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1,
+			Header:  http.Header{HeaderCacheStatus: {string(CacheMiss)}},
+			Body:    http.NoBody,
+			Request: req,
+		}, nil
+	}
+
+	// 调用方自己带了条件请求头时不使用缓存的验证器，304交给调用方处理
+	outgoing := req
+	conditional := req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+	if entry != nil && entry.hasValidator() && !conditional {
+		outgoing = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			outgoing.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			outgoing.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	requestTime := now
+	resp, err := next(outgoing)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil && outgoing != req {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		entry.revalidated(resp.Header, requestTime, c.now())
+		c.save(key, entry)
+		return entry.response(req, CacheRevalidated, c.now()), nil
+	}
+	return c.storeResponse(key, req, resp, requestTime), nil
+}
+
+// storeResponse 可以缓存的响应读取响应体后写入缓存，其他响应原样返回
+func (c *HTTPCache) storeResponse(key string, req *http.Request, resp *http.Response, requestTime time.Time) *http.Response {
+	resp.Header.Set(HeaderCacheStatus, string(CacheMiss))
+	respCC := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if !cacheableStatus(resp.StatusCode) || respCC.has("no-store") || resp.Header.Get("Vary") == "*" {
+		return resp
+	}
+
+	entry := &cacheEntry{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header.Clone(),
+		Date:       responseDate(resp.Header, requestTime),
+		Received:   c.now(),
+		Vary:       varyValues(req, resp.Header),
+	}
+	entry.Header.Del(HeaderCacheStatus)
+	lifetime := entry.freshnessLifetime()
+	if lifetime <= 0 && !entry.hasValidator() {
+		return resp
+	}
+
+	// 超过大小限制时不缓存，已经读取的部分和剩余的响应体拼接后返回
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.maxBodySize+1))
+	if err != nil || int64(len(body)) > c.maxBodySize {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	entry.Body = body
+	c.save(key, entry)
+	return resp
+}
+
+func (c *HTTPCache) load(key string, req *http.Request) *cacheEntry {
+	data, ok := c.store.Get(key)
+	if !ok {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	// Vary 中的请求头不同时不能使用缓存
+	for name, value := range entry.Vary {
+		if req.Header.Get(name) != value {
+			return nil
+		}
+	}
+	return &entry
+}
+
+func (c *HTTPCache) save(key string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	ttl := entry.freshnessLifetime() - entry.age(c.now())
+	if entry.hasValidator() {
+		ttl += c.staleTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	c.store.Set(key, data, ttl)
+}
+
+// fresh 缓存是否可以直接使用，考虑请求的 max-age、min-fresh、max-stale
+func (c *HTTPCache) fresh(entry *cacheEntry, reqCC cacheControl, now time.Time) bool {
+	respCC := parseCacheControl(entry.Header.Get("Cache-Control"))
+	if respCC.has("no-cache") {
+		return false
+	}
+	age := entry.age(now)
+	lifetime := entry.freshnessLifetime()
+	if maxAge, ok := reqCC.duration("max-age"); ok {
+		lifetime = min(lifetime, maxAge)
+	}
+	if minFresh, ok := reqCC.duration("min-fresh"); ok {
+		age += minFresh
+	}
+	if maxStale, ok := reqCC["max-stale"]; ok && !respCC.has("must-revalidate") {
+		if maxStale == "" {
+			return true
+		}
+		if d, ok := reqCC.duration("max-stale"); ok {
+			lifetime += d
+		}
+	}
+	return age < lifetime
+}
+
+func (e *cacheEntry) hasValidator() bool {
+	return e.Header.Get("ETag") != "" || e.Header.Get("Last-Modified") != ""
+}
+
+// freshnessLifetime 响应的有效期: max-age，其次 Expires，都没有时按 Last-Modified 估算为距今时间的10%，最多24小时
+func (e *cacheEntry) freshnessLifetime() time.Duration {
+	cc := parseCacheControl(e.Header.Get("Cache-Control"))
+	if maxAge, ok := cc.duration("max-age"); ok {
+		return maxAge
+	}
+	if expires := e.Header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0 // 无效的 Expires 表示已经过期
+		}
+		return t.Sub(e.Date)
+	}
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && e.StatusCode == http.StatusOK {
+		return min(e.Date.Sub(lastModified)/10, 24*time.Hour)
+	}
+	return 0
+}
+
+// age 缓存的年龄: 收到响应时的年龄加上之后经过的时间，收到时的年龄取 Date 和上游缓存返回的 Age
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	age := max(e.Received.Sub(e.Date), 0) + max(now.Sub(e.Received), 0)
+	if seconds, err := strconv.Atoi(e.Header.Get("Age")); err == nil && seconds > 0 {
+		age += time.Duration(seconds) * time.Second
+	}
+	return age
+}
+
+// revalidated 使用304响应的头部更新缓存
+func (e *cacheEntry) revalidated(header http.Header, requestTime, received time.Time) {
+	for name, values := range header {
+		switch name {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding", HeaderCacheStatus:
+			continue
+		}
+		e.Header[name] = values
+	}
+	e.Header.Del("Age")
+	e.Date = responseDate(header, requestTime)
+	e.Received = received
+}
+
+func (e *cacheEntry) response(req *http.Request, status CacheStatus, now time.Time) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(e.age(now).Seconds())))
+	header.Set(HeaderCacheStatus, string(status))
+	return &http.Response{
+		Status:        e.Status,
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// cacheableStatus 默认可以缓存的状态码
+func cacheableStatus(code int) bool {
+	switch code {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent, http.StatusMultipleChoices,
+		http.StatusMovedPermanently, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusGone,
+		http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// responseDate 响应的 Date，没有或无效时使用发送请求的时间
+func responseDate(header http.Header, fallback time.Time) time.Time {
+	if date, err := http.ParseTime(header.Get("Date")); err == nil {
+		return date
+	}
+	return fallback
+}
+
+// varyValues 记录 Vary 中列出的请求头的值
+func varyValues(req *http.Request, header http.Header) map[string]string {
+	var values map[string]string
+	for _, vary := range header.Values("Vary") {
+		for _, name := range strings.Split(vary, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+				if values == nil {
+					values = make(map[string]string)
+				}
+				values[name] = req.Header.Get(name)
+			}
+		}
+	}
+	return values
+}
+
+// cacheControl 解析后的 Cache-Control，没有值的指令值为空字符串
+type cacheControl map[string]string
+
+func parseCacheControl(value string) cacheControl {
+	cc := cacheControl{}
+	for _, directive := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			cc[name] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// duration 秒数指令的值
+func (cc cacheControl) duration(name string) (time.Duration, bool) {
+	value, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
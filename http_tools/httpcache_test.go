@@ -0,0 +1,183 @@
+package http_tools
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/otkinlife/go_tools/cache_tools"
+)
+
+// newTestCache 使用 CacheManager 存储，时间可以手动推进
+func newTestCache(t *testing.T, now *time.Time) *HTTPCache {
+	manager := cache_tools.NewCacheManager()
+	if err := manager.Init(1<<20, "", cache_tools.WithKeyStrategy(cache_tools.RawKey)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { manager.Close() })
+	cache := NewHTTPCache(NewCacheManagerStore(manager))
+	cache.now = func() time.Time { return *now }
+	return cache
+}
+
+func getCached(t *testing.T, client *Client, url string) (*Response, string) {
+	t.Helper()
+	req, err := client.NewRequest("GET", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, resp, err := Do[string](req)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	return resp, body
+}
+
+// 测试未过期的响应直接从缓存返回，过期后使用 ETag 重新验证
+func TestHTTPCacheFreshAndRevalidate(t *testing.T) {
+	now := time.Now()
+	mock := NewMockServer(t)
+	mock.Expect("GET", "/data").RespondHeader("Cache-Control", "max-age=60").RespondHeader("ETag", `"v1"`).
+		RespondHeader("Date", now.UTC().Format(http.TimeFormat)).Respond(200, "hello")
+	notModified := mock.Expect("GET", "/data").WithHeader("If-None-Match", `"v1"`).
+		RespondHeader("Cache-Control", "max-age=60").RespondHeader("Date", now.Add(2*time.Minute).UTC().Format(http.TimeFormat)).
+		Respond(http.StatusNotModified, "")
+
+	client := mock.Client()
+	client.middlewares = []Middleware{CacheResponses(newTestCache(t, &now))}
+
+	resp, body := getCached(t, client, mock.URL()+"/data")
+	if resp.CacheStatus != CacheMiss || body != "hello" {
+		t.Errorf("Expected miss, got %s %q", resp.CacheStatus, body)
+	}
+	resp, body = getCached(t, client, mock.URL()+"/data")
+	if resp.CacheStatus != CacheHit || body != "hello" || resp.Header.Get("ETag") != `"v1"` {
+		t.Errorf("Expected hit, got %s %q", resp.CacheStatus, body)
+	}
+
+	now = now.Add(2 * time.Minute)
+	resp, body = getCached(t, client, mock.URL()+"/data")
+	if resp.CacheStatus != CacheRevalidated || resp.StatusCode != 200 || body != "hello" {
+		t.Errorf("Expected revalidated, got %s %d %q", resp.CacheStatus, resp.StatusCode, body)
+	}
+	resp, _ = getCached(t, client, mock.URL()+"/data")
+	if resp.CacheStatus != CacheHit || notModified.Calls() != 1 {
+		t.Errorf("Expected hit after revalidation, got %s", resp.CacheStatus)
+	}
+}
+
+// 测试 Last-Modified 重新验证和内容变化后更新缓存
+func TestHTTPCacheLastModified(t *testing.T) {
+	now := time.Now()
+	lastModified := now.Add(-time.Hour).UTC().Format(http.TimeFormat)
+	mock := NewMockServer(t)
+	mock.Expect("GET", "/file").RespondHeader("Cache-Control", "no-cache").
+		RespondHeader("Last-Modified", lastModified).Respond(200, "v1")
+	mock.Expect("GET", "/file").WithHeader("If-Modified-Since", lastModified).Respond(200, "v2")
+
+	client := mock.Client()
+	client.middlewares = []Middleware{CacheResponses(newTestCache(t, &now))}
+	getCached(t, client, mock.URL()+"/file")
+	resp, body := getCached(t, client, mock.URL()+"/file")
+	if resp.CacheStatus != CacheMiss || body != "v2" {
+		t.Errorf("Expected new content, got %s %q", resp.CacheStatus, body)
+	}
+}
+
+// 测试 no-store、Vary 和修改请求后的失效
+func TestHTTPCacheBypass(t *testing.T) {
+	now := time.Now()
+	mock := NewMockServer(t)
+	noStore := mock.Expect("GET", "/private").RespondHeader("Cache-Control", "no-store").Respond(200, "secret").Times(2)
+	vary := mock.Expect("GET", "/lang").RespondHeader("Cache-Control", "max-age=60").
+		RespondHeader("Vary", "Accept-Language").Respond(200, "text").Times(2)
+	items := mock.Expect("GET", "/items").RespondHeader("Cache-Control", "max-age=60").Respond(200, "[]").Times(2)
+	mock.Expect("POST", "/items").Respond(201, "")
+
+	client := mock.Client()
+	client.middlewares = []Middleware{CacheResponses(newTestCache(t, &now))}
+	for i := 0; i < 2; i++ {
+		getCached(t, client, mock.URL()+"/private")
+	}
+	if noStore.Calls() != 2 {
+		t.Errorf("Expected no-store response not cached")
+	}
+
+	for _, lang := range []string{"en", "en", "zh"} {
+		req, _ := client.NewRequest("GET", mock.URL()+"/lang")
+		req.SetHeaders(map[string]string{"Accept-Language": lang})
+		if _, _, err := Do[string](req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if vary.Calls() != 2 {
+		t.Errorf("Expected 2 requests for different languages, got %d", vary.Calls())
+	}
+
+	getCached(t, client, mock.URL()+"/items")
+	req, _ := client.NewRequest("POST", mock.URL()+"/items")
+	if _, _, err := Do[string](req); err != nil {
+		t.Fatal(err)
+	}
+	getCached(t, client, mock.URL()+"/items")
+	if items.Calls() != 2 {
+		t.Errorf("Expected cache invalidated after POST, got %d calls", items.Calls())
+	}
+}
+
+// 测试请求的 Cache-Control 指令
+func TestHTTPCacheRequestDirectives(t *testing.T) {
+	now := time.Now()
+	mock := NewMockServer(t)
+	data := mock.Expect("GET", "/data").RespondHeader("Cache-Control", "max-age=60").Respond(200, "ok").Times(2)
+
+	client := mock.Client()
+	client.middlewares = []Middleware{CacheResponses(newTestCache(t, &now))}
+
+	req, _ := client.NewRequest("GET", mock.URL()+"/missing")
+	req.SetHeaders(map[string]string{"Cache-Control": "only-if-cached"})
+	if err := req.Send(); err != nil || req.GetHttpCode() != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504 for only-if-cached, got %d %v", req.GetHttpCode(), err)
+	}
+
+	getCached(t, client, mock.URL()+"/data")
+	req, _ = client.NewRequest("GET", mock.URL()+"/data")
+	req.SetHeaders(map[string]string{"Cache-Control": "no-cache"})
+	if err := req.Send(); err != nil || req.IsFromCache() {
+		t.Errorf("Expected no-cache request sent to server, err %v", err)
+	}
+	req.Close()
+
+	now = now.Add(90 * time.Second)
+	req, _ = client.NewRequest("GET", mock.URL()+"/data")
+	req.SetHeaders(map[string]string{"Cache-Control": "max-stale=60"})
+	if err := req.Send(); err != nil || req.GetCacheStatus() != CacheHit {
+		t.Errorf("Expected stale response accepted, got %s %v", req.GetCacheStatus(), err)
+	}
+	req.Close()
+	if data.Calls() != 2 {
+		t.Errorf("Expected 2 requests, got %d", data.Calls())
+	}
+}
+
+// 测试有效期计算
+func TestCacheEntryFreshness(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header http.Header
+		want   time.Duration
+	}{
+		{http.Header{"Cache-Control": {"public, max-age=30"}, "Expires": {date.Add(time.Hour).Format(http.TimeFormat)}}, 30 * time.Second},
+		{http.Header{"Expires": {date.Add(time.Hour).Format(http.TimeFormat)}}, time.Hour},
+		{http.Header{"Expires": {"0"}}, 0},
+		{http.Header{"Last-Modified": {date.Add(-10 * time.Hour).Format(http.TimeFormat)}}, time.Hour},
+		{http.Header{"Last-Modified": {date.Add(-1000 * time.Hour).Format(http.TimeFormat)}}, 24 * time.Hour},
+		{http.Header{}, 0},
+	}
+	for _, tt := range tests {
+		entry := &cacheEntry{StatusCode: 200, Header: tt.header, Date: date}
+		if got := entry.freshnessLifetime(); got != tt.want {
+			t.Errorf("freshnessLifetime(%v) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
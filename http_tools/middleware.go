@@ -0,0 +1,222 @@
+package http_tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/otkinlife/go_tools/logger_tools"
+)
+
+// RoundTripFunc 发送一次请求并返回响应
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware 请求中间件，包装下一个 RoundTripFunc
+// 中间件在每次尝试时都会执行，重试时会再执行一遍
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// chain 按顺序组合中间件，第一个中间件在最外层
+func chain(final RoundTripFunc, middlewares []Middleware) RoundTripFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		final = middlewares[i](final)
+	}
+	return final
+}
+
+// BearerAuth 添加 Authorization: Bearer token 请求头
+func BearerAuth(token string) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("Authorization", "Bearer "+token)
+			return next(req)
+		}
+	}
+}
+
+// BasicAuth 添加 HTTP Basic 认证请求头
+func BasicAuth(username, password string) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			req.SetBasicAuth(username, password)
+			return next(req)
+		}
+	}
+}
+
+// HMAC签名使用的请求头
+const (
+	HeaderHMACKey           = "X-Auth-Key"
+	HeaderHMACTimestamp     = "X-Auth-Timestamp"
+	HeaderHMACSignature     = "X-Auth-Signature"
+	HeaderHMACContentSHA256 = "X-Auth-Content-Sha256"
+)
+
+// UnsignedPayload 流式请求体不参与签名时 X-Auth-Content-Sha256 的值
+const UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// HMACAuth 使用 HMAC-SHA256 对请求签名
+// 添加 X-Auth-Key、X-Auth-Timestamp(Unix秒)、X-Auth-Content-Sha256、X-Auth-Signature 四个请求头，签名的计算方式见 HMACSignature
+// 请求体的SHA256可以由调用方提前写入 X-Auth-Content-Sha256，此时不会读取请求体
+// 流式请求体(不能重放的请求体和 multipart 上传)不会读入内存，签名时使用 UnsignedPayload，请求体不受签名保护
+func HMACAuth(keyID string, secret []byte) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			bodyHash := req.Header.Get(HeaderHMACContentSHA256)
+			if bodyHash == "" {
+				if isStreamingBody(req) {
+					bodyHash = UnsignedPayload
+				} else {
+					var err error
+					if bodyHash, err = bodySHA256(req); err != nil {
+						return nil, err
+					}
+				}
+			}
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			req.Header.Set(HeaderHMACKey, keyID)
+			req.Header.Set(HeaderHMACTimestamp, timestamp)
+			req.Header.Set(HeaderHMACContentSHA256, bodyHash)
+			req.Header.Set(HeaderHMACSignature, hmacSignature(req, secret, timestamp, bodyHash))
+			return next(req)
+		}
+	}
+}
+
+// HMACSignature 计算请求的签名，服务端可以用它校验 HMACAuth 生成的签名
+// 签名内容为: 请求方法\n路径和参数\ntimestamp\n请求体的SHA256(hex)
+// X-Auth-Content-Sha256 为 UnsignedPayload 时使用 UnsignedPayload 代替请求体的SHA256，不读取请求体
+// 返回 HMAC-SHA256 的hex编码
+func HMACSignature(req *http.Request, secret []byte, timestamp string) (string, error) {
+	bodyHash := req.Header.Get(HeaderHMACContentSHA256)
+	if bodyHash != UnsignedPayload {
+		var err error
+		if bodyHash, err = bodySHA256(req); err != nil {
+			return "", err
+		}
+	}
+	return hmacSignature(req, secret, timestamp, bodyHash), nil
+}
+
+func hmacSignature(req *http.Request, secret []byte, timestamp, bodyHash string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(req.Method + "\n" + req.URL.RequestURI() + "\n" + timestamp + "\n" + bodyHash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// bodySHA256 返回请求体SHA256的hex编码，不消耗请求体
+func bodySHA256(req *http.Request) (string, error) {
+	body, err := peekBody(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// isStreamingBody 请求体是否是流式的，读取它需要把整个请求体放入内存或者重新读取文件
+func isStreamingBody(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return false
+	}
+	if _, ok := req.Body.(*multipartBody); ok {
+		return true
+	}
+	return req.GetBody == nil
+}
+
+// HeaderRequestID 默认的请求ID请求头
+const HeaderRequestID = "X-Request-Id"
+
+// RequestID 添加请求ID请求头，header为空时使用 X-Request-Id
+// 请求的context由 logger_tools.NewContext 创建时使用其中的 trace_id，否则生成新的UUID，已经设置了该请求头时不覆盖
+func RequestID(header string) Middleware {
+	if header == "" {
+		header = HeaderRequestID
+	}
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) == "" {
+				id := logger_tools.GetTraceID(req.Context())
+				if id == "" {
+					id = uuid.NewString()
+				}
+				req.Header.Set(header, id)
+			}
+			return next(req)
+		}
+	}
+}
+
+// CurlLogger 以curl命令的格式打印请求，logf为空时使用 log.Printf
+// 通过 ReqClient 发送的文件使用 @filepath 格式，不会打印文件内容
+func CurlLogger(logf func(format string, args ...any)) Middleware {
+	if logf == nil {
+		logf = log.Printf
+	}
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			var formFields map[string]string
+			var files []fileField
+			if source, ok := req.Context().Value(curlSourceKey{}).(*ReqClient); ok {
+				formFields, files = source.formFields, source.files
+			}
+			curlCmd, err := buildCurlCommand(req, formFields, files)
+			if err != nil {
+				logf("Failed to generate curl command: %v", err)
+			} else {
+				logf("%s", curlCmd)
+			}
+			return next(req)
+		}
+	}
+}
+
+// curlSourceKey 在请求的context中保存 ReqClient，用于 CurlLogger 获取表单和文件
+type curlSourceKey struct{}
+
+// Timing 在每次请求结束后调用observe，传入请求耗时
+func Timing(observe func(req *http.Request, resp *http.Response, err error, elapsed time.Duration)) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			observe(req, resp, err, time.Since(start))
+			return resp, err
+		}
+	}
+}
+
+// peekBody 读取请求体但不消耗它
+func peekBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// withCurlSource 在context中保存发送请求的 ReqClient
+func withCurlSource(ctx context.Context, r *ReqClient) context.Context {
+	return context.WithValue(ctx, curlSourceKey{}, r)
+}
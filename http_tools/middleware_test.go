@@ -0,0 +1,262 @@
+package http_tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/otkinlife/go_tools/logger_tools"
+)
+
+// 测试中间件的执行顺序，客户端的中间件在请求的中间件之前
+func TestMiddlewareOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Join(r.Header.Values("X-Trace"), ",")))
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				order = append(order, name+" before")
+				mu.Unlock()
+				req.Header.Add("X-Trace", name)
+				resp, err := next(req)
+				mu.Lock()
+				order = append(order, name+" after")
+				mu.Unlock()
+				return resp, err
+			}
+		}
+	}
+
+	client, err := NewClientBuilder().Use(tag("client1"), tag("client2")).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := client.NewRequest("GET", server.URL)
+	req.Use(tag("request"))
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	defer req.Close()
+
+	if body := req.GetBodyString(); body != "client1,client2,request" {
+		t.Errorf("Unexpected header order %q", body)
+	}
+	want := "[client1 before client2 before request before request after client2 after client1 after]"
+	if fmt.Sprint(order) != want {
+		t.Errorf("Expected %s, got %v", want, order)
+	}
+
+	// 请求的中间件不会影响客户端创建的其他请求
+	other, _ := client.NewRequest("GET", server.URL)
+	if len(other.middlewares) != 2 {
+		t.Errorf("Expected 2 client middlewares, got %d", len(other.middlewares))
+	}
+}
+
+// 测试认证中间件
+func TestAuthMiddlewares(t *testing.T) {
+	secret := []byte("s3cret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bearer":
+			_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+		case "/basic":
+			user, pass, _ := r.BasicAuth()
+			_, _ = w.Write([]byte(user + ":" + pass))
+		case "/hmac":
+			want, err := HMACSignature(r, secret, r.Header.Get(HeaderHMACTimestamp))
+			if err != nil || r.Header.Get(HeaderHMACSignature) != want || r.Header.Get(HeaderHMACKey) != "key-1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte("signed"))
+		}
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		path       string
+		middleware Middleware
+		want       string
+	}{
+		{"/bearer", BearerAuth("token"), "Bearer token"},
+		{"/basic", BasicAuth("alice", "pw"), "alice:pw"},
+		{"/hmac?a=1", HMACAuth("key-1", secret), "signed"},
+	}
+	for _, tc := range cases {
+		req, _ := NewReqClient("POST", server.URL+tc.path)
+		req.Use(tc.middleware)
+		if err := req.SetJson(map[string]int{"n": 1}); err != nil {
+			t.Fatal(err)
+		}
+		if err := req.Send(); err != nil {
+			t.Fatal(err)
+		}
+		if body := req.GetBodyString(); body != tc.want {
+			t.Errorf("%s: expected %q, got %q (status %d)", tc.path, tc.want, body, req.GetHttpCode())
+		}
+		req.Close()
+	}
+}
+
+// 测试流式请求体不读取请求体签名，以及调用方提供请求体的SHA256
+func TestHMACAuthStreamingBody(t *testing.T) {
+	secret := []byte("s3cret")
+	var contentHash string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentHash = r.Header.Get(HeaderHMACContentSHA256)
+		want, err := HMACSignature(r, secret, r.Header.Get(HeaderHMACTimestamp))
+		if err != nil || r.Header.Get(HeaderHMACSignature) != want {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(path, []byte("file content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := NewReqClient("POST", server.URL)
+	req.Use(HMACAuth("key-1", secret))
+	if err := req.SetFile("file", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if req.GetHttpCode() != http.StatusOK || contentHash != UnsignedPayload {
+		t.Errorf("Expected unsigned payload accepted, got %d %q", req.GetHttpCode(), contentHash)
+	}
+	req.Close()
+
+	body := []byte(`{"n":1}`)
+	sum := sha256.Sum256(body)
+	for _, hash := range []string{hex.EncodeToString(sum[:]), strings.Repeat("0", 64)} {
+		req, _ := NewReqClient("POST", server.URL)
+		req.Use(HMACAuth("key-1", secret))
+		req.SetHeaders(map[string]string{HeaderHMACContentSHA256: hash})
+		req.SetBody(body)
+		if err := req.Send(); err != nil {
+			t.Fatal(err)
+		}
+		if ok := req.GetHttpCode() == http.StatusOK; ok != (hash == hex.EncodeToString(sum[:])) {
+			t.Errorf("Unexpected status %d for content hash %s", req.GetHttpCode(), hash)
+		}
+		req.Close()
+	}
+}
+
+// 测试请求ID使用 logger_tools 的 trace_id
+func TestRequestIDMiddleware(t *testing.T) {
+	var got string
+	final := func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Get(HeaderRequestID)
+		return &http.Response{StatusCode: 200}, nil
+	}
+	roundTrip := chain(final, []Middleware{RequestID("")})
+
+	ctx := logger_tools.NewContext(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com", nil)
+	if _, err := roundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if got == "" || got != logger_tools.GetTraceID(ctx) {
+		t.Errorf("Expected trace id %q, got %q", logger_tools.GetTraceID(ctx), got)
+	}
+
+	req, _ = http.NewRequest("GET", "http://example.com", nil)
+	if _, err := roundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 36 {
+		t.Errorf("Expected generated uuid, got %q", got)
+	}
+
+	req.Header.Set(HeaderRequestID, "fixed")
+	_, _ = roundTrip(req)
+	if got != "fixed" {
+		t.Errorf("Expected existing request id to be kept, got %q", got)
+	}
+}
+
+// 测试curl日志和计时中间件
+func TestCurlLoggerAndTiming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tmpFile, err := os.CreateTemp(t.TempDir(), "upload_*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = tmpFile.WriteString("file content")
+	tmpFile.Close()
+
+	var logs []string
+	var elapsed time.Duration
+	var status int
+	req, _ := NewReqClient("POST", server.URL)
+	req.Use(
+		Timing(func(req *http.Request, resp *http.Response, err error, d time.Duration) {
+			elapsed, status = d, resp.StatusCode
+		}),
+		BearerAuth("token"),
+		CurlLogger(func(format string, args ...any) {
+			logs = append(logs, fmt.Sprintf(format, args...))
+		}),
+	)
+	req.SetForm(map[string]string{"field": "value"})
+	if err := req.SetFile("file", tmpFile.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	req.Close()
+
+	if len(logs) != 1 {
+		t.Fatalf("Expected 1 curl log, got %v", logs)
+	}
+	for _, part := range []string{"-X POST", "'Authorization: Bearer token'", "-F 'field=value'", "-F 'file=@" + tmpFile.Name() + "'"} {
+		if !strings.Contains(logs[0], part) {
+			t.Errorf("Expected %q in curl log %q", part, logs[0])
+		}
+	}
+	if strings.Contains(logs[0], "file content") {
+		t.Error("Curl log should not contain file content")
+	}
+	if elapsed <= 0 || status != 200 {
+		t.Errorf("Expected timing to observe the response, got %v %d", elapsed, status)
+	}
+
+	// 非表单的请求体使用 --data-raw，打印后请求体仍然可以发送
+	logs = nil
+	req, _ = NewReqClient("PUT", server.URL)
+	req.Use(CurlLogger(func(format string, args ...any) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}))
+	_ = req.SetJson(map[string]string{"a": "b"})
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	req.Close()
+	if len(logs) != 1 || !strings.Contains(logs[0], `--data-raw '{"a":"b"}'`) {
+		t.Errorf("Expected json body in curl log, got %v", logs)
+	}
+}
@@ -0,0 +1,304 @@
+package http_tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TestingT MockServer 需要的 testing.TB 方法，传入 *testing.T 即可
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Cleanup(func())
+}
+
+// MockServer 用于测试的HTTP服务，先声明预期的请求和响应，测试结束时检查是否都被调用
+// 例如:
+//
+//	mock := http_tools.NewMockServer(t)
+//	mock.Expect("GET", "/users/1").WithHeader("Authorization", "Bearer token").RespondJSON(200, user)
+//	cli, _ := mock.Client().NewRequest("GET", mock.URL()+"/users/1")
+type MockServer struct {
+	t            TestingT
+	server       *httptest.Server
+	mu           sync.Mutex
+	expectations []*Expectation
+	requests     []*RecordedRequest
+	unexpected   []string
+}
+
+// NewMockServer 启动测试服务，测试结束时自动关闭并调用 AssertExpectations
+func NewMockServer(t TestingT) *MockServer {
+	m := &MockServer{t: t}
+	m.server = httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+	t.Cleanup(func() {
+		m.Close()
+		m.AssertExpectations()
+	})
+	return m
+}
+
+// URL 返回服务地址，例如 http://127.0.0.1:12345
+func (m *MockServer) URL() string {
+	return m.server.URL
+}
+
+// Client 返回访问该服务的客户端
+func (m *MockServer) Client() *Client {
+	return &Client{transport: m.server.Client().Transport}
+}
+
+// Close 关闭服务
+func (m *MockServer) Close() {
+	m.server.Close()
+}
+
+// Expect 声明预期的请求，path 不包含查询参数，默认预期调用一次，返回200和空响应体
+func (m *MockServer) Expect(method, path string) *Expectation {
+	e := &Expectation{
+		t:      m.t,
+		mu:     &m.mu,
+		method: method,
+		path:   path,
+		times:  1,
+		status: http.StatusOK,
+		header: make(http.Header),
+	}
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+	return e
+}
+
+// Requests 返回收到的所有请求
+func (m *MockServer) Requests() []*RecordedRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*RecordedRequest(nil), m.requests...)
+}
+
+// AssertExpectations 检查所有预期的请求是否都按次数调用，以及是否收到了没有声明的请求
+func (m *MockServer) AssertExpectations() bool {
+	m.t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ok := true
+	for _, e := range m.expectations {
+		if e.times >= 0 && e.calls != e.times {
+			m.t.Errorf("http_tools: %s %s expected %d call(s), got %d", e.method, e.path, e.times, e.calls)
+			ok = false
+		}
+	}
+	for _, request := range m.unexpected {
+		m.t.Errorf("http_tools: unexpected request %s", request)
+		ok = false
+	}
+	return ok
+}
+
+func (m *MockServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := readRequestBody(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	m.requests = append(m.requests, &RecordedRequest{
+		Method: r.Method,
+		URL:    r.URL.String(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	var matched *Expectation
+	for _, e := range m.expectations {
+		if (e.times < 0 || e.calls < e.times) && e.matches(r, body) {
+			matched = e
+			e.calls++
+			break
+		}
+	}
+	if matched == nil {
+		m.unexpected = append(m.unexpected, r.Method+" "+r.URL.String())
+	}
+	m.mu.Unlock()
+
+	if matched == nil {
+		http.Error(w, fmt.Sprintf("http_tools: no expectation matches %s %s", r.Method, r.URL), http.StatusNotImplemented)
+		return
+	}
+	matched.respond(w, r)
+}
+
+// Expectation 预期的请求和对应的响应，所有方法都返回自身，可以链式调用
+type Expectation struct {
+	t        TestingT
+	mu       *sync.Mutex
+	method   string
+	path     string
+	query    map[string]string
+	headers  map[string]string
+	body     []byte
+	matchers []func(r *http.Request, body []byte) bool
+
+	times int // 预期调用次数，-1表示不限
+	calls int
+
+	status  int
+	header  http.Header
+	resp    []byte
+	handler http.HandlerFunc
+	delay   time.Duration
+}
+
+// WithQuery 要求查询参数 key 的值为 value
+func (e *Expectation) WithQuery(key, value string) *Expectation {
+	if e.query == nil {
+		e.query = make(map[string]string)
+	}
+	e.query[key] = value
+	return e
+}
+
+// WithHeader 要求请求头 key 的值为 value
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	if e.headers == nil {
+		e.headers = make(map[string]string)
+	}
+	e.headers[key] = value
+	return e
+}
+
+// WithBody 要求请求体相同，两边都是JSON时按JSON比较
+func (e *Expectation) WithBody(body string) *Expectation {
+	e.body = []byte(body)
+	return e
+}
+
+// WithJSONBody 要求请求体与v序列化后的JSON相同，忽略字段顺序
+func (e *Expectation) WithJSONBody(v any) *Expectation {
+	data, err := json.Marshal(v)
+	if err != nil {
+		e.t.Helper()
+		e.t.Errorf("http_tools: marshal expected body: %v", err)
+	}
+	e.body = data
+	return e
+}
+
+// Match 添加自定义的匹配规则
+func (e *Expectation) Match(fn func(r *http.Request, body []byte) bool) *Expectation {
+	e.matchers = append(e.matchers, fn)
+	return e
+}
+
+// Times 预期调用n次
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Once 预期调用一次
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+// AnyTimes 不限制调用次数，也可以不调用
+func (e *Expectation) AnyTimes() *Expectation {
+	return e.Times(-1)
+}
+
+// Respond 设置响应状态码和响应体
+func (e *Expectation) Respond(status int, body string) *Expectation {
+	e.status = status
+	e.resp = []byte(body)
+	return e
+}
+
+// RespondJSON 设置状态码，响应体为v序列化后的JSON
+func (e *Expectation) RespondJSON(status int, v any) *Expectation {
+	data, err := json.Marshal(v)
+	if err != nil {
+		e.t.Helper()
+		e.t.Errorf("http_tools: marshal response body: %v", err)
+	}
+	e.header.Set("Content-Type", "application/json")
+	e.status = status
+	e.resp = data
+	return e
+}
+
+// RespondHeader 设置响应头
+func (e *Expectation) RespondHeader(key, value string) *Expectation {
+	e.header.Set(key, value)
+	return e
+}
+
+// RespondWith 使用自定义的处理函数响应，设置后 Respond 等设置不再生效
+func (e *Expectation) RespondWith(handler http.HandlerFunc) *Expectation {
+	e.handler = handler
+	return e
+}
+
+// Delay 响应前等待一段时间，用于测试超时，请求取消时提前返回
+func (e *Expectation) Delay(d time.Duration) *Expectation {
+	e.delay = d
+	return e
+}
+
+// Calls 返回已经调用的次数
+func (e *Expectation) Calls() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls
+}
+
+func (e *Expectation) matches(r *http.Request, body []byte) bool {
+	if !strings.EqualFold(r.Method, e.method) || r.URL.Path != e.path {
+		return false
+	}
+	query := r.URL.Query()
+	for key, value := range e.query {
+		if query.Get(key) != value {
+			return false
+		}
+	}
+	for key, value := range e.headers {
+		if r.Header.Get(key) != value {
+			return false
+		}
+	}
+	if e.body != nil && !sameBody(body, e.body) {
+		return false
+	}
+	for _, fn := range e.matchers {
+		if !fn(r, body) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Expectation) respond(w http.ResponseWriter, r *http.Request) {
+	if e.delay > 0 {
+		select {
+		case <-time.After(e.delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if e.handler != nil {
+		e.handler(w, r)
+		return
+	}
+	for key, values := range e.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(e.status)
+	_, _ = w.Write(e.resp)
+}
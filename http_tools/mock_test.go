@@ -0,0 +1,80 @@
+package http_tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeT 记录 MockServer 报告的错误
+type fakeT struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+// 测试按请求方法、路径、参数、请求头和请求体匹配
+func TestMockServerExpectations(t *testing.T) {
+	mock := NewMockServer(t)
+	mock.Expect("GET", "/users").WithQuery("page", "2").WithHeader("Authorization", "Bearer token").
+		RespondJSON(200, []string{"alice"}).Times(2)
+	create := mock.Expect("POST", "/users").WithJSONBody(map[string]string{"name": "bob"}).
+		Respond(http.StatusCreated, "created").RespondHeader("Location", "/users/2")
+
+	client := mock.Client()
+	client.middlewares = []Middleware{BearerAuth("token")}
+	for i := 0; i < 2; i++ {
+		req, _ := client.NewRequest("GET", mock.URL()+"/users?page=2")
+		users, _, err := Do[[]string](req)
+		if err != nil || len(users) != 1 || users[0] != "alice" {
+			t.Errorf("Unexpected users %v %v", users, err)
+		}
+	}
+
+	req, _ := client.NewRequest("POST", mock.URL()+"/users")
+	req.SetJson(json.RawMessage(`{ "name": "bob" }`))
+	body, resp, err := Do[string](req)
+	if err != nil || body != "created" || resp.StatusCode != 201 || resp.Header.Get("Location") != "/users/2" {
+		t.Errorf("Unexpected response %q %v", body, err)
+	}
+	if create.Calls() != 1 || len(mock.Requests()) != 3 {
+		t.Errorf("Expected 3 requests, got %d", len(mock.Requests()))
+	}
+}
+
+// 测试缺少和多余的请求会报告错误
+func TestMockServerReportsMismatch(t *testing.T) {
+	ft := &fakeT{}
+	mock := NewMockServer(ft)
+	mock.Expect("GET", "/ping").Respond(200, "pong")
+	mock.Expect("DELETE", "/users/1")
+
+	for i := 0; i < 2; i++ {
+		req, _ := mock.Client().NewRequest("GET", mock.URL()+"/ping")
+		_ = req.Send()
+		if i == 1 && req.GetHttpCode() != http.StatusNotImplemented {
+			t.Errorf("Expected 501 for unexpected request, got %d", req.GetHttpCode())
+		}
+		req.Close()
+	}
+	for _, fn := range ft.cleanups {
+		fn()
+	}
+
+	report := strings.Join(ft.errors, "\n")
+	if len(ft.errors) != 2 || !strings.Contains(report, "DELETE /users/1 expected 1 call(s), got 0") ||
+		!strings.Contains(report, "unexpected request GET /ping") {
+		t.Errorf("Unexpected report:\n%s", report)
+	}
+}
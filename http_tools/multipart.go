@@ -0,0 +1,325 @@
+package http_tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// fileField 上传的文件，来源为文件路径或 io.Reader
+type fileField struct {
+	fieldName   string
+	filePath    string
+	reader      io.Reader
+	offset      int64 // reader 可以 Seek 时的起始位置，用于重放请求体
+	fileName    string
+	contentType string
+	size        int64 // 文件大小，-1表示未知
+}
+
+// FileOption 上传文件的可选配置
+type FileOption func(*fileField)
+
+// WithFileName 设置上传时使用的文件名，默认使用文件路径的文件名
+func WithFileName(name string) FileOption {
+	return func(f *fileField) {
+		f.fileName = name
+	}
+}
+
+// WithContentType 设置文件的 Content-Type，默认 application/octet-stream
+func WithContentType(contentType string) FileOption {
+	return func(f *fileField) {
+		f.contentType = contentType
+	}
+}
+
+// WithFileSize 设置 io.Reader 的大小，所有文件大小已知时请求会带上 Content-Length
+func WithFileSize(size int64) FileOption {
+	return func(f *fileField) {
+		f.size = size
+	}
+}
+
+// UploadProgress 上传进度回调，written 为已发送的字节数，total 为请求体总大小，未知时为-1
+// 回调在发送请求体的协程中执行
+type UploadProgress func(written, total int64)
+
+// newFileField 创建上传文件，对 io.Reader 尽量推断大小和起始位置
+func newFileField(fieldName, filePath string, reader io.Reader, opts []FileOption) fileField {
+	f := fileField{
+		fieldName: fieldName,
+		filePath:  filePath,
+		reader:    reader,
+		size:      -1,
+	}
+	if filePath != "" {
+		f.fileName = filepath.Base(filePath)
+	}
+	if reader != nil {
+		switch v := reader.(type) {
+		case interface{ Len() int }:
+			f.size = int64(v.Len())
+		case io.Seeker:
+			if offset, err := v.Seek(0, io.SeekCurrent); err == nil {
+				if end, err := v.Seek(0, io.SeekEnd); err == nil {
+					f.size = end - offset
+				}
+				_, _ = v.Seek(offset, io.SeekStart)
+			}
+		}
+		if seeker, ok := reader.(io.Seeker); ok {
+			f.offset, _ = seeker.Seek(0, io.SeekCurrent)
+		}
+	}
+	for _, opt := range opts {
+		opt(&f)
+	}
+	return f
+}
+
+// replayable 请求体是否可以重新生成
+func (f *fileField) replayable() bool {
+	if f.reader == nil {
+		return true
+	}
+	_, ok := f.reader.(io.Seeker)
+	return ok
+}
+
+// open 打开文件内容，文件路径每次重新打开，io.Reader 可以 Seek 时回到起始位置
+func (f *fileField) open() (io.ReadCloser, error) {
+	if f.reader == nil {
+		return os.Open(f.filePath)
+	}
+	if seeker, ok := f.reader.(io.Seeker); ok {
+		if _, err := seeker.Seek(f.offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return io.NopCloser(f.reader), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// header 文件part的头部
+func (f *fileField) header() textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(f.fieldName), quoteEscaper.Replace(f.fileName)))
+	contentType := f.contentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.Set("Content-Type", contentType)
+	return h
+}
+
+// buildMultipartRequest 构建流式的multipart请求体，文件内容在发送时才读取，不会全部读入内存
+func (r *ReqClient) buildMultipartRequest() error {
+	if len(r.formFields) == 0 && len(r.files) == 0 {
+		return nil
+	}
+
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	length, err := r.multipartLength(boundary)
+	if err != nil {
+		return err
+	}
+
+	replayable := true
+	for i := range r.files {
+		replayable = replayable && r.files[i].replayable()
+	}
+
+	ctx := r.req.Context()
+	var mu sync.Mutex
+	var last *multipartBody
+	newBody := func() (io.ReadCloser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		// 重放时文件来源是同一个 io.Reader，需要等上一次的写入协程退出后才能重新读取
+		if last != nil {
+			_ = last.Close()
+		}
+		last = &multipartBody{
+			ctx:      ctx,
+			boundary: boundary,
+			fields:   r.formFields,
+			files:    r.files,
+			total:    length,
+			progress: r.uploadProgress,
+		}
+		return last, nil
+	}
+	r.req.Body, _ = newBody()
+	r.req.ContentLength = length
+	r.req.GetBody = nil
+	if replayable {
+		r.req.GetBody = newBody
+	}
+	r.req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+	return nil
+}
+
+// multipartLength 计算请求体的长度，有文件大小未知时返回-1
+// 文件不存在时返回错误，避免发送请求之后才发现
+func (r *ReqClient) multipartLength(boundary string) (int64, error) {
+	var fileSize int64
+	known := true
+	for i := range r.files {
+		f := &r.files[i]
+		size := f.size
+		if f.reader == nil {
+			info, err := os.Stat(f.filePath)
+			if err != nil {
+				return 0, err
+			}
+			size = info.Size()
+		}
+		if size < 0 {
+			known = false
+		}
+		fileSize += size
+	}
+
+	counter := &countingWriter{}
+	writer := multipart.NewWriter(counter)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return 0, err
+	}
+	if err := writeMultipart(writer, r.formFields, r.files, func(part io.Writer, f *fileField) error { return nil }); err != nil {
+		return 0, err
+	}
+	if !known {
+		return -1, nil
+	}
+	return counter.n + fileSize, nil
+}
+
+// writeMultipart 按顺序写入表单字段和文件，文件内容由writeFile写入
+func writeMultipart(writer *multipart.Writer, fields map[string]string, files []fileField, writeFile func(part io.Writer, f *fileField) error) error {
+	// 添加表单字段
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			return err
+		}
+	}
+
+	// 添加文件
+	for i := range files {
+		part, err := writer.CreatePart(files[i].header())
+		if err != nil {
+			return err
+		}
+		if err := writeFile(part, &files[i]); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// multipartBody 流式的multipart请求体，第一次读取时才启动写入协程，未发送的请求不会留下协程
+type multipartBody struct {
+	ctx      context.Context
+	boundary string
+	fields   map[string]string
+	files    []fileField
+	total    int64
+	progress UploadProgress
+
+	once   sync.Once
+	reader *io.PipeReader
+	done   chan struct{} // 写入协程退出后关闭
+}
+
+func (b *multipartBody) Read(p []byte) (int, error) {
+	b.once.Do(b.start)
+	return b.reader.Read(p)
+}
+
+// Close 关闭请求体，等待写入协程因为管道关闭而退出后返回，之后可以安全地重新读取文件来源
+func (b *multipartBody) Close() error {
+	b.once.Do(func() {
+		b.reader, _ = io.Pipe()
+		b.done = make(chan struct{})
+		close(b.done)
+	})
+	err := b.reader.Close()
+	<-b.done
+	return err
+}
+
+func (b *multipartBody) start() {
+	pr, pw := io.Pipe()
+	b.reader = pr
+	b.done = make(chan struct{})
+	go func() {
+		defer close(b.done)
+		var out io.Writer = pw
+		if b.progress != nil {
+			out = &progressWriter{w: pw, total: b.total, progress: b.progress}
+		}
+		writer := multipart.NewWriter(out)
+		err := writer.SetBoundary(b.boundary)
+		if err == nil {
+			err = writeMultipart(writer, b.fields, b.files, func(part io.Writer, f *fileField) error {
+				file, err := f.open()
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				// context取消后停止读取文件
+				_, err = io.Copy(part, contextReader{ctx: b.ctx, r: file})
+				return err
+			})
+		}
+		_ = pw.CloseWithError(err)
+	}()
+}
+
+// contextReader context取消后读取返回context的错误
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// countingWriter 只统计写入的字节数
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// progressWriter 写入时回调上传进度
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress UploadProgress
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	if n > 0 {
+		p.written += int64(n)
+		p.progress(p.written, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,215 @@
+package http_tools
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// uploadServer 解析multipart请求，返回每个part的字段名、文件名、类型和内容的哈希
+func uploadServer(t *testing.T, fail *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail != nil && fail.Add(-1) >= 0 {
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var lines []string
+		lines = append(lines, fmt.Sprintf("length=%d", r.ContentLength))
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			h := sha256.New()
+			n, _ := io.Copy(h, part)
+			lines = append(lines, fmt.Sprintf("%s|%s|%s|%d|%s", part.FormName(), part.FileName(),
+				part.Header.Get("Content-Type"), n, hex.EncodeToString(h.Sum(nil))[:8]))
+		}
+		_, _ = w.Write([]byte(strings.Join(lines, "\n")))
+	}))
+}
+
+func hashPrefix(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// 测试流式上传文件，带 Content-Length 和上传进度
+func TestStreamingUpload(t *testing.T) {
+	server := uploadServer(t, nil)
+	defer server.Close()
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 256*1024) // 4MB
+	path := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var lastWritten, lastTotal atomic.Int64
+	var calls atomic.Int32
+	req, _ := NewReqClient("POST", server.URL)
+	req.SetForm(map[string]string{"name": "big"})
+	if err := req.SetFile("file", path, WithContentType("video/mp4"), WithFileName("movie.mp4")); err != nil {
+		t.Fatal(err)
+	}
+	req.SetUploadProgress(func(written, total int64) {
+		calls.Add(1)
+		lastWritten.Store(written)
+		lastTotal.Store(total)
+	})
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	defer req.Close()
+
+	body := req.GetBodyString()
+	want := fmt.Sprintf("file|movie.mp4|video/mp4|%d|%s", len(content), hashPrefix(content))
+	if !strings.Contains(body, want) || !strings.Contains(body, "name||") {
+		t.Errorf("Unexpected parts:\n%s", body)
+	}
+	if !strings.HasPrefix(body, fmt.Sprintf("length=%d\n", lastTotal.Load())) || lastTotal.Load() <= int64(len(content)) {
+		t.Errorf("Expected Content-Length %d, got:\n%s", lastTotal.Load(), body)
+	}
+	if lastWritten.Load() != lastTotal.Load() || calls.Load() < 2 {
+		t.Errorf("Expected progress to reach total, got %d/%d in %d calls", lastWritten.Load(), lastTotal.Load(), calls.Load())
+	}
+}
+
+// 测试从 io.Reader 上传，大小未知时使用分块传输
+func TestUploadFromReader(t *testing.T) {
+	server := uploadServer(t, nil)
+	defer server.Close()
+
+	content := []byte("streamed content")
+	req, _ := NewReqClient("POST", server.URL)
+	req.SetFileReader("doc", "doc.txt", iotest.HalfReader(bytes.NewBuffer(content)), WithContentType("text/plain"))
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	defer req.Close()
+
+	body := req.GetBodyString()
+	want := fmt.Sprintf("doc|doc.txt|text/plain|%d|%s", len(content), hashPrefix(content))
+	if !strings.HasPrefix(body, "length=-1\n") || !strings.Contains(body, want) {
+		t.Errorf("Unexpected upload:\n%s", body)
+	}
+
+	curl, err := req.ConvertToCurlWithFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(curl, "-F 'doc=@doc.txt;type=text/plain'") {
+		t.Errorf("Unexpected curl command %s", curl)
+	}
+}
+
+// 测试可以 Seek 的 io.Reader 在重试时重放，不能 Seek 时不重试
+func TestUploadRetry(t *testing.T) {
+	var fail atomic.Int32
+	server := uploadServer(t, &fail)
+	defer server.Close()
+
+	content := []byte("retry me")
+	policy := NewRetryPolicy(3).SetBackoff(time.Millisecond, time.Millisecond).SetRetryMethods(http.MethodPost)
+
+	fail.Store(2)
+	req, _ := NewReqClient("POST", server.URL)
+	req.SetRetryPolicy(policy)
+	req.SetFileReader("file", "a.txt", bytes.NewReader(content))
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	body := req.GetBodyString()
+	req.Close()
+	if !strings.Contains(body, fmt.Sprintf("file|a.txt|application/octet-stream|%d|%s", len(content), hashPrefix(content))) {
+		t.Errorf("Expected replayed upload, got:\n%s", body)
+	}
+	if !strings.HasPrefix(body, "length=") || strings.HasPrefix(body, "length=-1") {
+		t.Errorf("Expected known Content-Length for bytes.Reader, got:\n%s", body)
+	}
+
+	fail.Store(1)
+	req, _ = NewReqClient("POST", server.URL)
+	req.SetRetryPolicy(policy)
+	req.SetFileReader("file", "a.txt", io.MultiReader(bytes.NewReader(content)))
+	if err := req.Send(); err == nil || !strings.Contains(err.Error(), "cannot be replayed") {
+		t.Errorf("Expected replay error, got %v", err)
+	}
+}
+
+// 测试服务端没有读完请求体就返回时，重放等待上一次的写入协程退出后再读取同一个 io.Reader
+func TestUploadRetryUnreadBody(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	want := sha256.Sum256(content)
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.Header().Set("Connection", "close")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, file, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f, _ := file.Open()
+		defer f.Close()
+		h := sha256.New()
+		_, _ = io.Copy(h, f)
+		if !bytes.Equal(h.Sum(nil), want[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	req, _ := NewReqClient("POST", server.URL)
+	defer req.Close()
+	req.SetRetryPolicy(NewRetryPolicy(3).SetBackoff(time.Millisecond, time.Millisecond).SetRetryMethods(http.MethodPost))
+	req.SetFileReader("file", "big.bin", bytes.NewReader(content))
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if req.GetHttpCode() != http.StatusOK || calls.Load() != 3 {
+		t.Errorf("Expected upload intact after retries, got %d after %d calls", req.GetHttpCode(), calls.Load())
+	}
+}
+
+// 测试文件不存在时不发送请求
+func TestUploadMissingFile(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	req, _ := NewReqClient("POST", server.URL)
+	_ = req.SetFile("file", filepath.Join(t.TempDir(), "missing.bin"))
+	if err := req.Send(); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Error("Expected request not to be sent")
+	}
+}
@@ -0,0 +1,531 @@
+package http_tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited 不等待模式下没有许可，或者等待时间超过了context的截止时间
+var ErrRateLimited = errors.New("http_tools: rate limited")
+
+// Limiter 限制请求的速率或并发数，并发安全，可以在多个客户端之间共享
+type Limiter interface {
+	// Acquire 等待直到获得许可或ctx结束，请求结束后调用 release
+	Acquire(ctx context.Context) (release func(), err error)
+	// TryAcquire 不等待，没有许可时返回false
+	TryAcquire() (release func(), ok bool)
+	// Stats 返回当前状态
+	Stats() LimiterStats
+}
+
+// LimiterStats 限流器的当前状态
+type LimiterStats struct {
+	Available float64 // 可用的许可: 令牌桶的令牌数、滑动窗口剩余的次数、空闲的并发数
+	Waiting   int     // 正在等待的调用方数量
+	InFlight  int     // 正在进行的请求数量，只有并发限制有
+}
+
+// LimitMode 没有许可时的处理方式
+type LimitMode int
+
+const (
+	LimitWait     LimitMode = iota // 等待许可，请求的context取消时返回
+	LimitFailFast                  // 直接返回 ErrRateLimited
+)
+
+func noopRelease() {}
+
+// limiterState 限流器共用的等待逻辑，状态变化(释放许可、调整限制)时唤醒所有等待的调用方重新检查
+type limiterState struct {
+	mu      sync.Mutex
+	changed chan struct{}
+	waiting int
+	now     func() time.Time
+}
+
+func newLimiterState() limiterState {
+	return limiterState{changed: make(chan struct{}), now: time.Now}
+}
+
+// broadcast 唤醒等待的调用方，需要持有锁
+func (s *limiterState) broadcast() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// acquire 在持有锁时调用try，try返回是否获得许可和下次检查前需要等待的时间，等待时间为0表示等待状态变化
+// 等待时间超过ctx的截止时间时直接返回 ErrRateLimited
+func (s *limiterState) acquire(ctx context.Context, try func(now time.Time) (bool, time.Duration)) error {
+	s.mu.Lock()
+	for {
+		now := s.now()
+		ok, wait := try(now)
+		if ok {
+			s.mu.Unlock()
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		if deadline, has := ctx.Deadline(); has && wait > 0 && now.Add(wait).After(deadline) {
+			s.mu.Unlock()
+			return fmt.Errorf("%w: wait %v exceeds context deadline", ErrRateLimited, wait)
+		}
+
+		changed := s.changed
+		s.waiting++
+		s.mu.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		s.mu.Lock()
+		s.waiting--
+	}
+}
+
+// TokenBucket 令牌桶，按固定速率生成令牌，最多积累 burst 个，允许短时间的突发请求
+type TokenBucket struct {
+	limiterState
+	rate   float64 // 每秒生成的令牌数
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket 创建令牌桶，rate 为每秒的请求数，burst 为最多允许的突发请求数，初始时令牌是满的
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	b := &TokenBucket{limiterState: newLimiterState(), rate: rate, burst: max(burst, 1)}
+	b.tokens = float64(b.burst)
+	b.last = b.now()
+	return b
+}
+
+// SetRate 调整每秒生成的令牌数，0表示暂停生成
+func (b *TokenBucket) SetRate(rate float64) *TokenBucket {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(b.now())
+	b.rate = rate
+	b.broadcast()
+	return b
+}
+
+// SetBurst 调整最多积累的令牌数
+func (b *TokenBucket) SetBurst(burst int) *TokenBucket {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(b.now())
+	b.burst = max(burst, 1)
+	b.tokens = min(b.tokens, float64(b.burst))
+	b.broadcast()
+	return b
+}
+
+// Acquire 等待一个令牌
+func (b *TokenBucket) Acquire(ctx context.Context) (func(), error) {
+	if err := b.acquire(ctx, b.take); err != nil {
+		return nil, err
+	}
+	return noopRelease, nil
+}
+
+// TryAcquire 有令牌时取走一个
+func (b *TokenBucket) TryAcquire() (func(), bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ok, _ := b.take(b.now())
+	if !ok {
+		return nil, false
+	}
+	return noopRelease, true
+}
+
+// Stats 返回当前的令牌数和等待数量
+func (b *TokenBucket) Stats() LimiterStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(b.now())
+	return LimiterStats{Available: b.tokens, Waiting: b.waiting}
+}
+
+func (b *TokenBucket) idle() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(b.now())
+	return b.waiting == 0 && b.tokens >= float64(b.burst)
+}
+
+func (b *TokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(float64(b.burst), b.tokens+elapsed.Seconds()*b.rate)
+	}
+	b.last = now
+}
+
+func (b *TokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if b.rate <= 0 {
+		return false, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// SlidingWindow 滑动窗口，任意 window 时间内最多 limit 个请求，适合上游按窗口计数限流的场景
+type SlidingWindow struct {
+	limiterState
+	limit  int
+	window time.Duration
+	times  []time.Time // 窗口内请求的时间，按时间排序
+}
+
+// NewSlidingWindow 创建滑动窗口限流器
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{limiterState: newLimiterState(), limit: max(limit, 0), window: window}
+}
+
+// SetLimit 调整窗口内最多的请求数，0表示暂停
+func (w *SlidingWindow) SetLimit(limit int) *SlidingWindow {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.limit = max(limit, 0)
+	w.broadcast()
+	return w
+}
+
+// SetWindow 调整窗口大小
+func (w *SlidingWindow) SetWindow(window time.Duration) *SlidingWindow {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.window = window
+	w.broadcast()
+	return w
+}
+
+// Acquire 等待窗口内有空余
+func (w *SlidingWindow) Acquire(ctx context.Context) (func(), error) {
+	if err := w.acquire(ctx, w.take); err != nil {
+		return nil, err
+	}
+	return noopRelease, nil
+}
+
+// TryAcquire 窗口内有空余时记录一次请求
+func (w *SlidingWindow) TryAcquire() (func(), bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ok, _ := w.take(w.now())
+	if !ok {
+		return nil, false
+	}
+	return noopRelease, true
+}
+
+// Stats 返回窗口内剩余的请求数和等待数量
+func (w *SlidingWindow) Stats() LimiterStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire(w.now())
+	return LimiterStats{Available: float64(max(w.limit-len(w.times), 0)), Waiting: w.waiting}
+}
+
+func (w *SlidingWindow) idle() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire(w.now())
+	return w.waiting == 0 && len(w.times) == 0
+}
+
+func (w *SlidingWindow) expire(now time.Time) {
+	i := 0
+	for i < len(w.times) && !w.times[i].After(now.Add(-w.window)) {
+		i++
+	}
+	w.times = w.times[i:]
+}
+
+func (w *SlidingWindow) take(now time.Time) (bool, time.Duration) {
+	w.expire(now)
+	if len(w.times) < w.limit {
+		w.times = append(w.times, now)
+		return true, 0
+	}
+	if w.limit == 0 {
+		return false, 0
+	}
+	// 调小 limit 后窗口内的请求可能超过 limit，需要等到多出来的请求都过期
+	return false, w.times[len(w.times)-w.limit].Add(w.window).Sub(now)
+}
+
+// ConcurrencyLimiter 限制同时进行的请求数量，请求在响应体关闭后才算结束
+type ConcurrencyLimiter struct {
+	limiterState
+	max      int
+	inFlight int
+}
+
+// NewConcurrencyLimiter 创建并发限制，最多同时进行n个请求
+func NewConcurrencyLimiter(n int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{limiterState: newLimiterState(), max: n}
+}
+
+// SetMax 调整最多同时进行的请求数量，调小时不影响正在进行的请求
+func (c *ConcurrencyLimiter) SetMax(n int) *ConcurrencyLimiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.max = n
+	c.broadcast()
+	return c
+}
+
+// Acquire 等待空闲的并发数
+func (c *ConcurrencyLimiter) Acquire(ctx context.Context) (func(), error) {
+	if err := c.acquire(ctx, c.take); err != nil {
+		return nil, err
+	}
+	return c.releaseFunc(), nil
+}
+
+// TryAcquire 有空闲的并发数时占用一个
+func (c *ConcurrencyLimiter) TryAcquire() (func(), bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ok, _ := c.take(c.now()); !ok {
+		return nil, false
+	}
+	return c.releaseFunc(), true
+}
+
+// Stats 返回空闲的并发数、正在进行和等待的请求数量
+func (c *ConcurrencyLimiter) Stats() LimiterStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return LimiterStats{Available: float64(max(c.max-c.inFlight, 0)), Waiting: c.waiting, InFlight: c.inFlight}
+}
+
+func (c *ConcurrencyLimiter) idle() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.waiting == 0 && c.inFlight == 0
+}
+
+func (c *ConcurrencyLimiter) take(time.Time) (bool, time.Duration) {
+	if c.inFlight < c.max {
+		c.inFlight++
+		return true, 0
+	}
+	return false, 0
+}
+
+// releaseFunc 返回只生效一次的释放函数
+func (c *ConcurrencyLimiter) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.inFlight--
+			c.broadcast()
+		})
+	}
+}
+
+// KeyedLimiter 按key分别限流，例如每个host、每个租户使用独立的限流器
+// 限流器在第一次使用某个key时由 factory 创建，设置了 SetIdleTTL 时空闲的限流器会被删除，否则一直保留
+type KeyedLimiter struct {
+	factory   func(key string) Limiter
+	keyFunc   func(req *http.Request) string
+	mu        sync.Mutex
+	limiters  map[string]*keyedEntry
+	idleTTL   time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type keyedEntry struct {
+	limiter  Limiter
+	lastUsed time.Time
+	fixed    bool // 通过 Set 指定的限流器，不会被删除
+}
+
+// idleLimiter 内置的限流器实现，许可全部恢复并且没有等待的调用方时返回true
+type idleLimiter interface {
+	idle() bool
+}
+
+// NewKeyedLimiter 创建按key限流的限流器，默认按请求的host分组
+func NewKeyedLimiter(factory func(key string) Limiter) *KeyedLimiter {
+	return &KeyedLimiter{
+		factory:  factory,
+		keyFunc:  func(req *http.Request) string { return req.URL.Host },
+		limiters: make(map[string]*keyedEntry),
+		now:      time.Now,
+	}
+}
+
+// SetKeyFunc 设置作为中间件使用时如何从请求得到key，例如按请求头中的租户ID
+func (k *KeyedLimiter) SetKeyFunc(fn func(req *http.Request) string) *KeyedLimiter {
+	k.keyFunc = fn
+	return k
+}
+
+// SetIdleTTL 设置超过ttl没有使用的限流器在许可全部恢复、没有等待和进行中的请求时删除，下次使用时重新创建
+// key来自请求(例如租户ID、用户输入的host)时应该设置，避免限流器越来越多，0表示不删除
+func (k *KeyedLimiter) SetIdleTTL(ttl time.Duration) *KeyedLimiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.idleTTL = ttl
+	return k
+}
+
+// Get 返回key对应的限流器，不存在时创建，可以用于请求之外的场景或调整某个key的限制
+func (k *KeyedLimiter) Get(key string) Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := k.now()
+	k.sweep(now)
+	entry, ok := k.limiters[key]
+	if !ok {
+		entry = &keyedEntry{limiter: k.factory(key)}
+		k.limiters[key] = entry
+	}
+	entry.lastUsed = now
+	return entry.limiter
+}
+
+// Set 为某个key指定限流器，替换已有的限流器
+func (k *KeyedLimiter) Set(key string, limiter Limiter) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.limiters[key] = &keyedEntry{limiter: limiter, lastUsed: k.now(), fixed: true}
+}
+
+// Len 返回当前保留的限流器数量
+func (k *KeyedLimiter) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.limiters)
+}
+
+// Stats 返回每个key的状态
+func (k *KeyedLimiter) Stats() map[string]LimiterStats {
+	k.mu.Lock()
+	limiters := make(map[string]Limiter, len(k.limiters))
+	for key, entry := range k.limiters {
+		limiters[key] = entry.limiter
+	}
+	k.mu.Unlock()
+
+	stats := make(map[string]LimiterStats, len(limiters))
+	for key, limiter := range limiters {
+		stats[key] = limiter.Stats()
+	}
+	return stats
+}
+
+// sweep 删除空闲的限流器，每个ttl最多检查一次，需要持有锁
+func (k *KeyedLimiter) sweep(now time.Time) {
+	if k.idleTTL <= 0 || now.Sub(k.lastSweep) < k.idleTTL {
+		return
+	}
+	k.lastSweep = now
+	for key, entry := range k.limiters {
+		if entry.fixed || now.Sub(entry.lastUsed) < k.idleTTL {
+			continue
+		}
+		if isIdle(entry.limiter) {
+			delete(k.limiters, key)
+		}
+	}
+}
+
+// isIdle 判断限流器是否可以删除，自定义的限流器只检查没有等待和进行中的请求
+func isIdle(limiter Limiter) bool {
+	if l, ok := limiter.(idleLimiter); ok {
+		return l.idle()
+	}
+	stats := limiter.Stats()
+	return stats.Waiting == 0 && stats.InFlight == 0
+}
+
+// RateLimit 使用限流器限制请求，每次尝试(包括重试)都需要获得许可
+// 并发限制在响应体关闭或读完后释放
+func RateLimit(limiter Limiter, mode LimitMode) Middleware {
+	return limitMiddleware(func(*http.Request) Limiter { return limiter }, mode)
+}
+
+// KeyedRateLimit 按key分别限流，默认每个host独立限流
+func KeyedRateLimit(limiters *KeyedLimiter, mode LimitMode) Middleware {
+	return limitMiddleware(func(req *http.Request) Limiter { return limiters.Get(limiters.keyFunc(req)) }, mode)
+}
+
+func limitMiddleware(get func(req *http.Request) Limiter, mode LimitMode) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			limiter := get(req)
+			var release func()
+			if mode == LimitFailFast {
+				var ok bool
+				if release, ok = limiter.TryAcquire(); !ok {
+					return nil, fmt.Errorf("%w: %s", ErrRateLimited, req.URL.Host)
+				}
+			} else {
+				var err error
+				if release, err = limiter.Acquire(req.Context()); err != nil {
+					return nil, err
+				}
+			}
+
+			resp, err := next(req)
+			if err != nil || resp.Body == nil {
+				release()
+				return resp, err
+			}
+			resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+			return resp, nil
+		}
+	}
+}
+
+// releaseBody 响应体读完或关闭时释放许可
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
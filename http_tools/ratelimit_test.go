@@ -0,0 +1,254 @@
+package http_tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试令牌桶的突发、生成速率和调整速率
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	bucket := NewTokenBucket(2, 3)
+	bucket.now = func() time.Time { return now }
+	bucket.last = now
+
+	for i := 0; i < 3; i++ {
+		if _, ok := bucket.TryAcquire(); !ok {
+			t.Fatalf("Expected burst request %d to pass", i)
+		}
+	}
+	if _, ok := bucket.TryAcquire(); ok {
+		t.Fatal("Expected bucket to be empty")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if _, ok := bucket.TryAcquire(); !ok {
+		t.Error("Expected one token after 500ms at 2/s")
+	}
+	now = now.Add(time.Hour)
+	if stats := bucket.Stats(); stats.Available != 3 {
+		t.Errorf("Expected tokens to be capped at burst, got %v", stats.Available)
+	}
+
+	bucket.SetBurst(1)
+	if stats := bucket.Stats(); stats.Available != 1 {
+		t.Errorf("Expected tokens to shrink with burst, got %v", stats.Available)
+	}
+}
+
+// 测试等待令牌，调整速率后唤醒等待的调用方
+func TestTokenBucketWait(t *testing.T) {
+	bucket := NewTokenBucket(0, 1)
+	if _, err := bucket.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := bucket.Acquire(context.Background())
+		done <- err
+	}()
+	waitFor(t, func() bool { return bucket.Stats().Waiting == 1 })
+	bucket.SetRate(1000)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// 等待时间超过截止时间时直接返回
+	bucket.SetRate(0.1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := bucket.Acquire(ctx); !errors.Is(err, ErrRateLimited) || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("Expected early ErrRateLimited, got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	bucket.SetRate(0)
+	go func() {
+		waitFor(t, func() bool { return bucket.Stats().Waiting == 1 })
+		cancel()
+	}()
+	if _, err := bucket.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if n := bucket.Stats().Waiting; n != 0 {
+		t.Errorf("Expected no waiting callers, got %d", n)
+	}
+}
+
+// 测试滑动窗口
+func TestSlidingWindow(t *testing.T) {
+	now := time.Unix(0, 0)
+	window := NewSlidingWindow(2, time.Second)
+	window.now = func() time.Time { return now }
+
+	window.TryAcquire()
+	now = now.Add(600 * time.Millisecond)
+	window.TryAcquire()
+	if _, ok := window.TryAcquire(); ok {
+		t.Fatal("Expected window to be full")
+	}
+	if ok, wait := window.take(now); ok || wait != 400*time.Millisecond {
+		t.Errorf("Expected to wait 400ms for the oldest request, got %v", wait)
+	}
+	now = now.Add(400 * time.Millisecond)
+	if _, ok := window.TryAcquire(); !ok {
+		t.Error("Expected the oldest request to expire")
+	}
+
+	window.SetLimit(1)
+	if ok, wait := window.take(now); ok || wait != time.Second {
+		t.Errorf("Expected to wait until extra requests expire, got %v", wait)
+	}
+	if stats := window.Stats(); stats.Available != 0 {
+		t.Errorf("Expected no available requests, got %v", stats.Available)
+	}
+}
+
+// 测试并发限制在响应体关闭后释放
+func TestConcurrencyLimit(t *testing.T) {
+	var current, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	limiter := NewConcurrencyLimiter(2)
+	client, _ := NewClientBuilder().Use(RateLimit(limiter, LimitWait)).Build()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := client.NewRequest("GET", server.URL)
+			if body, _, err := Do[string](req); err != nil || body != "ok" {
+				t.Errorf("Unexpected response %q %v", body, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", peak.Load())
+	}
+	if stats := limiter.Stats(); stats.InFlight != 0 || stats.Available != 2 {
+		t.Errorf("Expected all permits to be released, got %+v", stats)
+	}
+
+	// 不等待模式
+	release, _ := limiter.TryAcquire()
+	limiter.SetMax(1)
+	req, _ := NewReqClient("GET", server.URL)
+	req.Use(RateLimit(limiter, LimitFailFast))
+	req.SetRetryPolicy(NewRetryPolicy(3))
+	if err := req.Send(); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+	release()
+	release()
+	if n := limiter.Stats().InFlight; n != 0 {
+		t.Errorf("Expected release to be idempotent, got %d in flight", n)
+	}
+}
+
+// 测试按host分别限流
+func TestKeyedRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	other := "http://localhost:" + u.Port()
+
+	limiters := NewKeyedLimiter(func(key string) Limiter { return NewTokenBucket(0, 1) })
+	client, _ := NewClientBuilder().Use(KeyedRateLimit(limiters, LimitFailFast)).Build()
+	send := func(target string) error {
+		req, _ := client.NewRequest("GET", target)
+		err := req.Send()
+		req.Close()
+		return err
+	}
+
+	if err := send(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(server.URL); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected second request to the same host to be limited, got %v", err)
+	}
+	if err := send(other); err != nil {
+		t.Errorf("Expected other host to have its own limit, got %v", err)
+	}
+
+	limiters.Get(u.Host).(*TokenBucket).SetRate(1000)
+	time.Sleep(5 * time.Millisecond)
+	if err := send(server.URL); err != nil {
+		t.Errorf("Expected adjusted limit to allow the request, got %v", err)
+	}
+	if stats := limiters.Stats(); len(stats) != 2 {
+		t.Errorf("Expected stats for 2 hosts, got %v", stats)
+	}
+}
+
+// 测试空闲的限流器超过ttl后删除，正在使用或许可没有恢复的限流器保留
+func TestKeyedLimiterIdleTTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+	limiters := NewKeyedLimiter(func(key string) Limiter {
+		if key == "slow" {
+			bucket := NewTokenBucket(0.001, 1)
+			bucket.now, bucket.last = clock, now
+			return bucket
+		}
+		return NewConcurrencyLimiter(1)
+	}).SetIdleTTL(time.Minute)
+	limiters.now = clock
+
+	for i := 0; i < 100; i++ {
+		limiters.Get(fmt.Sprintf("tenant%d", i))
+	}
+	release, _ := limiters.Get("busy").TryAcquire()
+	limiters.Get("slow").TryAcquire()
+	limiters.Set("fixed", NewConcurrencyLimiter(1))
+	if n := limiters.Len(); n != 103 {
+		t.Fatalf("Expected 103 limiters, got %d", n)
+	}
+
+	now = now.Add(2 * time.Minute)
+	limiters.Get("active")
+	// busy 还有进行中的请求，slow 的令牌还没有恢复，fixed 是手动指定的
+	if n := limiters.Len(); n != 4 {
+		t.Errorf("Expected idle limiters removed, got %v", limiters.Stats())
+	}
+
+	release()
+	now = now.Add(2 * time.Minute)
+	limiters.Get("active")
+	if n := limiters.Len(); n != 3 {
+		t.Errorf("Expected released limiter removed, got %v", limiters.Stats())
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
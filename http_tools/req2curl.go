@@ -1,8 +1,17 @@
 package http_tools
 
 import (
-	"github.com/moul/http2curl"
+	"fmt"
+	"maps"
 	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/moul/http2curl"
 )
 
 // ConvertToCurlString 将请求转换为 cURL 命令
@@ -15,3 +24,181 @@ func ConvertToCurlString(r http.Request) (string, error) {
 	}
 	return curlCmd.String(), nil
 }
+
+// buildCurlCommand 将请求转换为curl命令，表单和文件使用-F，文件使用@filepath格式
+func buildCurlCommand(req *http.Request, formFields map[string]string, files []fileField) (string, error) {
+	var parts []string
+
+	// 基础curl命令
+	parts = append(parts, "curl")
+
+	// 请求方法
+	if req.Method != "GET" {
+		parts = append(parts, "-X", req.Method)
+	}
+
+	// URL
+	parts = append(parts, shellQuote(req.URL.String()))
+
+	// 请求头
+	multipart := len(formFields) > 0 || len(files) > 0
+	for _, key := range slices.Sorted(maps.Keys(req.Header)) {
+		// 表单的Content-Type由curl根据-F设置
+		if multipart && key == "Content-Type" {
+			continue
+		}
+		for _, value := range req.Header[key] {
+			parts = append(parts, "-H", shellQuote(key+": "+value))
+		}
+	}
+
+	// 处理表单数据和文件
+	if multipart {
+		// 添加表单字段
+		for _, key := range slices.Sorted(maps.Keys(formFields)) {
+			parts = append(parts, "-F", shellQuote(key+"="+formFields[key]))
+		}
+
+		// 添加文件（使用@filepath格式），io.Reader 上传的文件使用文件名代替路径
+		for _, file := range files {
+			value := "@" + file.filePath
+			if file.filePath == "" {
+				value = "@" + file.fileName
+			} else if file.fileName != filepath.Base(file.filePath) {
+				value += ";filename=" + file.fileName
+			}
+			if file.contentType != "" {
+				value += ";type=" + file.contentType
+			}
+			parts = append(parts, "-F", shellQuote(file.fieldName+"="+value))
+		}
+	} else {
+		// 如果有其他类型的请求体，使用--data-raw
+		bodyBytes, err := peekBody(req)
+		if err != nil {
+			return "", err
+		}
+		if len(bodyBytes) > 0 {
+			parts = append(parts, "--data-raw", shellQuote(string(bodyBytes)))
+		}
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+// buildHTTPieCommand 将请求转换为 HTTPie 命令，请求体使用 --raw，表单和文件使用 --multipart
+func buildHTTPieCommand(req *http.Request, formFields map[string]string, files []fileField) (string, error) {
+	multipart := len(formFields) > 0 || len(files) > 0
+	parts := []string{"http"}
+	if multipart {
+		parts = append(parts, "--multipart")
+	}
+	var body []byte
+	if !multipart {
+		var err error
+		if body, err = peekBody(req); err != nil {
+			return "", err
+		}
+		if len(body) > 0 {
+			parts = append(parts, "--raw", shellQuote(string(body)))
+		}
+	}
+	parts = append(parts, req.Method, shellQuote(req.URL.String()))
+
+	for _, key := range slices.Sorted(maps.Keys(req.Header)) {
+		if multipart && key == "Content-Type" {
+			continue
+		}
+		for _, value := range req.Header[key] {
+			parts = append(parts, shellQuote(key+":"+value))
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(formFields)) {
+		parts = append(parts, shellQuote(key+"="+formFields[key]))
+	}
+	for _, file := range files {
+		value := file.fieldName + "@" + file.filePath
+		if file.filePath == "" {
+			value = file.fieldName + "@" + file.fileName
+		}
+		if file.contentType != "" {
+			value += ";type=" + file.contentType
+		}
+		parts = append(parts, shellQuote(value))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// buildGoCode 将请求转换为使用 http_tools 发送请求的Go代码
+func buildGoCode(req *http.Request, timeout time.Duration, formFields map[string]string, files []fileField) (string, error) {
+	multipart := len(formFields) > 0 || len(files) > 0
+	var b strings.Builder
+	fmt.Fprintf(&b, "cli, err := http_tools.NewReqClient(%s, %s)\n", strconv.Quote(req.Method), strconv.Quote(req.URL.String()))
+	b.WriteString("if err != nil {\n\treturn err\n}\n")
+
+	var keys []string
+	for _, key := range slices.Sorted(maps.Keys(req.Header)) {
+		if !(multipart && key == "Content-Type") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 {
+		b.WriteString("cli.SetHeaders(map[string]string{\n")
+		for _, key := range keys {
+			fmt.Fprintf(&b, "\t%s: %s,\n", strconv.Quote(key), strconv.Quote(strings.Join(req.Header[key], ", ")))
+		}
+		b.WriteString("})\n")
+	}
+	if timeout > 0 {
+		fmt.Fprintf(&b, "cli.SetTimeout(%d * time.Millisecond)\n", timeout.Milliseconds())
+	}
+
+	if multipart {
+		if len(formFields) > 0 {
+			b.WriteString("cli.SetForm(map[string]string{\n")
+			for _, key := range slices.Sorted(maps.Keys(formFields)) {
+				fmt.Fprintf(&b, "\t%s: %s,\n", strconv.Quote(key), goString(formFields[key]))
+			}
+			b.WriteString("})\n")
+		}
+		for _, file := range files {
+			path := file.filePath
+			if path == "" {
+				path = file.fileName
+			}
+			args := []string{strconv.Quote(file.fieldName), strconv.Quote(path)}
+			if file.fileName != filepath.Base(path) {
+				args = append(args, fmt.Sprintf("http_tools.WithFileName(%s)", strconv.Quote(file.fileName)))
+			}
+			if file.contentType != "" {
+				args = append(args, fmt.Sprintf("http_tools.WithContentType(%s)", strconv.Quote(file.contentType)))
+			}
+			fmt.Fprintf(&b, "if err := cli.SetFile(%s); err != nil {\n\treturn err\n}\n", strings.Join(args, ", "))
+		}
+	} else {
+		body, err := peekBody(req)
+		if err != nil {
+			return "", err
+		}
+		if len(body) > 0 {
+			fmt.Fprintf(&b, "cli.SetBody([]byte(%s))\n", goString(string(body)))
+		}
+	}
+
+	b.WriteString("if err := cli.Send(); err != nil {\n\treturn err\n}\n")
+	b.WriteString("defer cli.Close()\n")
+	return b.String(), nil
+}
+
+// shellQuote 使用单引号转义shell参数
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// goString 返回Go字符串字面量，多行文本使用反引号
+func goString(s string) string {
+	if strings.Contains(s, "\n") && !strings.ContainsAny(s, "`\r") && utf8.ValidString(s) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
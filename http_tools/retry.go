@@ -0,0 +1,242 @@
+package http_tools
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy 重试策略，可以在多个请求之间共享
+// 只有请求体可以重放时才会重试，SetJson、SetForm、SetFile 设置的请求体都可以重放
+type RetryPolicy struct {
+	maxAttempts       int
+	initialBackoff    time.Duration
+	maxBackoff        time.Duration
+	multiplier        float64
+	jitter            float64
+	statusCodes       []int
+	retryNetworkError bool
+	respectRetryAfter bool
+	maxRetryAfter     time.Duration
+	methods           []string
+	retryIf           func(resp *http.Response, err error) bool
+}
+
+// HeaderIdempotencyKey 带有该请求头的请求可以安全地重试，不受请求方法的限制
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// NewRetryPolicy 创建重试策略
+// maxAttempts: 最多尝试次数，包括第一次请求
+// 默认在网络错误和 429、502、503、504 时重试，退避时间从100ms开始每次翻倍，最长10s，带20%的随机抖动
+// 默认只重试幂等的 GET、HEAD、OPTIONS、PUT、DELETE 请求和带有 Idempotency-Key 请求头的请求，
+// POST、PATCH 可能已经被服务端处理，重试会重复创建数据，需要通过 SetRetryMethods 或 SetRetryIf 开启
+func NewRetryPolicy(maxAttempts int) *RetryPolicy {
+	return &RetryPolicy{
+		maxAttempts:       maxAttempts,
+		initialBackoff:    100 * time.Millisecond,
+		maxBackoff:        10 * time.Second,
+		multiplier:        2,
+		jitter:            0.2,
+		statusCodes:       []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		retryNetworkError: true,
+		respectRetryAfter: true,
+		maxRetryAfter:     time.Minute,
+		methods:           []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete},
+	}
+}
+
+// SetBackoff 设置第一次重试前的等待时间和最长等待时间
+func (p *RetryPolicy) SetBackoff(initial, max time.Duration) *RetryPolicy {
+	p.initialBackoff = initial
+	p.maxBackoff = max
+	return p
+}
+
+// SetMultiplier 设置每次重试等待时间的增长倍数
+func (p *RetryPolicy) SetMultiplier(multiplier float64) *RetryPolicy {
+	p.multiplier = multiplier
+	return p
+}
+
+// SetJitter 设置随机抖动比例，取值0-1，0.2表示等待时间在±20%之间随机
+func (p *RetryPolicy) SetJitter(jitter float64) *RetryPolicy {
+	p.jitter = min(max(jitter, 0), 1)
+	return p
+}
+
+// SetRetryStatusCodes 设置需要重试的状态码，会覆盖默认值
+func (p *RetryPolicy) SetRetryStatusCodes(codes ...int) *RetryPolicy {
+	p.statusCodes = codes
+	return p
+}
+
+// SetRetryOnNetworkError 设置是否在网络错误时重试
+func (p *RetryPolicy) SetRetryOnNetworkError(retry bool) *RetryPolicy {
+	p.retryNetworkError = retry
+	return p
+}
+
+// SetRespectRetryAfter 设置是否按响应头 Retry-After 等待
+// Retry-After 超过 maxWait 时不再重试，直接返回响应
+func (p *RetryPolicy) SetRespectRetryAfter(respect bool, maxWait time.Duration) *RetryPolicy {
+	p.respectRetryAfter = respect
+	p.maxRetryAfter = maxWait
+	return p
+}
+
+// SetRetryMethods 设置可以重试的请求方法，会覆盖默认值，带有 Idempotency-Key 请求头的请求总是可以重试
+// 例如: SetRetryMethods(http.MethodGet, http.MethodPost) 服务端能够去重时允许重试 POST
+func (p *RetryPolicy) SetRetryMethods(methods ...string) *RetryPolicy {
+	p.methods = methods
+	return p
+}
+
+// SetRetryIf 设置自定义的重试判断，设置后忽略状态码、网络错误和请求方法的配置
+func (p *RetryPolicy) SetRetryIf(retryIf func(resp *http.Response, err error) bool) *RetryPolicy {
+	p.retryIf = retryIf
+	return p
+}
+
+// shouldRetry 判断本次请求结果是否需要重试
+func (p *RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrRateLimited) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if p.retryIf != nil {
+		return p.retryIf(resp, err)
+	}
+	if !slices.Contains(p.methods, req.Method) && req.Header.Get(HeaderIdempotencyKey) == "" {
+		return false
+	}
+	if err != nil {
+		return p.retryNetworkError
+	}
+	return slices.Contains(p.statusCodes, resp.StatusCode)
+}
+
+// backoff 计算第attempt次请求失败后的等待时间，返回false表示不再重试
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) (time.Duration, bool) {
+	if p.respectRetryAfter && resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if p.maxRetryAfter > 0 && wait > p.maxRetryAfter {
+				return 0, false
+			}
+			return wait, true
+		}
+	}
+
+	wait := float64(p.initialBackoff) * math.Pow(p.multiplier, float64(attempt-1))
+	if p.maxBackoff > 0 && wait > float64(p.maxBackoff) {
+		wait = float64(p.maxBackoff)
+	}
+	if p.jitter > 0 {
+		wait *= 1 - p.jitter + 2*p.jitter*rand.Float64()
+	}
+	return time.Duration(wait), true
+}
+
+// parseRetryAfter 解析 Retry-After，支持秒数和HTTP日期两种格式
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// doWithRetry 按重试策略发送请求，每次重试前重新生成请求体
+func (r *ReqClient) doWithRetry() (*http.Response, error) {
+	attempts := 1
+	if r.retry != nil && r.retry.maxAttempts > 1 {
+		attempts = r.retry.maxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			if err := r.rewindBody(); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := r.doOnce()
+		// context已经取消或超时时不再重试
+		if attempt >= attempts || r.req.Context().Err() != nil || !r.retry.shouldRetry(r.req, resp, err) {
+			return resp, err
+		}
+		wait, ok := r.retry.backoff(attempt, resp)
+		if !ok {
+			return resp, err
+		}
+		if resp != nil {
+			// 读完响应体才能复用连接
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			_ = resp.Body.Close()
+		}
+		if err := sleepContext(r.req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// doOnce 发送一次请求，配置了熔断器时先检查熔断状态
+func (r *ReqClient) doOnce() (*http.Response, error) {
+	if r.breaker == nil {
+		return r.roundTrip()
+	}
+	done, err := r.breaker.allow(r.req.Context(), r.req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.roundTrip()
+	done(resp, err)
+	return resp, err
+}
+
+// roundTrip 经过中间件发送请求，开启打印curl命令时 CurlLogger 在最内层，打印的是中间件处理后的请求
+func (r *ReqClient) roundTrip() (*http.Response, error) {
+	middlewares := r.middlewares
+	if r.isPrintCurl {
+		middlewares = append(slices.Clip(middlewares), CurlLogger(nil))
+	}
+	req := r.req.WithContext(withCurlSource(r.req.Context(), r))
+	return chain(r.client.Do, middlewares)(req)
+}
+
+// rewindBody 重新生成请求体，请求体无法重放时返回错误
+func (r *ReqClient) rewindBody() error {
+	if r.req.Body == nil || r.req.Body == http.NoBody {
+		return nil
+	}
+	if r.req.GetBody == nil {
+		return errors.New("http_tools: request body cannot be replayed for retry")
+	}
+	body, err := r.req.GetBody()
+	if err != nil {
+		return err
+	}
+	r.req.Body = body
+	return nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
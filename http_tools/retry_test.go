@@ -0,0 +1,341 @@
+package http_tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试失败后重试并重放请求体
+func TestRetryReplaysBody(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		n := len(bodies)
+		mu.Unlock()
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client, err := NewClientBuilder().SetRetryPolicy(NewRetryPolicy(3).SetBackoff(time.Millisecond, 10*time.Millisecond)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := client.NewRequest("POST", server.URL)
+	req.SetHeaders(map[string]string{HeaderIdempotencyKey: "order-1"})
+	if err := req.SetJson(map[string]string{"name": "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	defer req.Close()
+	if req.GetHttpCode() != 200 || req.GetBodyString() != "ok" {
+		t.Errorf("Expected success after retries, got %d", req.GetHttpCode())
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(bodies))
+	}
+	for _, body := range bodies {
+		if body != `{"name":"test"}` {
+			t.Errorf("Expected body to be replayed, got %q", body)
+		}
+	}
+}
+
+// 测试默认不重试 POST、PATCH，设置 SetRetryMethods 后重试
+func TestRetryMethods(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	send := func(method string, policy *RetryPolicy) int32 {
+		calls.Store(0)
+		req, _ := NewReqClient(method, server.URL)
+		defer req.Close()
+		req.SetRetryPolicy(policy)
+		if err := req.Send(); err != nil {
+			t.Fatal(err)
+		}
+		return calls.Load()
+	}
+	policy := NewRetryPolicy(3).SetBackoff(time.Millisecond, time.Millisecond)
+	for method, want := range map[string]int32{"POST": 1, "PATCH": 1, "GET": 3, "PUT": 3, "DELETE": 3} {
+		if n := send(method, policy); n != want {
+			t.Errorf("%s: expected %d attempts, got %d", method, want, n)
+		}
+	}
+	if n := send("POST", NewRetryPolicy(3).SetBackoff(time.Millisecond, time.Millisecond).SetRetryMethods(http.MethodPost)); n != 3 {
+		t.Errorf("Expected POST retried after SetRetryMethods, got %d attempts", n)
+	}
+}
+
+// 测试重试次数用完后返回最后一次的响应，以及不在重试范围内的状态码
+func TestRetryGivesUp(t *testing.T) {
+	var calls atomic.Int32
+	status := atomic.Int32{}
+	status.Store(http.StatusBadGateway)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	policy := NewRetryPolicy(2).SetBackoff(time.Millisecond, time.Millisecond)
+	req, _ := NewReqClient("GET", server.URL)
+	req.SetRetryPolicy(policy)
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	req.Close()
+	if req.GetHttpCode() != http.StatusBadGateway || calls.Load() != 2 {
+		t.Errorf("Expected 2 attempts ending with 502, got %d attempts and %d", calls.Load(), req.GetHttpCode())
+	}
+
+	calls.Store(0)
+	status.Store(http.StatusBadRequest)
+	req, _ = NewReqClient("GET", server.URL)
+	req.SetRetryPolicy(policy)
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	req.Close()
+	if calls.Load() != 1 {
+		t.Errorf("Expected 400 not to be retried, got %d attempts", calls.Load())
+	}
+}
+
+// 测试 Retry-After
+func TestRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", r.URL.Query().Get("after"))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Retry-After 为0时立即重试，不使用退避时间
+	policy := NewRetryPolicy(3).SetBackoff(time.Hour, time.Hour)
+	req, _ := NewReqClient("GET", server.URL+"?after=0")
+	req.SetRetryPolicy(policy)
+	start := time.Now()
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	req.Close()
+	if req.GetHttpCode() != http.StatusOK || time.Since(start) > time.Second {
+		t.Errorf("Expected immediate retry, got %d after %v", req.GetHttpCode(), time.Since(start))
+	}
+
+	// Retry-After 超过上限时不重试
+	calls.Store(0)
+	req, _ = NewReqClient("GET", server.URL+"?after=3600")
+	req.SetRetryPolicy(NewRetryPolicy(3).SetRespectRetryAfter(true, time.Minute))
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	req.Close()
+	if req.GetHttpCode() != http.StatusTooManyRequests || calls.Load() != 1 {
+		t.Errorf("Expected no retry for long Retry-After, got %d", req.GetHttpCode())
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if d, ok := parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now); !ok || d != 90*time.Second {
+		t.Errorf("Expected 90s from http date, got %v %v", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Error("Expected invalid Retry-After to be ignored")
+	}
+}
+
+// 测试网络错误重试
+func TestRetryNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	var attempts atomic.Int32
+	policy := NewRetryPolicy(3).SetBackoff(time.Millisecond, time.Millisecond).SetRetryIf(func(resp *http.Response, err error) bool {
+		attempts.Add(1)
+		return err != nil
+	})
+	req, _ := NewReqClient("GET", url)
+	req.SetRetryPolicy(policy)
+	if err := req.Send(); err == nil {
+		t.Fatal("Expected connection error")
+	}
+	if attempts.Load() != 2 {
+		t.Errorf("Expected retry check after first 2 attempts, got %d", attempts.Load())
+	}
+}
+
+// 测试退避时间
+func TestRetryBackoff(t *testing.T) {
+	policy := NewRetryPolicy(5).SetBackoff(100*time.Millisecond, 300*time.Millisecond).SetJitter(0)
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, w := range want {
+		if got, _ := policy.backoff(i+1, nil); got != w {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, w, got)
+		}
+	}
+	policy.SetJitter(0.5)
+	for i := 0; i < 100; i++ {
+		if got, _ := policy.backoff(1, nil); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("Expected jittered backoff within ±50%%, got %v", got)
+		}
+	}
+}
+
+// 测试熔断器的打开、半开和关闭
+func TestCircuitBreaker(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	var nowMu sync.Mutex
+	breaker := NewCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time {
+		nowMu.Lock()
+		defer nowMu.Unlock()
+		return now
+	}
+	client, err := NewClientBuilder().SetCircuitBreaker(breaker).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := func() error {
+		req, _ := client.NewRequest("GET", server.URL)
+		err := req.Send()
+		req.Close()
+		return err
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	_ = send()
+	_ = send()
+	if state := breaker.State(host); state != CircuitOpen {
+		t.Fatalf("Expected circuit to open after 2 failures, got %s", state)
+	}
+	if err := send(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected open circuit to skip the request, got %d calls", calls.Load())
+	}
+
+	// 探测失败重新打开
+	nowMu.Lock()
+	now = now.Add(time.Minute)
+	nowMu.Unlock()
+	if state := breaker.State(host); state != CircuitHalfOpen {
+		t.Fatalf("Expected half-open after timeout, got %s", state)
+	}
+	_ = send()
+	if state := breaker.State(host); state != CircuitOpen {
+		t.Fatalf("Expected failed probe to reopen, got %s", state)
+	}
+
+	// 探测成功关闭
+	nowMu.Lock()
+	now = now.Add(time.Minute)
+	nowMu.Unlock()
+	failing.Store(false)
+	if err := send(); err != nil {
+		t.Fatal(err)
+	}
+	if state := breaker.State(host); state != CircuitClosed {
+		t.Errorf("Expected successful probe to close, got %s", state)
+	}
+	if state := breaker.State("other.example.com"); state != CircuitClosed {
+		t.Errorf("Expected other hosts to be unaffected, got %s", state)
+	}
+}
+
+// 测试半开状态只放行有限的探测请求
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(1, time.Second).SetHalfOpen(1, 2)
+	breaker.now = func() time.Time { return now }
+
+	done, _ := breaker.allow(context.Background(), "h")
+	done(nil, errors.New("boom"))
+	now = now.Add(time.Second)
+
+	probe, err := breaker.allow(context.Background(), "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := breaker.allow(context.Background(), "h"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected second concurrent probe to be rejected, got %v", err)
+	}
+	probe(&http.Response{StatusCode: 200}, nil)
+	if breaker.State("h") != CircuitHalfOpen {
+		t.Error("Expected circuit to stay half-open until 2 successful probes")
+	}
+	probe, _ = breaker.allow(context.Background(), "h")
+	probe(&http.Response{StatusCode: 200}, nil)
+	if breaker.State("h") != CircuitClosed {
+		t.Error("Expected circuit to close after 2 successful probes")
+	}
+}
+
+// 测试调用方取消请求不计入失败，半开状态下归还探测名额
+func TestCircuitBreakerCallerCanceled(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(1, time.Second)
+	breaker.now = func() time.Time { return now }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range []error{context.Canceled, fmt.Errorf("read body: %w", context.DeadlineExceeded)} {
+		done, _ := breaker.allow(ctx, "h")
+		done(nil, err)
+	}
+	if breaker.State("h") != CircuitClosed {
+		t.Fatal("Expected caller cancellation not counted as failure")
+	}
+
+	// 上游超时，请求自身的context没有结束，算作失败
+	done, _ := breaker.allow(context.Background(), "h")
+	done(nil, context.DeadlineExceeded)
+	if breaker.State("h") != CircuitOpen {
+		t.Fatal("Expected upstream timeout counted as failure")
+	}
+
+	now = now.Add(time.Second)
+	probe, _ := breaker.allow(ctx, "h")
+	probe(nil, context.Canceled)
+	if _, err := breaker.allow(context.Background(), "h"); err != nil {
+		t.Errorf("Expected canceled probe to be released, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package http_tools
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/url"
@@ -54,18 +55,32 @@ func (r *ReqClient) SetFormWithMapAny(form map[string]any) {
 	}
 }
 
-// SetFile 上传文件
+// SetFile 上传文件，文件内容在发送时流式读取，不会全部读入内存
 // fieldName: 字段名
 // filePath: 文件路径
+// opts: 可选配置，如 WithFileName、WithContentType
 // return: 错误
-func (r *ReqClient) SetFile(fieldName, filePath string) error {
-	r.files = append(r.files, fileField{
-		fieldName: fieldName,
-		filePath:  filePath,
-	})
+func (r *ReqClient) SetFile(fieldName, filePath string, opts ...FileOption) error {
+	r.files = append(r.files, newFileField(fieldName, filePath, nil, opts))
 	return nil
 }
 
+// SetFileReader 从 io.Reader 上传文件
+// reader 实现了 io.Seeker 时请求体可以在重试时重放，大小可以推断时请求会带上 Content-Length
+// fieldName: 字段名
+// fileName: 上传时使用的文件名
+// reader: 文件内容
+// opts: 可选配置，如 WithContentType、WithFileSize
+func (r *ReqClient) SetFileReader(fieldName, fileName string, reader io.Reader, opts ...FileOption) {
+	opts = append([]FileOption{WithFileName(fileName)}, opts...)
+	r.files = append(r.files, newFileField(fieldName, "", reader, opts))
+}
+
+// SetUploadProgress 设置上传表单和文件时的进度回调
+func (r *ReqClient) SetUploadProgress(progress UploadProgress) {
+	r.uploadProgress = progress
+}
+
 // SetJson 设置 JSON 请求体
 // jsonObject: JSON 对象
 func (r *ReqClient) SetJson(jsonObject any) error {
@@ -73,17 +88,58 @@ func (r *ReqClient) SetJson(jsonObject any) error {
 	if err != nil {
 		return err
 	}
-	r.req.Body = io.NopCloser(bytes.NewReader(jsonValue))
+	r.setBodyBytes(jsonValue)
 	r.req.Header.Set("Content-Type", "application/json")
 	return nil
 }
 
+// SetBody 设置原始请求体，Content-Type 需要通过 SetHeaders 设置
+// body: 请求体
+func (r *ReqClient) SetBody(body []byte) {
+	r.setBodyBytes(body)
+}
+
+// setBodyBytes 设置请求体，同时设置 GetBody 使请求体可以在重试和重定向时重放
+func (r *ReqClient) setBodyBytes(body []byte) {
+	r.req.ContentLength = int64(len(body))
+	r.req.Body = io.NopCloser(bytes.NewReader(body))
+	r.req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+}
+
 // SetTimeout 设置超时时间
 // timeout: 超时时间
 func (r *ReqClient) SetTimeout(timeout time.Duration) {
 	r.client.Timeout = timeout
 }
 
+// SetRetryPolicy 设置重试策略，覆盖客户端的默认策略，传入nil表示不重试
+func (r *ReqClient) SetRetryPolicy(policy *RetryPolicy) {
+	r.retry = policy
+}
+
+// SetCircuitBreaker 设置熔断器，覆盖客户端的默认熔断器，传入nil表示不熔断
+func (r *ReqClient) SetCircuitBreaker(breaker *CircuitBreaker) {
+	r.breaker = breaker
+}
+
+// Use 添加中间件，在客户端的中间件之后执行
+func (r *ReqClient) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// SetContext 设置请求的context，需要在 Send 之前调用
+func (r *ReqClient) SetContext(ctx context.Context) {
+	r.req = r.req.WithContext(ctx)
+}
+
+// SetDeadline 设置请求的截止时间，包括重试和读取响应体，在 Close 之后释放
+// 与 SetTimeout 不同，截止时间不会在每次重试时重新计算
+func (r *ReqClient) SetDeadline(deadline time.Time) {
+	r.deadline = deadline
+}
+
 // SetIsPrintCurl 设置是否打印 curl 命令
 // isPrintCurl: 是否打印 curl 命令
 func (r *ReqClient) SetIsPrintCurl(isPrintCurl bool) {
@@ -0,0 +1,327 @@
+package http_tools
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// Client 可复用的HTTP客户端，所有由它创建的 ReqClient 共享同一个连接池，并发安全
+// 应该在程序中长期持有并复用，而不是每次请求都创建
+type Client struct {
+	transport   http.RoundTripper
+	timeout     time.Duration
+	retry       *RetryPolicy
+	breaker     *CircuitBreaker
+	middlewares []Middleware
+}
+
+// NewRequest 创建使用该客户端连接池的请求
+func (c *Client) NewRequest(method, url string) (*ReqClient, error) {
+	return c.NewRequestWithContext(context.Background(), method, url)
+}
+
+// NewRequestWithContext 创建带context的请求，context取消后请求、重试等待和文件上传都会中止
+func (c *Client) NewRequestWithContext(ctx context.Context, method, url string) (*ReqClient, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ReqClient{
+		client:      c.httpClient(),
+		req:         req,
+		formFields:  make(map[string]string),
+		files:       make([]fileField, 0),
+		retry:       c.retry,
+		breaker:     c.breaker,
+		middlewares: slices.Clone(c.middlewares),
+	}, nil
+}
+
+// HTTPClient 返回共享连接池的 http.Client，用于需要直接使用标准库的场景
+func (c *Client) HTTPClient() *http.Client {
+	return c.httpClient()
+}
+
+// Transport 返回底层的 RoundTripper
+func (c *Client) Transport() http.RoundTripper {
+	return c.transport
+}
+
+// CloseIdleConnections 关闭连接池中的空闲连接
+func (c *Client) CloseIdleConnections() {
+	if t, ok := c.transport.(interface{ CloseIdleConnections() }); ok {
+		t.CloseIdleConnections()
+	}
+}
+
+// httpClient 每个请求使用独立的 http.Client，SetTimeout 只影响当前请求，连接池由 transport 共享
+func (c *Client) httpClient() *http.Client {
+	return &http.Client{
+		Transport: c.transport,
+		Timeout:   c.timeout,
+	}
+}
+
+var defaultClient atomic.Pointer[Client]
+
+func init() {
+	client, _ := NewClientBuilder().Build()
+	defaultClient.Store(client)
+}
+
+// DefaultClient 返回 NewReqClient 使用的默认客户端
+func DefaultClient() *Client {
+	return defaultClient.Load()
+}
+
+// SetDefaultClient 替换 NewReqClient 使用的默认客户端，传入nil时不做任何事
+func SetDefaultClient(client *Client) {
+	if client != nil {
+		defaultClient.Store(client)
+	}
+}
+
+// ClientBuilder 客户端构建器
+type ClientBuilder struct {
+	transport    *http.Transport
+	roundTripper http.RoundTripper
+	dialer       *net.Dialer
+	timeout      time.Duration
+	retry        *RetryPolicy
+	breaker      *CircuitBreaker
+	middlewares  []Middleware
+	err          error
+}
+
+// NewClientBuilder 创建客户端构建器，默认配置与 http.DefaultTransport 相同，每个host最多保留32个空闲连接
+func NewClientBuilder() *ClientBuilder {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 32
+	return &ClientBuilder{
+		transport: transport,
+		dialer:    dialer,
+	}
+}
+
+// SetTimeout 设置请求的默认超时时间，包括连接、发送和读取响应体，0表示不超时
+func (b *ClientBuilder) SetTimeout(timeout time.Duration) *ClientBuilder {
+	b.timeout = timeout
+	return b
+}
+
+// SetRetryPolicy 设置请求的默认重试策略
+func (b *ClientBuilder) SetRetryPolicy(policy *RetryPolicy) *ClientBuilder {
+	b.retry = policy
+	return b
+}
+
+// SetCircuitBreaker 设置请求的默认熔断器，熔断状态按host区分
+func (b *ClientBuilder) SetCircuitBreaker(breaker *CircuitBreaker) *ClientBuilder {
+	b.breaker = breaker
+	return b
+}
+
+// Use 添加所有请求都会经过的中间件，第一个添加的中间件在最外层
+func (b *ClientBuilder) Use(middlewares ...Middleware) *ClientBuilder {
+	b.middlewares = append(b.middlewares, middlewares...)
+	return b
+}
+
+// SetProxy 设置代理地址，支持 http、https、socks5，传入空字符串表示不使用代理
+func (b *ClientBuilder) SetProxy(proxyURL string) *ClientBuilder {
+	if proxyURL == "" {
+		b.transport.Proxy = nil
+		return b
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		b.setErr(fmt.Errorf("invalid proxy url: %w", err))
+		return b
+	}
+	b.transport.Proxy = http.ProxyURL(u)
+	return b
+}
+
+// SetProxyFunc 设置代理选择函数，例如 http.ProxyFromEnvironment
+func (b *ClientBuilder) SetProxyFunc(proxy func(*http.Request) (*url.URL, error)) *ClientBuilder {
+	b.transport.Proxy = proxy
+	return b
+}
+
+// SetTLSConfig 设置TLS配置，会覆盖之前设置的证书
+func (b *ClientBuilder) SetTLSConfig(config *tls.Config) *ClientBuilder {
+	b.transport.TLSClientConfig = config
+	return b
+}
+
+// SetInsecureSkipVerify 设置是否跳过服务端证书校验，只应在测试环境使用
+func (b *ClientBuilder) SetInsecureSkipVerify(skip bool) *ClientBuilder {
+	b.tlsConfig().InsecureSkipVerify = skip
+	return b
+}
+
+// SetRootCAs 设置校验服务端证书的根证书
+func (b *ClientBuilder) SetRootCAs(pool *x509.CertPool) *ClientBuilder {
+	b.tlsConfig().RootCAs = pool
+	return b
+}
+
+// SetRootCAFile 从PEM文件加载校验服务端证书的根证书
+func (b *ClientBuilder) SetRootCAFile(caFile string) *ClientBuilder {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		b.setErr(fmt.Errorf("failed to read ca file: %w", err))
+		return b
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		b.setErr(fmt.Errorf("no certificate found in %s", caFile))
+		return b
+	}
+	return b.SetRootCAs(pool)
+}
+
+// SetClientCert 设置双向TLS认证使用的客户端证书
+func (b *ClientBuilder) SetClientCert(cert tls.Certificate) *ClientBuilder {
+	config := b.tlsConfig()
+	config.Certificates = append(config.Certificates, cert)
+	return b
+}
+
+// SetClientCertFile 从PEM文件加载双向TLS认证使用的客户端证书和私钥
+func (b *ClientBuilder) SetClientCertFile(certFile, keyFile string) *ClientBuilder {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		b.setErr(fmt.Errorf("failed to load client certificate: %w", err))
+		return b
+	}
+	return b.SetClientCert(cert)
+}
+
+// SetDialTimeout 设置建立TCP连接的超时时间
+func (b *ClientBuilder) SetDialTimeout(timeout time.Duration) *ClientBuilder {
+	b.dialer.Timeout = timeout
+	return b
+}
+
+// SetKeepAlive 设置TCP keep-alive探测间隔，负数表示关闭TCP keep-alive
+func (b *ClientBuilder) SetKeepAlive(interval time.Duration) *ClientBuilder {
+	b.dialer.KeepAlive = interval
+	return b
+}
+
+// SetDisableKeepAlives 设置是否关闭HTTP连接复用，关闭后每个请求都会建立新连接
+func (b *ClientBuilder) SetDisableKeepAlives(disable bool) *ClientBuilder {
+	b.transport.DisableKeepAlives = disable
+	return b
+}
+
+// SetMaxIdleConns 设置所有host最多保留的空闲连接数，0表示不限制
+func (b *ClientBuilder) SetMaxIdleConns(n int) *ClientBuilder {
+	b.transport.MaxIdleConns = n
+	return b
+}
+
+// SetMaxIdleConnsPerHost 设置每个host最多保留的空闲连接数
+func (b *ClientBuilder) SetMaxIdleConnsPerHost(n int) *ClientBuilder {
+	b.transport.MaxIdleConnsPerHost = n
+	return b
+}
+
+// SetMaxConnsPerHost 设置每个host最多同时建立的连接数，超过时请求会等待，0表示不限制
+func (b *ClientBuilder) SetMaxConnsPerHost(n int) *ClientBuilder {
+	b.transport.MaxConnsPerHost = n
+	return b
+}
+
+// SetIdleConnTimeout 设置空闲连接的保留时间
+func (b *ClientBuilder) SetIdleConnTimeout(timeout time.Duration) *ClientBuilder {
+	b.transport.IdleConnTimeout = timeout
+	return b
+}
+
+// SetTLSHandshakeTimeout 设置TLS握手的超时时间
+func (b *ClientBuilder) SetTLSHandshakeTimeout(timeout time.Duration) *ClientBuilder {
+	b.transport.TLSHandshakeTimeout = timeout
+	return b
+}
+
+// SetResponseHeaderTimeout 设置发送请求后等待响应头的超时时间
+func (b *ClientBuilder) SetResponseHeaderTimeout(timeout time.Duration) *ClientBuilder {
+	b.transport.ResponseHeaderTimeout = timeout
+	return b
+}
+
+// SetHTTP2 设置是否尝试使用HTTP/2，默认开启
+func (b *ClientBuilder) SetHTTP2(enabled bool) *ClientBuilder {
+	b.transport.ForceAttemptHTTP2 = enabled
+	if !enabled {
+		// 非空的TLSNextProto会禁用标准库自动启用的HTTP/2
+		b.transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	} else {
+		b.transport.TLSNextProto = nil
+	}
+	return b
+}
+
+// SetHTTP2Config 设置HTTP/2的参数，例如最大并发流数量和健康检查的ping间隔
+func (b *ClientBuilder) SetHTTP2Config(config *http.HTTP2Config) *ClientBuilder {
+	b.transport.HTTP2 = config
+	return b
+}
+
+// SetTransport 使用自定义的 RoundTripper，例如测试时使用 Recorder
+// 设置后连接池、代理、TLS等配置都不再生效，由 RoundTripper 自己处理
+func (b *ClientBuilder) SetTransport(transport http.RoundTripper) *ClientBuilder {
+	b.roundTripper = transport
+	return b
+}
+
+// Build 创建客户端，设置过程中的错误在这里返回
+func (b *ClientBuilder) Build() (*Client, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	// 复制一份配置，之后继续修改构建器不会影响已经创建的客户端
+	var transport http.RoundTripper = b.roundTripper
+	if transport == nil {
+		t := b.transport.Clone()
+		dialer := *b.dialer
+		t.DialContext = dialer.DialContext
+		transport = t
+	}
+	return &Client{
+		transport:   transport,
+		timeout:     b.timeout,
+		retry:       b.retry,
+		breaker:     b.breaker,
+		middlewares: slices.Clone(b.middlewares),
+	}, nil
+}
+
+func (b *ClientBuilder) tlsConfig() *tls.Config {
+	if b.transport.TLSClientConfig == nil {
+		b.transport.TLSClientConfig = &tls.Config{}
+	}
+	return b.transport.TLSClientConfig
+}
+
+// setErr 只保留第一个错误
+func (b *ClientBuilder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
@@ -0,0 +1,211 @@
+package http_tools
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试同一个客户端创建的请求复用连接
+func TestClientReusesConnections(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client, err := NewClientBuilder().SetTimeout(5 * time.Second).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		req, err := client.NewRequest("GET", server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := req.Send(); err != nil {
+			t.Fatal(err)
+		}
+		if body := req.GetBodyString(); body != "ok" {
+			t.Errorf("Expected ok, got %q", body)
+		}
+		req.Close()
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("Expected 1 connection, got %d", n)
+	}
+}
+
+// 测试请求通过代理发送
+func TestClientProxy(t *testing.T) {
+	var proxied atomic.Value
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Store(r.URL.String())
+		_, _ = w.Write([]byte("from proxy"))
+	}))
+	defer proxy.Close()
+
+	client, err := NewClientBuilder().SetProxy(proxy.URL).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := client.NewRequest("GET", "http://upstream.invalid/path?q=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer req.Close()
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if body := req.GetBodyString(); body != "from proxy" {
+		t.Errorf("Expected response from proxy, got %q", body)
+	}
+	if got, _ := proxied.Load().(string); got != "http://upstream.invalid/path?q=1" {
+		t.Errorf("Expected absolute url at proxy, got %q", got)
+	}
+
+	if _, err := NewClientBuilder().SetProxy("://bad").Build(); err == nil {
+		t.Error("Expected error for invalid proxy url")
+	}
+}
+
+// 测试双向TLS认证
+func TestClientMutualTLS(t *testing.T) {
+	clientCert, clientPool := newTestCertificate(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientPool,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	serverPool := x509.NewCertPool()
+	serverPool.AddCert(server.Certificate())
+
+	// 写入文件后通过文件加载
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	writePEM(t, certFile, "CERTIFICATE", clientCert.Certificate[0])
+	keyDER, err := x509.MarshalECPrivateKey(clientCert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", server.Certificate().Raw)
+
+	client, err := NewClientBuilder().SetRootCAFile(caFile).SetClientCertFile(certFile, keyFile).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := client.NewRequest("GET", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer req.Close()
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if body := req.GetBodyString(); body != "http_tools test client" {
+		t.Errorf("Expected client certificate name, got %q", body)
+	}
+
+	// 没有客户端证书时握手失败
+	noCert, err := NewClientBuilder().SetRootCAs(serverPool).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req2, _ := noCert.NewRequest("GET", server.URL)
+	if err := req2.Send(); err == nil {
+		req2.Close()
+		t.Error("Expected handshake to fail without client certificate")
+	}
+
+	if _, err := NewClientBuilder().SetClientCertFile(filepath.Join(dir, "missing.pem"), keyFile).Build(); err == nil {
+		t.Error("Expected error for missing certificate file")
+	}
+}
+
+// 测试请求的超时设置互不影响
+func TestClientTimeoutIsPerRequest(t *testing.T) {
+	client, err := NewClientBuilder().SetTimeout(time.Minute).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := client.NewRequest("GET", "http://example.com")
+	b, _ := client.NewRequest("GET", "http://example.com")
+	a.SetTimeout(time.Second)
+	if b.client.Timeout != time.Minute {
+		t.Errorf("Expected default timeout to be kept, got %v", b.client.Timeout)
+	}
+	if a.client.Transport != b.client.Transport {
+		t.Error("Expected requests to share the transport")
+	}
+
+	// 默认客户端同样共享连接池
+	c, _ := NewReqClient("GET", "http://example.com")
+	if c.client.Transport != DefaultClient().Transport() {
+		t.Error("Expected NewReqClient to use the default client")
+	}
+}
+
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "http_tools test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,154 @@
+package jwt_tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// RegisteredClaims JWT 标准声明，零值的字段不会写入令牌
+// 生成令牌时 exp 按 ExpireTime 计算，iat 为当前时间，验证后 ExpiresAt 和 IssuedAt 为令牌中的值
+// 嵌入到 GenerateTyped 使用的结构体中时不参与 JSON 序列化，由包内单独处理
+type RegisteredClaims struct {
+	Issuer    string    `json:"-"` // iss 签发方
+	Subject   string    `json:"-"` // sub 主题，通常为用户ID
+	Audience  []string  `json:"-"` // aud 接收方，只有一个时写入为字符串
+	ID        string    `json:"-"` // jti 令牌ID
+	NotBefore time.Time `json:"-"` // nbf 生效时间
+	ExpiresAt time.Time `json:"-"` // exp 过期时间
+	IssuedAt  time.Time `json:"-"` // iat 签发时间
+}
+
+// VerifyOptions 验证令牌的选项，零值只检查签名和过期时间
+type VerifyOptions struct {
+	Issuer         string        // 签发方必须等于 Issuer，为空时不检查
+	Audience       []string      // 令牌的 aud 至少包含其中一个，为空时不检查
+	Leeway         time.Duration // 检查 exp、nbf、iat 时允许的时钟偏差
+	MaxAge         time.Duration // 签发时间距今的最长时间，为0时不检查，设置后令牌必须带有 iat
+	RequiredClaims []string      // 必须存在的自定义声明
+}
+
+// setClaims 将标准声明写入 claims
+func (c RegisteredClaims) setClaims(claims jwt.MapClaims) {
+	setString := func(name, value string) {
+		if value != "" {
+			claims[name] = value
+		}
+	}
+	setString("iss", c.Issuer)
+	setString("sub", c.Subject)
+	setString("jti", c.ID)
+	switch len(c.Audience) {
+	case 0:
+	case 1:
+		claims["aud"] = c.Audience[0]
+	default:
+		claims["aud"] = c.Audience
+	}
+	for name, value := range map[string]time.Time{"nbf": c.NotBefore, "exp": c.ExpiresAt, "iat": c.IssuedAt} {
+		if !value.IsZero() {
+			claims[name] = value.Unix()
+		}
+	}
+}
+
+// parseRegisteredClaims 从令牌的声明中读取标准声明，类型不正确时返回 ErrInvalid
+func parseRegisteredClaims(claims jwt.MapClaims) (RegisteredClaims, error) {
+	var c RegisteredClaims
+	var err error
+	for name, target := range map[string]*string{"iss": &c.Issuer, "sub": &c.Subject, "jti": &c.ID} {
+		if value, ok := claims[name]; ok {
+			if *target, ok = value.(string); !ok {
+				return c, fmt.Errorf("%w: %s must be a string", ErrInvalid, name)
+			}
+		}
+	}
+	for name, target := range map[string]*time.Time{"nbf": &c.NotBefore, "exp": &c.ExpiresAt, "iat": &c.IssuedAt} {
+		if *target, err = numericDate(claims, name); err != nil {
+			return c, err
+		}
+	}
+	switch aud := claims["aud"].(type) {
+	case nil:
+	case string:
+		c.Audience = []string{aud}
+	case []string:
+		c.Audience = aud
+	case []any:
+		for _, v := range aud {
+			s, ok := v.(string)
+			if !ok {
+				return c, fmt.Errorf("%w: aud must be a string or an array of strings", ErrInvalid)
+			}
+			c.Audience = append(c.Audience, s)
+		}
+	default:
+		return c, fmt.Errorf("%w: aud must be a string or an array of strings", ErrInvalid)
+	}
+	return c, nil
+}
+
+// numericDate 读取时间声明，不存在时返回零值
+func numericDate(claims jwt.MapClaims, name string) (time.Time, error) {
+	var seconds float64
+	switch v := claims[name].(type) {
+	case nil:
+		return time.Time{}, nil
+	case float64:
+		seconds = v
+	case int64:
+		seconds = float64(v)
+	case int:
+		seconds = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %s must be a number", ErrInvalid, name)
+		}
+		seconds = f
+	default:
+		return time.Time{}, fmt.Errorf("%w: %s must be a number", ErrInvalid, name)
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9)), nil
+}
+
+// verify 按选项检查标准声明和必需的声明
+func (o VerifyOptions) verify(c RegisteredClaims, claims jwt.MapClaims, now time.Time) error {
+	if c.ExpiresAt.IsZero() {
+		return fmt.Errorf("%w: exp", ErrMissingClaim)
+	}
+	if !now.Before(c.ExpiresAt.Add(o.Leeway)) {
+		return fmt.Errorf("%w: expired at %s", ErrExpired, c.ExpiresAt.Format(time.RFC3339))
+	}
+	if !c.NotBefore.IsZero() && now.Add(o.Leeway).Before(c.NotBefore) {
+		return fmt.Errorf("%w: valid from %s", ErrNotYetValid, c.NotBefore.Format(time.RFC3339))
+	}
+	if !c.IssuedAt.IsZero() && now.Add(o.Leeway).Before(c.IssuedAt) {
+		return fmt.Errorf("%w: issued at %s", ErrIssuedAt, c.IssuedAt.Format(time.RFC3339))
+	}
+	if o.MaxAge > 0 {
+		if c.IssuedAt.IsZero() {
+			return fmt.Errorf("%w: iat", ErrMissingClaim)
+		}
+		if now.Sub(c.IssuedAt) > o.MaxAge+o.Leeway {
+			return fmt.Errorf("%w: issued at %s", ErrTokenTooOld, c.IssuedAt.Format(time.RFC3339))
+		}
+	}
+	if o.Issuer != "" && c.Issuer != o.Issuer {
+		return fmt.Errorf("%w: expected %q, got %q", ErrIssuer, o.Issuer, c.Issuer)
+	}
+	if len(o.Audience) > 0 && !slices.ContainsFunc(c.Audience, func(aud string) bool { return slices.Contains(o.Audience, aud) }) {
+		return fmt.Errorf("%w: expected one of %q, got %q", ErrAudience, o.Audience, c.Audience)
+	}
+	for _, name := range o.RequiredClaims {
+		if _, ok := claims[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrMissingClaim, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package jwt_tools
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 测试生成的令牌带有标准声明，验证后可以读取
+func TestRegisteredClaims(t *testing.T) {
+	config := JwtConfig{SecretKey: "secret", ExpireTime: time.Hour}
+	token, err := NewTokenBuilder(config).SetClaims(RegisteredClaims{
+		Issuer:    "auth",
+		Subject:   "user-1",
+		Audience:  []string{"api"},
+		ID:        "token-1",
+		NotBefore: time.Now().Add(-time.Minute),
+	}).GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, _, _ := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if aud := parsed.Claims.(jwt.MapClaims)["aud"]; aud != "api" {
+		t.Errorf("Expected single audience as string, got %v", aud)
+	}
+
+	tb := NewTokenBuilder(config).SetToken(token)
+	if err := tb.VerifyToken(); err != nil {
+		t.Fatal(err)
+	}
+	claims := tb.GetClaims()
+	if claims.Issuer != "auth" || claims.Subject != "user-1" || claims.ID != "token-1" || len(claims.Audience) != 1 {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+	if time.Until(claims.ExpiresAt) <= 59*time.Minute || time.Since(claims.IssuedAt) > time.Minute {
+		t.Errorf("Unexpected times %v %v", claims.ExpiresAt, claims.IssuedAt)
+	}
+
+	if _, err := NewTokenBuilder(config).GenerateToken(); !errors.Is(err, ErrMetaEmpty) {
+		t.Errorf("Expected ErrMetaEmpty, got %v", err)
+	}
+}
+
+// 测试验证选项返回对应的错误
+func TestVerifyOptions(t *testing.T) {
+	now := time.Now()
+	sign := func(claims jwt.MapClaims) string {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+		return token
+	}
+	valid := func(extra jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{"iss": "auth", "aud": []string{"web", "api"}, "role": "admin",
+			"iat": now.Unix(), "exp": now.Add(time.Hour).Unix()}
+		// 值为nil的声明表示删除
+		for k, v := range extra {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+		return claims
+	}
+	options := VerifyOptions{Issuer: "auth", Audience: []string{"api"}, MaxAge: 2 * time.Hour, RequiredClaims: []string{"role"}}
+
+	tests := []struct {
+		name    string
+		token   string
+		options VerifyOptions
+		want    error
+	}{
+		{"valid", sign(valid(nil)), options, nil},
+		{"expired", sign(valid(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()})), options, ErrExpired},
+		{"expired within leeway", sign(valid(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()})), VerifyOptions{Leeway: 2 * time.Minute}, nil},
+		{"missing exp", sign(jwt.MapClaims{"role": "admin"}), VerifyOptions{}, ErrMissingClaim},
+		{"not before", sign(valid(jwt.MapClaims{"nbf": now.Add(time.Hour).Unix()})), options, ErrNotYetValid},
+		{"issued in future", sign(valid(jwt.MapClaims{"iat": now.Add(time.Hour).Unix()})), options, ErrIssuedAt},
+		{"too old", sign(valid(jwt.MapClaims{"iat": now.Add(-3 * time.Hour).Unix()})), options, ErrTokenTooOld},
+		{"missing iat", sign(valid(jwt.MapClaims{"iat": nil})), options, ErrMissingClaim},
+		{"issuer", sign(valid(jwt.MapClaims{"iss": "other"})), options, ErrIssuer},
+		{"audience", sign(valid(jwt.MapClaims{"aud": "web"})), options, ErrAudience},
+		{"required claim", sign(valid(jwt.MapClaims{"role": nil})), options, ErrMissingClaim},
+		{"invalid exp type", sign(valid(jwt.MapClaims{"exp": "tomorrow"})), options, ErrInvalid},
+		{"malformed", "not.a.token", options, ErrMalformed},
+		{"signature", sign(valid(nil))[:len(sign(valid(nil)))-2] + "xx", options, ErrSignature},
+	}
+	for _, tt := range tests {
+		tb := NewTokenBuilder(JwtConfig{SecretKey: "secret", VerifyOptions: tt.options})
+		err := tb.SetToken(tt.token).VerifyToken()
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	tb := NewTokenBuilder(JwtConfig{SecretKey: "secret"}).RegisterValidateFunc(func(meta map[string]any) error {
+		return errors.New("role is not allowed")
+	})
+	if err := tb.SetToken(sign(valid(nil))).VerifyToken(); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+	if err := tb.SetToken("").VerifyToken(); !errors.Is(err, ErrTokenEmpty) {
+		t.Errorf("Expected ErrTokenEmpty, got %v", err)
+	}
+	hs384, _ := jwt.NewWithClaims(jwt.SigningMethodHS384, valid(nil)).SignedString([]byte("secret"))
+	if err := tb.SetToken(hs384).VerifyToken(); !errors.Is(err, ErrAlgorithm) {
+		t.Errorf("Expected ErrAlgorithm, got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package jwt_tools
+
+import "errors"
+
+// 验证令牌返回的错误，可以用 errors.Is 判断
+var (
+	ErrTokenEmpty   = errors.New("token is empty")
+	ErrMetaEmpty    = errors.New("meta is empty")
+	ErrMalformed    = errors.New("token is malformed")
+	ErrAlgorithm    = errors.New("token signing method is not allowed")
+	ErrSignature    = errors.New("token signature is invalid")
+	ErrExpired      = errors.New("token is expired")
+	ErrNotYetValid  = errors.New("token is not valid yet")
+	ErrIssuedAt     = errors.New("token used before issued")
+	ErrTokenTooOld  = errors.New("token is too old")
+	ErrIssuer       = errors.New("token issuer is invalid")
+	ErrAudience     = errors.New("token audience is invalid")
+	ErrMissingClaim = errors.New("token is missing required claim")
+	ErrTokenType    = errors.New("token type is invalid") // 刷新令牌和访问令牌混用
+	ErrRevoked      = errors.New("token is revoked")
+	ErrTokenReused  = errors.New("refresh token is reused") // 已经使用过的刷新令牌再次使用，整个令牌族被撤销
+	ErrInvalid      = errors.New("token is invalid")        // ValidMethod 返回错误或声明的类型不正确
+
+	// ErrNoRevocationStore 刷新和撤销令牌需要设置 JwtConfig.Revocations
+	ErrNoRevocationStore = errors.New("revocation store is not configured")
+)
@@ -0,0 +1,194 @@
+package jwt_tools
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// JWK JSON Web Key，只包含公钥
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+	Crv string `json:"crv,omitempty"` // EC 和 OKP 的曲线
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK 返回密钥的公钥部分，HMAC密钥不能公开，返回错误
+func (k *Key) JWK() (JWK, error) {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+	switch key := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeSegment(key.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = key.Curve.Params().Name
+		jwk.X = encodeSegment(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeSegment(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeSegment(key)
+	default:
+		return JWK{}, fmt.Errorf("jwt_tools: key %q of type %T cannot be published", k.ID, k.PublicKey)
+	}
+	return jwk, nil
+}
+
+// Key 将JWK转换为只能验证的密钥，没有 alg 时按密钥类型推断
+func (j JWK) Key() (*Key, error) {
+	method := jwt.GetSigningMethod(j.Alg)
+	if j.Alg != "" && method == nil {
+		return nil, fmt.Errorf("jwt_tools: key %q uses unsupported algorithm %s", j.Kid, j.Alg)
+	}
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeSegment(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeSegment(j.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("jwt_tools: key %q has invalid exponent", j.Kid)
+		}
+		if method == nil {
+			method = jwt.SigningMethodRS256
+		}
+		return NewKey(j.Kid, method, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())})
+	case "EC":
+		curve, defaultMethod := jwkCurve(j.Crv)
+		if curve == nil {
+			return nil, fmt.Errorf("jwt_tools: key %q uses unsupported curve %s", j.Kid, j.Crv)
+		}
+		x, err := decodeSegment(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeSegment(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("jwt_tools: key %q is not on curve %s", j.Kid, j.Crv)
+		}
+		if method == nil {
+			method = defaultMethod
+		}
+		return NewKey(j.Kid, method, key)
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("jwt_tools: key %q uses unsupported curve %s", j.Kid, j.Crv)
+		}
+		x, err := decodeSegment(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwt_tools: key %q has invalid size", j.Kid)
+		}
+		if method == nil {
+			method = jwt.SigningMethodEdDSA
+		}
+		return NewKey(j.Kid, method, ed25519.PublicKey(x))
+	}
+	return nil, fmt.Errorf("jwt_tools: key %q uses unsupported type %s", j.Kid, j.Kty)
+}
+
+// JWKS 返回密钥集中可以公开的公钥，HMAC密钥会被跳过
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range ks.Keys() {
+		if jwk, err := key.JWK(); err == nil {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
+
+// ParseJWKS 解析JWKS文档，用途不是签名(use 不为 sig)或不支持的密钥会被跳过
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var jwks JWKS
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("jwt_tools: parse jwks: %w", err)
+	}
+	ks := NewKeySet()
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.Key(); err == nil {
+			ks.Add(key)
+		}
+	}
+	return ks, nil
+}
+
+// JWKSHandler 以JWKS格式公开密钥集中的公钥，通常挂载在 /.well-known/jwks.json
+// 每次请求读取当前的密钥，轮换密钥后立即生效
+func JWKSHandler(ks *KeySet) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := json.Marshal(ks.JWKS())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_, _ = w.Write(data)
+	})
+}
+
+// jwkCurve 返回JWK曲线名对应的曲线和默认算法
+func jwkCurve(name string) (elliptic.Curve, jwt.SigningMethod) {
+	switch name {
+	case "P-256":
+		return elliptic.P256(), jwt.SigningMethodES256
+	case "P-384":
+		return elliptic.P384(), jwt.SigningMethodES384
+	case "P-521":
+		return elliptic.P521(), jwt.SigningMethodES512
+	}
+	return nil, nil
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("jwt_tools: decode jwk: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,201 @@
+package jwt_tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// asymmetricMethods 还没有获取到密钥时允许的算法，JWKS中不会公开HMAC密钥
+var asymmetricMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// RemoteKeySet 从JWKS地址获取验证密钥，用于验证其他服务或身份提供方签发的令牌
+// 密钥缓存在内存中，超过刷新间隔后在下次验证时重新获取，也可以调用 Start 在后台定时刷新
+// 遇到未知的 kid 时立即重新获取，两次获取之间至少间隔 refetchInterval，避免伪造的 kid 导致频繁请求
+type RemoteKeySet struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+	refetchInterval time.Duration
+	now             func() time.Time
+
+	mu        sync.RWMutex
+	keys      *KeySet
+	fetchedAt time.Time
+
+	fetchMu   sync.Mutex // 同一时间只有一个获取请求
+	lastFetch time.Time
+
+	stop      chan struct{}
+	done      chan struct{}
+	startOnce sync.Once
+	closeOnce sync.Once
+}
+
+// NewRemoteKeySet 创建远程密钥集，默认每小时刷新，未知 kid 最多每分钟重新获取一次
+func NewRemoteKeySet(url string) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:             url,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		refreshInterval: time.Hour,
+		refetchInterval: time.Minute,
+		now:             time.Now,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// SetHTTPClient 设置获取JWKS使用的客户端
+func (r *RemoteKeySet) SetHTTPClient(client *http.Client) *RemoteKeySet {
+	r.client = client
+	return r
+}
+
+// SetRefreshInterval 设置刷新间隔
+func (r *RemoteKeySet) SetRefreshInterval(d time.Duration) *RemoteKeySet {
+	r.refreshInterval = d
+	return r
+}
+
+// SetRefetchInterval 设置遇到未知 kid 或获取失败后，两次获取之间的最小间隔
+func (r *RemoteKeySet) SetRefetchInterval(d time.Duration) *RemoteKeySet {
+	r.refetchInterval = d
+	return r
+}
+
+// Refresh 立即获取JWKS，失败时保留之前的密钥
+func (r *RemoteKeySet) Refresh(ctx context.Context) error {
+	r.fetchMu.Lock()
+	defer r.fetchMu.Unlock()
+	return r.fetch(ctx)
+}
+
+// Start 获取一次JWKS后在后台定时刷新，直到调用 Close
+// 后台刷新失败时继续使用之前的密钥，等下一次刷新
+func (r *RemoteKeySet) Start() error {
+	err := r.Refresh(context.Background())
+	r.startOnce.Do(func() {
+		go r.refreshLoop()
+	})
+	return err
+}
+
+// Close 停止后台刷新
+func (r *RemoteKeySet) Close() {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
+	started := true
+	r.startOnce.Do(func() { started = false })
+	if started {
+		<-r.done
+	}
+}
+
+// Keys 返回当前缓存的密钥，还没有获取时返回nil
+func (r *RemoteKeySet) Keys() *KeySet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.keys
+}
+
+// ResolveKey 按令牌头部的 kid 选择密钥，缓存过期或找不到 kid 时重新获取
+func (r *RemoteKeySet) ResolveKey(token *jwt.Token) (*Key, error) {
+	keys, fetchedAt := r.current()
+	if keys == nil || r.now().Sub(fetchedAt) >= r.refreshInterval {
+		err := r.refetch()
+		if keys, _ = r.current(); keys == nil {
+			if err == nil {
+				err = fmt.Errorf("jwt_tools: jwks not fetched yet")
+			}
+			return nil, err
+		}
+	}
+
+	key, err := keys.ResolveKey(token)
+	if errors.Is(err, ErrUnknownKey) && r.refetch() == nil {
+		keys, _ = r.current()
+		key, err = keys.ResolveKey(token)
+	}
+	return key, err
+}
+
+// Methods 返回JWKS中使用的签名算法，还没有获取到密钥时返回所有非对称算法
+func (r *RemoteKeySet) Methods() []string {
+	keys, _ := r.current()
+	if keys == nil {
+		return asymmetricMethods
+	}
+	return keys.Methods()
+}
+
+func (r *RemoteKeySet) current() (*KeySet, time.Time) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.keys, r.fetchedAt
+}
+
+// refetch 距离上次获取超过 refetchInterval 时重新获取，并发的调用只会发出一个请求
+func (r *RemoteKeySet) refetch() error {
+	r.fetchMu.Lock()
+	defer r.fetchMu.Unlock()
+	if r.now().Sub(r.lastFetch) < r.refetchInterval {
+		return nil
+	}
+	return r.fetch(context.Background())
+}
+
+// fetch 获取并解析JWKS，需要持有 fetchMu
+func (r *RemoteKeySet) fetch(ctx context.Context) error {
+	r.lastFetch = r.now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return fmt.Errorf("jwt_tools: fetch jwks: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("jwt_tools: fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwt_tools: fetch jwks: unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("jwt_tools: fetch jwks: %w", err)
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.fetchedAt = r.now()
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *RemoteKeySet) refreshLoop() {
+	defer close(r.done)
+	if r.refreshInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			_ = r.Refresh(context.Background())
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package jwt_tools
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// jwksServer 使用 JWKSHandler 公开密钥集，记录请求次数
+func jwksServer(t *testing.T, ks *KeySet) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	handler := JWKSHandler(ks)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func signedToken(t *testing.T, ks *KeySet) string {
+	t.Helper()
+	token, err := NewTokenBuilder(JwtConfig{KeySet: ks, ExpireTime: time.Hour}).
+		SetMeta(map[string]any{"uid": 1}).GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// 测试公钥导出为JWKS后可以解析并验证令牌，HMAC密钥不会公开
+func TestJWKSRoundTrip(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaSigner, _ := NewKey("rsa", jwt.SigningMethodPS256, rsaKey)
+	ecSigner, _ := NewKey("ec", jwt.SigningMethodES384, ecKey)
+	edSigner, _ := NewKey("ed", jwt.SigningMethodEdDSA, edKey)
+	hmacKey, _ := NewHMACKey("hmac", jwt.SigningMethodHS256, []byte("secret"))
+	issuer := NewKeySet(rsaSigner, ecSigner, edSigner, hmacKey)
+
+	server, _ := jwksServer(t, issuer)
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var jwks JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks.Keys) != 3 || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected 3 public keys, got %+v", jwks.Keys)
+	}
+	data, _ := json.Marshal(jwks)
+	verifyKeys, err := ParseJWKS(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kid := range []string{"rsa", "ec", "ed"} {
+		if err := issuer.SetSigningKey(kid); err != nil {
+			t.Fatal(err)
+		}
+		token := signedToken(t, issuer)
+		tb := NewTokenBuilder(JwtConfig{VerifyKeys: verifyKeys})
+		if err := tb.SetToken(token).VerifyToken(); err != nil {
+			t.Errorf("%s: %v", kid, err)
+		}
+	}
+	if _, ok := verifyKeys.Get("hmac"); ok {
+		t.Errorf("Expected HMAC key not published")
+	}
+}
+
+// 测试JWK中没有 alg 时按密钥类型推断，不支持的密钥被跳过
+func TestParseJWKS(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	key, _ := NewKey("ec", jwt.SigningMethodES256, &ecKey.PublicKey)
+	jwk, _ := key.JWK()
+	jwk.Alg = ""
+	enc := jwk
+	enc.Kid, enc.Use = "enc", "enc"
+	data, _ := json.Marshal(JWKS{Keys: []JWK{jwk, enc, {Kty: "oct", Kid: "oct", Alg: "HS256"}, {Kty: "EC", Kid: "bad", Crv: "P-256", X: jwk.Y, Y: jwk.X}}})
+
+	ks, err := ParseJWKS(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := ks.Keys()
+	if len(keys) != 1 || keys[0].ID != "ec" || keys[0].Method != jwt.SigningMethodES256 {
+		t.Errorf("Expected only ec key, got %d keys", len(keys))
+	}
+	if _, err := ParseJWKS([]byte("not json")); err == nil {
+		t.Errorf("Expected error for invalid document")
+	}
+}
+
+// 测试远程密钥集缓存密钥，未知 kid 时重新获取并限制频率
+func TestRemoteKeySet(t *testing.T) {
+	first, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	second, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	firstKey, _ := NewKey("k1", jwt.SigningMethodES256, first)
+	secondKey, _ := NewKey("k2", jwt.SigningMethodES256, second)
+	issuer := NewKeySet(firstKey)
+	server, requests := jwksServer(t, issuer)
+
+	now := time.Now()
+	remote := NewRemoteKeySet(server.URL).SetRefetchInterval(time.Minute)
+	remote.now = func() time.Time { return now }
+	verifier := NewTokenBuilder(JwtConfig{VerifyKeys: remote})
+
+	for i := 0; i < 3; i++ {
+		if err := verifier.SetToken(signedToken(t, issuer)).VerifyToken(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Expected keys cached, got %d requests", requests.Load())
+	}
+
+	// 轮换密钥后第一次遇到新的 kid 时重新获取
+	now = now.Add(2 * time.Minute)
+	issuer.Add(secondKey)
+	issuer.SetSigningKey("k2")
+	if err := verifier.SetToken(signedToken(t, issuer)).VerifyToken(); err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected refetch for new kid, got %d requests", requests.Load())
+	}
+
+	// 伪造的 kid 不会导致频繁请求
+	other, _ := NewKey("unknown", jwt.SigningMethodES256, first)
+	for i := 0; i < 3; i++ {
+		err := verifier.SetToken(signedToken(t, NewKeySet(other))).VerifyToken()
+		if !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("Expected ErrUnknownKey, got %v", err)
+		}
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected refetch throttled, got %d requests", requests.Load())
+	}
+
+	// 超过刷新间隔后重新获取
+	now = now.Add(2 * time.Hour)
+	verifier.SetToken(signedToken(t, issuer)).VerifyToken()
+	if requests.Load() != 3 {
+		t.Errorf("Expected refresh after interval, got %d requests", requests.Load())
+	}
+}
+
+// 测试获取失败时返回错误，后台刷新可以停止
+func TestRemoteKeySetRefresh(t *testing.T) {
+	var fail atomic.Bool
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	signer, _ := NewKey("k1", jwt.SigningMethodES256, key)
+	issuer := NewKeySet(signer)
+	handler := JWKSHandler(issuer)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if fail.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	fail.Store(true)
+	remote := NewRemoteKeySet(server.URL).SetRefreshInterval(10 * time.Millisecond)
+	if err := remote.Start(); err == nil {
+		t.Errorf("Expected error for unavailable server")
+	}
+	if err := NewTokenBuilder(JwtConfig{VerifyKeys: remote}).SetToken(signedToken(t, issuer)).VerifyToken(); err == nil {
+		t.Errorf("Expected error without keys")
+	}
+
+	fail.Store(false)
+	deadline := time.Now().Add(time.Second)
+	for remote.Keys() == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	remote.Close()
+	if remote.Keys() == nil {
+		t.Fatalf("Expected keys fetched by background refresh")
+	}
+	count := requests.Load()
+	time.Sleep(30 * time.Millisecond)
+	if requests.Load() != count {
+		t.Errorf("Expected background refresh stopped")
+	}
+}
@@ -0,0 +1,267 @@
+package jwt_tools
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	// ErrUnknownKey 令牌头部的 kid 在密钥集中不存在
+	ErrUnknownKey = errors.New("jwt_tools: unknown key id")
+	// ErrNoSigningKey 密钥集中没有可以签名的密钥
+	ErrNoSigningKey = errors.New("jwt_tools: no signing key")
+)
+
+// Key 签名和验证使用的密钥
+type Key struct {
+	ID         string            // kid，签名时写入令牌头部
+	Method     jwt.SigningMethod // 签名算法
+	PrivateKey crypto.PrivateKey // 签名使用，HMAC为[]byte，只用于验证的密钥为nil
+	PublicKey  crypto.PublicKey  // 验证使用，HMAC为[]byte
+}
+
+// NewHMACKey 创建HMAC密钥，method 为 HS256、HS384 或 HS512
+func NewHMACKey(kid string, method jwt.SigningMethod, secret []byte) (*Key, error) {
+	return NewKey(kid, method, secret)
+}
+
+// NewKey 使用已经解析的密钥创建 Key
+// key 可以是 []byte(HMAC)、*rsa.PrivateKey、*ecdsa.PrivateKey、ed25519.PrivateKey 或对应的公钥，只有公钥时只能用于验证
+func NewKey(kid string, method jwt.SigningMethod, key any) (*Key, error) {
+	if method == nil {
+		return nil, fmt.Errorf("jwt_tools: signing method is nil")
+	}
+	k := &Key{ID: kid, Method: method}
+	switch v := key.(type) {
+	case []byte:
+		k.PrivateKey, k.PublicKey = v, v
+	case *rsa.PrivateKey:
+		k.PrivateKey, k.PublicKey = v, &v.PublicKey
+	case *ecdsa.PrivateKey:
+		k.PrivateKey, k.PublicKey = v, &v.PublicKey
+	case ed25519.PrivateKey:
+		k.PrivateKey, k.PublicKey = v, v.Public()
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		k.PublicKey = v
+	default:
+		return nil, fmt.Errorf("jwt_tools: unsupported key type %T", key)
+	}
+	if err := checkKeyType(method, k.PublicKey); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// NewKeyFromPEM 从PEM创建密钥，支持 PKCS#1、PKCS#8、SEC 1 私钥，PKIX 公钥和证书，不支持加密的PEM
+func NewKeyFromPEM(kid string, method jwt.SigningMethod, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwt_tools: no PEM data found")
+	}
+	return NewKeyFromDER(kid, method, block.Bytes)
+}
+
+// NewKeyFromDER 从DER创建密钥，支持的格式与 NewKeyFromPEM 相同
+func NewKeyFromDER(kid string, method jwt.SigningMethod, der []byte) (*Key, error) {
+	key, err := parseDER(der)
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(kid, method, key)
+}
+
+// LoadKeyFile 从文件读取密钥，自动识别PEM和DER
+func LoadKeyFile(kid string, method jwt.SigningMethod, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwt_tools: read key file: %w", err)
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		return NewKeyFromPEM(kid, method, data)
+	}
+	return NewKeyFromDER(kid, method, data)
+}
+
+// CanSign 是否可以用于签名
+func (k *Key) CanSign() bool {
+	return k.PrivateKey != nil
+}
+
+func parseDER(der []byte) (any, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return key, nil
+	}
+	if cert, err := x509.ParseCertificate(der); err == nil {
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("jwt_tools: unsupported key format")
+}
+
+// checkKeyType 检查密钥类型是否与签名算法匹配
+func checkKeyType(method jwt.SigningMethod, publicKey crypto.PublicKey) error {
+	ok := false
+	switch m := method.(type) {
+	case *jwt.SigningMethodHMAC:
+		_, ok = publicKey.([]byte)
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok = publicKey.(*rsa.PublicKey)
+	case *jwt.SigningMethodECDSA:
+		var key *ecdsa.PublicKey
+		if key, ok = publicKey.(*ecdsa.PublicKey); ok {
+			ok = key.Curve.Params().BitSize == m.CurveBits
+		}
+	case *jwt.SigningMethodEd25519:
+		_, ok = publicKey.(ed25519.PublicKey)
+	default:
+		return fmt.Errorf("jwt_tools: unsupported signing method %s", method.Alg())
+	}
+	if !ok {
+		return fmt.Errorf("jwt_tools: key type %T does not match signing method %s", publicKey, method.Alg())
+	}
+	return nil
+}
+
+// KeyResolver 验证令牌时按令牌头部选择密钥，KeySet 和 RemoteKeySet 都实现了这个接口
+type KeyResolver interface {
+	// ResolveKey 返回验证令牌使用的密钥，密钥的算法需要与令牌一致
+	ResolveKey(token *jwt.Token) (*Key, error)
+	// Methods 返回验证时允许的签名算法
+	Methods() []string
+}
+
+// KeySet 多个密钥的集合，签名使用当前的签名密钥，验证时按令牌头部的 kid 选择密钥
+// 轮换密钥时添加新密钥并设为签名密钥，旧密钥保留到已签发的令牌过期后再删除
+type KeySet struct {
+	mu      sync.RWMutex
+	keys    map[string]*Key
+	signing string
+}
+
+// NewKeySet 创建密钥集，第一个可以签名的密钥作为签名密钥
+func NewKeySet(keys ...*Key) *KeySet {
+	ks := &KeySet{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		ks.Add(key)
+	}
+	return ks
+}
+
+// Add 添加密钥，kid相同时替换，没有签名密钥时作为签名密钥
+func (ks *KeySet) Add(key *Key) *KeySet {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[key.ID] = key
+	if current, ok := ks.keys[ks.signing]; key.CanSign() && (!ok || !current.CanSign()) {
+		ks.signing = key.ID
+	}
+	return ks
+}
+
+// SetSigningKey 设置签名使用的密钥
+func (ks *KeySet) SetSigningKey(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	key, ok := ks.keys[kid]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if !key.CanSign() {
+		return fmt.Errorf("jwt_tools: key %q has no private key", kid)
+	}
+	ks.signing = kid
+	return nil
+}
+
+// Remove 删除密钥，删除签名密钥后需要重新设置签名密钥
+func (ks *KeySet) Remove(kid string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	delete(ks.keys, kid)
+}
+
+// Get 按 kid 获取密钥
+func (ks *KeySet) Get(kid string) (*Key, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// SigningKey 返回签名使用的密钥
+func (ks *KeySet) SigningKey() (*Key, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[ks.signing]
+	if !ok || !key.CanSign() {
+		return nil, ErrNoSigningKey
+	}
+	return key, nil
+}
+
+// Keys 返回所有密钥，按 kid 排序
+func (ks *KeySet) Keys() []*Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	keys := make([]*Key, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+// Methods 返回密钥集中使用的签名算法
+func (ks *KeySet) Methods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, key := range ks.Keys() {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// ResolveKey 按令牌头部的 kid 选择验证密钥，没有 kid 时只有一个密钥才能使用
+func (ks *KeySet) ResolveKey(token *jwt.Token) (*Key, error) {
+	kid, _ := token.Header["kid"].(string)
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[kid]
+	if !ok && kid == "" && len(ks.keys) == 1 {
+		for _, only := range ks.keys {
+			key, ok = only, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if key.Method.Alg() != token.Method.Alg() {
+		return nil, fmt.Errorf("jwt_tools: key %q does not use algorithm %s", kid, token.Method.Alg())
+	}
+	return key, nil
+}
@@ -0,0 +1,168 @@
+package jwt_tools
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// pemKeys 返回私钥的 PKCS#8 PEM 和公钥的 PKIX PEM
+func pemKeys(t *testing.T, private any, public any) ([]byte, []byte) {
+	t.Helper()
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+}
+
+// 测试使用PEM密钥签名，只有公钥的一方验证
+func TestAsymmetricKeys(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		method  jwt.SigningMethod
+		private any
+		public  any
+	}{
+		{jwt.SigningMethodRS256, rsaKey, &rsaKey.PublicKey},
+		{jwt.SigningMethodPS256, rsaKey, &rsaKey.PublicKey},
+		{jwt.SigningMethodES256, ecKey, &ecKey.PublicKey},
+		{jwt.SigningMethodEdDSA, edPrivate, edPublic},
+	}
+	for _, tt := range tests {
+		privatePEM, publicPEM := pemKeys(t, tt.private, tt.public)
+		signingKey, err := NewKeyFromPEM("k1", tt.method, privatePEM)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.method.Alg(), err)
+		}
+		verifyKey, err := NewKeyFromPEM("k1", tt.method, publicPEM)
+		if err != nil || verifyKey.CanSign() {
+			t.Fatalf("%s: expected public key only, got %v", tt.method.Alg(), err)
+		}
+
+		issuer := NewTokenBuilder(JwtConfig{KeySet: NewKeySet(signingKey), ExpireTime: time.Hour})
+		token, err := issuer.SetMeta(map[string]any{"uid": 1}).GenerateToken()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.method.Alg(), err)
+		}
+		verifier := NewTokenBuilder(JwtConfig{KeySet: NewKeySet(verifyKey)})
+		if err := verifier.SetToken(token).VerifyToken(); err != nil {
+			t.Errorf("%s: %v", tt.method.Alg(), err)
+		}
+		if _, err := NewTokenBuilder(JwtConfig{KeySet: NewKeySet(verifyKey)}).SetMeta(map[string]any{"uid": 1}).GenerateToken(); !errors.Is(err, ErrNoSigningKey) {
+			t.Errorf("%s: expected ErrNoSigningKey, got %v", tt.method.Alg(), err)
+		}
+	}
+
+	if _, err := NewKey("k1", jwt.SigningMethodES384, ecKey); err == nil {
+		t.Errorf("Expected error for P-256 key with ES384")
+	}
+	if _, err := NewKey("k1", jwt.SigningMethodHS256, rsaKey); err == nil {
+		t.Errorf("Expected error for RSA key with HS256")
+	}
+	if _, err := NewTokenBuilder(JwtConfig{SigningMethod: jwt.SigningMethodRS256}).SetMeta(map[string]any{"uid": 1}).GenerateToken(); err == nil {
+		t.Errorf("Expected error for RS256 without key set")
+	}
+}
+
+// 测试从DER文件和PKCS#1 PEM读取密钥
+func TestLoadKeyFile(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	dir := t.TempDir()
+	derPath := filepath.Join(dir, "key.der")
+	pemPath := filepath.Join(dir, "key.pem")
+	os.WriteFile(derPath, x509.MarshalPKCS1PrivateKey(rsaKey), 0o600)
+	os.WriteFile(pemPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)}), 0o600)
+
+	private, err := LoadKeyFile("der", jwt.SigningMethodRS256, derPath)
+	if err != nil || !private.CanSign() {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	public, err := LoadKeyFile("pem", jwt.SigningMethodRS256, pemPath)
+	if err != nil || public.CanSign() {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, err := LoadKeyFile("missing", jwt.SigningMethodRS256, filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Expected error for missing file")
+	}
+}
+
+// 测试轮换密钥后旧令牌仍然有效，删除旧密钥后失效
+func TestKeySetRotation(t *testing.T) {
+	oldKey, _ := NewHMACKey("2024-01", jwt.SigningMethodHS256, []byte("old-secret"))
+	newKey, _ := NewHMACKey("2024-02", jwt.SigningMethodHS256, []byte("new-secret"))
+	keys := NewKeySet(oldKey)
+	tb := NewTokenBuilder(JwtConfig{KeySet: keys, ExpireTime: time.Hour})
+	oldToken, _ := tb.SetMeta(map[string]any{"uid": 1}).GenerateToken()
+
+	keys.Add(newKey)
+	if err := keys.SetSigningKey("2024-02"); err != nil {
+		t.Fatal(err)
+	}
+	newToken, _ := tb.GenerateToken()
+	parsed, _, _ := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	if parsed.Header["kid"] != "2024-02" {
+		t.Errorf("Expected kid 2024-02, got %v", parsed.Header["kid"])
+	}
+	for _, token := range []string{oldToken, newToken} {
+		if err := tb.SetToken(token).VerifyToken(); err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+	}
+
+	keys.Remove("2024-01")
+	if err := tb.SetToken(oldToken).VerifyToken(); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+	if err := keys.SetSigningKey("2024-01"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+}
+
+// 测试验证时拒绝不在白名单中的算法
+func TestValidMethods(t *testing.T) {
+	claims := jwt.MapClaims{"uid": 1, "exp": time.Now().Add(time.Hour).Unix()}
+	hs384, _ := jwt.NewWithClaims(jwt.SigningMethodHS384, claims).SignedString([]byte("secret"))
+	none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	tb := NewTokenBuilder(JwtConfig{SecretKey: "secret", SigningMethod: jwt.SigningMethodHS256})
+	for _, token := range []string{hs384, none} {
+		if err := tb.SetToken(token).VerifyToken(); err == nil {
+			t.Errorf("Expected token %s rejected", token)
+		}
+	}
+
+	tb = NewTokenBuilder(JwtConfig{SecretKey: "secret", ValidMethods: []string{"HS256", "HS384"}})
+	if err := tb.SetToken(hs384).VerifyToken(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	// 使用RSA公钥作为HMAC密钥伪造的令牌
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, publicPEM := pemKeys(t, rsaKey, &rsaKey.PublicKey)
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(publicPEM)
+	public, _ := NewKeyFromPEM("", jwt.SigningMethodRS256, publicPEM)
+	tb = NewTokenBuilder(JwtConfig{KeySet: NewKeySet(public)})
+	if err := tb.SetToken(forged).VerifyToken(); err == nil {
+		t.Errorf("Expected forged token rejected")
+	}
+}
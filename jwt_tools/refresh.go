@@ -0,0 +1,186 @@
+package jwt_tools
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	claimTokenType   = "token_type" // 刷新令牌的类型声明
+	claimFamily      = "fid"        // 同一次登录签发的令牌共享的令牌族ID
+	tokenTypeRefresh = "refresh"
+
+	defaultRefreshExpireTime = 7 * 24 * time.Hour
+)
+
+// registeredClaimNames 标准声明和包内使用的声明，刷新时不会作为元数据复制
+var registeredClaimNames = []string{"iss", "sub", "aud", "jti", "nbf", "exp", "iat", claimTokenType, claimFamily}
+
+// TokenPair 访问令牌和刷新令牌
+type TokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	ExpiresAt        time.Time // 访问令牌的过期时间
+	RefreshExpiresAt time.Time // 刷新令牌的过期时间
+}
+
+// IssuePair 使用元数据和标准声明签发访问令牌和刷新令牌，两者属于同一个令牌族
+// 访问令牌按 ExpireTime 过期，刷新令牌按 RefreshExpireTime 过期
+func (t *TokenBuilder) IssuePair() (*TokenPair, error) {
+	if len(t.Meta) == 0 && t.Claims.Subject == "" {
+		return nil, ErrMetaEmpty
+	}
+	return t.issuePair(t.Meta, t.Claims, newTokenID())
+}
+
+// Refresh 使用刷新令牌签发新的令牌对，旧的刷新令牌被撤销
+// 已经使用过的刷新令牌再次使用时，认为令牌被盗用，撤销整个令牌族并返回 ErrTokenReused
+func (t *TokenBuilder) Refresh(refreshToken string) (*TokenPair, error) {
+	store := t.Config.Revocations
+	if store == nil {
+		return nil, ErrNoRevocationStore
+	}
+
+	t.TokenStr = refreshToken
+	if err := t.verify(true); err != nil {
+		return nil, err
+	}
+	family, _ := t.Meta[claimFamily].(string)
+	if t.Claims.ID == "" || family == "" {
+		return nil, fmt.Errorf("%w: jti and %s", ErrMissingClaim, claimFamily)
+	}
+
+	used, err := store.Revoke(t.Claims.ID, t.remaining(t.Claims.ExpiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("revoke refresh token: %w", err)
+	}
+	if used {
+		if _, err := store.Revoke(familyID(family), t.familyTTL()); err != nil {
+			return nil, fmt.Errorf("revoke token family: %w", err)
+		}
+		return nil, ErrTokenReused
+	}
+
+	meta := make(map[string]any, len(t.Meta))
+	for k, v := range t.Meta {
+		meta[k] = v
+	}
+	for _, name := range registeredClaimNames {
+		delete(meta, name)
+	}
+	registered := RegisteredClaims{Issuer: t.Claims.Issuer, Subject: t.Claims.Subject, Audience: t.Claims.Audience}
+	return t.issuePair(meta, registered, family)
+}
+
+// RevokeToken 撤销令牌，令牌属于某个令牌族时同时撤销整个令牌族，用于退出登录
+// 访问令牌和刷新令牌都可以，签名不正确时返回错误，已经过期的令牌不需要撤销
+func (t *TokenBuilder) RevokeToken(token string) error {
+	store := t.Config.Revocations
+	if store == nil {
+		return ErrNoRevocationStore
+	}
+
+	t.TokenStr = token
+	claims, err := t.parse()
+	if err != nil {
+		return err
+	}
+	registered, err := parseRegisteredClaims(claims)
+	if err != nil {
+		return err
+	}
+	family, _ := claims[claimFamily].(string)
+	if registered.ID == "" && family == "" {
+		return fmt.Errorf("%w: jti", ErrMissingClaim)
+	}
+
+	if ttl := t.remaining(registered.ExpiresAt); registered.ID != "" && ttl > 0 {
+		if _, err := store.Revoke(registered.ID, ttl); err != nil {
+			return fmt.Errorf("revoke token: %w", err)
+		}
+	}
+	if family != "" {
+		if _, err := store.Revoke(familyID(family), t.familyTTL()); err != nil {
+			return fmt.Errorf("revoke token family: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkRevoked 检查令牌和令牌族是否已经撤销，checkID 为false时只检查令牌族
+// 没有 jti 的令牌(开启撤销之前签发的令牌、外部身份提供方签发的令牌)无法单独撤销，不检查 jti
+func (t *TokenBuilder) checkRevoked(id string, claims jwt.MapClaims, checkID bool) error {
+	store := t.Config.Revocations
+	if store == nil {
+		return nil
+	}
+	if checkID && id != "" {
+		revoked, err := store.IsRevoked(id)
+		if err != nil {
+			return fmt.Errorf("check revocation: %w", err)
+		}
+		if revoked {
+			return ErrRevoked
+		}
+	}
+	if family, _ := claims[claimFamily].(string); family != "" {
+		revoked, err := store.IsRevoked(familyID(family))
+		if err != nil {
+			return fmt.Errorf("check revocation: %w", err)
+		}
+		if revoked {
+			return ErrRevoked
+		}
+	}
+	return nil
+}
+
+func (t *TokenBuilder) issuePair(meta map[string]any, registered RegisteredClaims, family string) (*TokenPair, error) {
+	registered.ID = newTokenID()
+	access, expiresAt, err := t.sign(meta, registered, t.Config.ExpireTime, map[string]any{claimFamily: family})
+	if err != nil {
+		return nil, err
+	}
+	registered.ID = newTokenID()
+	refresh, refreshExpiresAt, err := t.sign(meta, registered, t.refreshExpireTime(), map[string]any{
+		claimFamily:    family,
+		claimTokenType: tokenTypeRefresh,
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.TokenStr = access
+	return &TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresAt: expiresAt, RefreshExpiresAt: refreshExpiresAt}, nil
+}
+
+func (t *TokenBuilder) refreshExpireTime() time.Duration {
+	if t.Config.RefreshExpireTime > 0 {
+		return t.Config.RefreshExpireTime
+	}
+	return defaultRefreshExpireTime
+}
+
+// remaining 令牌的剩余有效期，包括允许的时钟偏差
+func (t *TokenBuilder) remaining(expiresAt time.Time) time.Duration {
+	return time.Until(expiresAt) + t.Config.VerifyOptions.Leeway
+}
+
+// familyTTL 令牌族中的令牌最晚在一个刷新令牌有效期后全部过期
+func (t *TokenBuilder) familyTTL() time.Duration {
+	return max(t.refreshExpireTime(), t.Config.ExpireTime) + t.Config.VerifyOptions.Leeway
+}
+
+func familyID(family string) string {
+	return "family:" + family
+}
+
+// newTokenID 生成随机的令牌ID
+func newTokenID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,194 @@
+package jwt_tools
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/otkinlife/go_tools/cache_tools"
+)
+
+func newRevocationManager(t *testing.T) *cache_tools.CacheManager {
+	manager := cache_tools.NewCacheManager()
+	if err := manager.Init(math.MaxInt64, "", cache_tools.WithKeyStrategy(cache_tools.RawKey)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { manager.Close() })
+	return manager
+}
+
+func newRefreshConfig(t *testing.T) JwtConfig {
+	return JwtConfig{
+		SecretKey:         "secret",
+		ExpireTime:        time.Minute,
+		RefreshExpireTime: time.Hour,
+		Revocations:       NewCacheRevocationStore(newRevocationManager(t)),
+	}
+}
+
+// 测试签发令牌对并使用刷新令牌轮换
+func TestIssuePairAndRefresh(t *testing.T) {
+	config := newRefreshConfig(t)
+	pair, err := NewTokenBuilder(config).SetClaims(RegisteredClaims{Subject: "user-1"}).
+		SetMeta(map[string]any{"role": "admin"}).IssuePair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.RefreshExpiresAt.Sub(pair.ExpiresAt) < 50*time.Minute {
+		t.Errorf("Unexpected expiry %v %v", pair.ExpiresAt, pair.RefreshExpiresAt)
+	}
+
+	access := NewTokenBuilder(config)
+	if err := access.SetToken(pair.AccessToken).VerifyToken(); err != nil {
+		t.Fatal(err)
+	}
+	if err := access.SetToken(pair.RefreshToken).VerifyToken(); !errors.Is(err, ErrTokenType) {
+		t.Errorf("Expected refresh token rejected as access token, got %v", err)
+	}
+	if _, err := NewTokenBuilder(config).Refresh(pair.AccessToken); !errors.Is(err, ErrTokenType) {
+		t.Errorf("Expected access token rejected as refresh token, got %v", err)
+	}
+
+	next, err := NewTokenBuilder(config).Refresh(pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb := NewTokenBuilder(config)
+	if err := tb.SetToken(next.AccessToken).VerifyToken(); err != nil {
+		t.Fatal(err)
+	}
+	if tb.GetClaims().Subject != "user-1" || tb.GetMeta()["role"] != "admin" || tb.GetMeta()[claimTokenType] != nil {
+		t.Errorf("Unexpected claims after refresh %v", tb.GetMeta())
+	}
+
+	// 旧的访问令牌在过期前仍然有效
+	if err := access.SetToken(pair.AccessToken).VerifyToken(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+// 测试重复使用刷新令牌时撤销整个令牌族
+func TestRefreshReuseDetection(t *testing.T) {
+	config := newRefreshConfig(t)
+	pair, _ := NewTokenBuilder(config).SetMeta(map[string]any{"uid": 1}).IssuePair()
+	next, err := NewTokenBuilder(config).Refresh(pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewTokenBuilder(config).Refresh(pair.RefreshToken); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("Expected ErrTokenReused, got %v", err)
+	}
+	for _, token := range []string{pair.AccessToken, next.AccessToken} {
+		if err := NewTokenBuilder(config).SetToken(token).VerifyToken(); !errors.Is(err, ErrRevoked) {
+			t.Errorf("Expected ErrRevoked, got %v", err)
+		}
+	}
+	if _, err := NewTokenBuilder(config).Refresh(next.RefreshToken); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Expected ErrRevoked, got %v", err)
+	}
+}
+
+// 测试撤销令牌
+func TestRevokeToken(t *testing.T) {
+	config := newRefreshConfig(t)
+	tb := NewTokenBuilder(config)
+	token, err := tb.SetMeta(map[string]any{"uid": 1}).GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := tb.GenerateToken()
+	if err := tb.RevokeToken(token); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.SetToken(token).VerifyToken(); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Expected ErrRevoked, got %v", err)
+	}
+	if err := tb.SetToken(other).VerifyToken(); err != nil {
+		t.Errorf("Expected other token valid, got %v", err)
+	}
+
+	// 退出登录时撤销令牌对
+	pair, _ := NewTokenBuilder(config).SetMeta(map[string]any{"uid": 1}).IssuePair()
+	if err := NewTokenBuilder(config).RevokeToken(pair.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTokenBuilder(config).Refresh(pair.RefreshToken); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Expected ErrRevoked, got %v", err)
+	}
+
+	// 开启撤销之前签发的令牌没有 jti，仍然可以验证
+	legacy, _ := NewTokenBuilder(JwtConfig{SecretKey: "secret", ExpireTime: time.Minute}).SetMeta(map[string]any{"uid": 1}).GenerateToken()
+	if err := NewTokenBuilder(config).SetToken(legacy).VerifyToken(); err != nil {
+		t.Errorf("Expected token without jti accepted, got %v", err)
+	}
+
+	if err := tb.RevokeToken("invalid"); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Expected ErrMalformed, got %v", err)
+	}
+	noStore := NewTokenBuilder(JwtConfig{SecretKey: "secret"})
+	if err := noStore.RevokeToken(token); !errors.Is(err, ErrNoRevocationStore) {
+		t.Errorf("Expected ErrNoRevocationStore, got %v", err)
+	}
+	if _, err := noStore.Refresh(pair.RefreshToken); !errors.Is(err, ErrNoRevocationStore) {
+		t.Errorf("Expected ErrNoRevocationStore, got %v", err)
+	}
+}
+
+// 测试没有大小限制的管理器撤销大量令牌后最早的撤销记录仍然有效
+func TestCacheRevocationStore(t *testing.T) {
+	store := NewCacheRevocationStore(newRevocationManager(t))
+	if used, err := store.Revoke("victim", time.Hour); used || err != nil {
+		t.Fatalf("Expected first revoke not used, got %v %v", used, err)
+	}
+	for i := range 10000 {
+		if _, err := store.Revoke(fmt.Sprintf("token-%d", i), time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if revoked, _ := store.IsRevoked("victim"); !revoked {
+		t.Error("Expected victim still revoked")
+	}
+	if used, _ := store.Revoke("victim", time.Hour); !used {
+		t.Error("Expected victim revoked before")
+	}
+	if revoked, _ := store.IsRevoked("other"); revoked {
+		t.Error("Expected other not revoked")
+	}
+}
+
+// 测试撤销大量令牌后最早的撤销记录仍然有效，过期的记录被清理
+func TestMemoryRevocationStore(t *testing.T) {
+	store := NewMemoryRevocationStore().(*memoryRevocationStore)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	if used, _ := store.Revoke("victim", time.Hour); used {
+		t.Fatal("Expected first revoke not used")
+	}
+	for i := range 10000 {
+		if _, err := store.Revoke(fmt.Sprintf("token-%d", i), time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if revoked, _ := store.IsRevoked("victim"); !revoked {
+		t.Error("Expected victim still revoked")
+	}
+	if used, _ := store.Revoke("victim", time.Hour); !used {
+		t.Error("Expected victim revoked before")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if revoked, _ := store.IsRevoked("token-0"); revoked {
+		t.Error("Expected expired revocation ignored")
+	}
+	_, _ = store.Revoke("other", time.Minute)
+	if len(store.revoked) != 2 {
+		t.Errorf("Expected expired revocations swept, got %d", len(store.revoked))
+	}
+	if revoked, _ := store.IsRevoked("victim"); !revoked {
+		t.Error("Expected victim still revoked")
+	}
+}
@@ -0,0 +1,102 @@
+package jwt_tools
+
+import (
+	"sync"
+	"time"
+
+	"github.com/otkinlife/go_tools/cache_tools"
+)
+
+// revocationSweepInterval 内存撤销列表清理过期记录的最小间隔
+const revocationSweepInterval = time.Minute
+
+// RevocationStore 已撤销令牌的列表，按 jti 或令牌族ID保存，过期后可以删除
+// 多个实例共享撤销列表时可以使用 Redis 等实现，Revoke 需要是原子的，否则并发刷新时可能检测不到重复使用
+// 撤销记录在令牌过期前不能丢失，不要使用会按容量淘汰数据的缓存实现
+type RevocationStore interface {
+	// Revoke 撤销id，ttl为令牌的剩余有效期，返回之前是否已经撤销
+	Revoke(id string, ttl time.Duration) (bool, error)
+	// IsRevoked 返回id是否已经撤销
+	IsRevoked(id string) (bool, error)
+}
+
+// cacheRevocationStore 使用 cache_tools.CacheManager 保存撤销列表
+type cacheRevocationStore struct {
+	mu      sync.Mutex
+	manager *cache_tools.CacheManager
+}
+
+// NewCacheRevocationStore 使用 cache_tools.CacheManager 在内存中保存撤销列表，缓存时间为令牌的剩余有效期
+// 撤销记录被淘汰或清空后已撤销的令牌会重新生效，因此管理器不能有大小限制(maxSize 使用 math.MaxInt64)，
+// 需要使用 WithKeyStrategy(RawKey)(默认的 HashedDateKey 在零点后找不到之前的记录)，不设置清空缓存的定时计划，也不与其他数据共用
+//
+//	manager := cache_tools.NewCacheManager()
+//	_ = manager.Init(math.MaxInt64, "", cache_tools.WithKeyStrategy(cache_tools.RawKey))
+//	config.Revocations = jwt_tools.NewCacheRevocationStore(manager)
+func NewCacheRevocationStore(manager *cache_tools.CacheManager) RevocationStore {
+	return &cacheRevocationStore{manager: manager}
+}
+
+func (s *cacheRevocationStore) Revoke(id string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.manager.GetValue(revocationKey(id)); ok {
+		return true, nil
+	}
+	return false, s.manager.SetValueWithTTL(revocationKey(id), true, ttl)
+}
+
+func (s *cacheRevocationStore) IsRevoked(id string) (bool, error) {
+	_, ok := s.manager.GetValue(revocationKey(id))
+	return ok, nil
+}
+
+func revocationKey(id string) string {
+	return "jwt_tools:revoked:" + id
+}
+
+// memoryRevocationStore 在内存中保存撤销列表，记录只在过期后删除
+type memoryRevocationStore struct {
+	mu        sync.Mutex
+	revoked   map[string]time.Time // id -> 过期时间
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRevocationStore 在内存中保存撤销列表，记录的有效期为令牌的剩余有效期
+// 不依赖 cache_tools，没有容量限制，撤销的令牌在过期前不会被淘汰，过期的记录在撤销新令牌时定期清理
+func NewMemoryRevocationStore() RevocationStore {
+	return &memoryRevocationStore{revoked: make(map[string]time.Time), now: time.Now}
+}
+
+func (s *memoryRevocationStore) Revoke(id string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	if expiresAt, ok := s.revoked[id]; ok && now.Before(expiresAt) {
+		return true, nil
+	}
+	s.revoked[id] = now.Add(ttl)
+	return false, nil
+}
+
+func (s *memoryRevocationStore) IsRevoked(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt, ok := s.revoked[id]
+	return ok && s.now().Before(expiresAt), nil
+}
+
+// sweep 删除过期的记录，距离上次清理不足 revocationSweepInterval 时跳过
+func (s *memoryRevocationStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < revocationSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for id, expiresAt := range s.revoked {
+		if !now.Before(expiresAt) {
+			delete(s.revoked, id)
+		}
+	}
+}
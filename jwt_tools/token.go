@@ -1,24 +1,34 @@
 package jwt_tools
 
 import (
+	"errors"
 	"fmt"
-	"github.com/golang-jwt/jwt/v4"
+	"slices"
 	"time"
+
+	"github.com/golang-jwt/jwt/v4"
 )
 
 // TokenBuilder 用于构建和管理 JWT 令牌
 type TokenBuilder struct {
-	Meta        map[string]any // 元数据
-	Config      *JwtConfig     // 配置
-	ValidMethod ValidMethod    // 验证方法
-	TokenStr    string         // 令牌字符串
+	Meta        map[string]any   // 元数据
+	Claims      RegisteredClaims // 标准声明，验证后为令牌中的值
+	Config      *JwtConfig       // 配置
+	ValidMethod ValidMethod      // 验证方法
+	TokenStr    string           // 令牌字符串
 }
 
 // JwtConfig 包含 JWT 相关的配置
 type JwtConfig struct {
-	SecretKey     string            // 密钥
-	SigningMethod jwt.SigningMethod // 签名方法
-	ExpireTime    time.Duration     // 过期时间
+	SecretKey         string            // 密钥
+	SigningMethod     jwt.SigningMethod // 签名方法
+	ExpireTime        time.Duration     // 过期时间
+	KeySet            *KeySet           // 密钥集，设置后使用其中的密钥签名和验证，忽略 SecretKey 和 SigningMethod
+	VerifyKeys        KeyResolver       // 验证使用的密钥，设置后优先于 KeySet，例如使用 RemoteKeySet 验证其他服务签发的令牌
+	ValidMethods      []string          // 验证时允许的签名算法，为空时只允许签名使用的算法或密钥集中的算法
+	VerifyOptions     VerifyOptions     // 验证选项，例如签发方、接收方和允许的时钟偏差
+	Revocations       RevocationStore   // 撤销列表，设置后生成的令牌都带有 jti，验证时检查令牌是否已经撤销
+	RefreshExpireTime time.Duration     // 刷新令牌的过期时间，为0时为7天
 }
 
 // ValidMethod 是一个函数类型，用于验证元数据
@@ -42,6 +52,17 @@ func (t *TokenBuilder) GetMeta() map[string]any {
 	return t.Meta
 }
 
+// SetClaims 设置令牌的标准声明，ExpiresAt 和 IssuedAt 在生成时计算
+func (t *TokenBuilder) SetClaims(claims RegisteredClaims) *TokenBuilder {
+	t.Claims = claims
+	return t
+}
+
+// GetClaims 获取令牌的标准声明
+func (t *TokenBuilder) GetClaims() RegisteredClaims {
+	return t.Claims
+}
+
 // SetToken 设置令牌字符串
 func (t *TokenBuilder) SetToken(token string) *TokenBuilder {
 	t.TokenStr = token
@@ -61,70 +82,195 @@ func (t *TokenBuilder) RegisterValidateFunc(f ValidMethod) *TokenBuilder {
 
 // GenerateToken 生成一个 JWT 令牌
 func (t *TokenBuilder) GenerateToken() (string, error) {
-	if len(t.Meta) == 0 {
-		return "", fmt.Errorf("meta is empty")
+	if len(t.Meta) == 0 && t.Claims.Subject == "" && t.Claims.Issuer == "" && t.Claims.ID == "" && len(t.Claims.Audience) == 0 {
+		return "", ErrMetaEmpty
 	}
 
-	// 设置加密算法，如果未指定则使用默认的 HS256
-	signingMethod := t.Config.SigningMethod
-	if signingMethod == nil {
-		signingMethod = jwt.SigningMethodHS256
+	tokenString, _, err := t.sign(t.Meta, t.Claims, t.Config.ExpireTime, nil)
+	if err != nil {
+		return "", err
+	}
+
+	t.TokenStr = tokenString
+	return tokenString, nil
+}
+
+// sign 使用元数据、标准声明和额外的声明生成令牌，返回令牌和过期时间
+func (t *TokenBuilder) sign(meta map[string]any, registered RegisteredClaims, expire time.Duration, extra map[string]any) (string, time.Time, error) {
+	key, err := t.signingKey()
+	if err != nil {
+		return "", time.Time{}, err
 	}
 
-	token := jwt.New(signingMethod)
+	token := jwt.New(key.Method)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
 	claims := token.Claims.(jwt.MapClaims)
-	claims["exp"] = time.Now().Add(t.Config.ExpireTime).Unix()
 
-	// 将元数据添加到声明中
-	for k, v := range t.Meta {
+	// 将元数据添加到声明中，包内使用的声明只能通过 extra 设置
+	for k, v := range meta {
+		claims[k] = v
+	}
+	delete(claims, claimTokenType)
+	delete(claims, claimFamily)
+	for k, v := range extra {
 		claims[k] = v
 	}
 
+	// 标准声明覆盖元数据中的同名字段，验证后的元数据可以直接用于生成新令牌
+	now := time.Now()
+	registered.IssuedAt = now
+	registered.ExpiresAt = now.Add(expire)
+	if registered.ID == "" && t.Config.Revocations != nil {
+		// 撤销令牌需要 jti
+		registered.ID = newTokenID()
+	}
+	registered.setClaims(claims)
+
 	// 生成签名字符串
-	tokenString, err := token.SignedString([]byte(t.Config.SecretKey))
+	tokenString, err := token.SignedString(key.PrivateKey)
 	if err != nil {
-		return "", fmt.Errorf("token generate failed: %w", err)
+		return "", time.Time{}, fmt.Errorf("token generate failed: %w", err)
 	}
-
-	t.TokenStr = tokenString
-	return tokenString, nil
+	return tokenString, registered.ExpiresAt, nil
 }
 
-// VerifyToken 验证 JWT 令牌并更新元数据
+// VerifyToken 验证 JWT 令牌并更新元数据和标准声明
+// 失败时返回的错误可以用 errors.Is 判断，例如 ErrExpired、ErrAudience
+// 刷新令牌不能作为访问令牌使用，设置了 Revocations 时检查令牌是否已经撤销
 func (t *TokenBuilder) VerifyToken() error {
+	return t.verify(false)
+}
+
+// verify 验证令牌，refresh 表示需要刷新令牌，刷新令牌自身是否已经使用由 Refresh 检查
+func (t *TokenBuilder) verify(refresh bool) error {
 	if t.TokenStr == "" {
-		return fmt.Errorf("token is empty")
+		return ErrTokenEmpty
 	}
 
-	token, err := jwt.Parse(t.TokenStr, func(token *jwt.Token) (interface{}, error) {
-		return []byte(t.Config.SecretKey), nil
-	})
+	claims, err := t.parse()
 	if err != nil {
-		return fmt.Errorf("token parsed error: %w", err)
+		return err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
-		return fmt.Errorf("token is invalid")
+	t.Meta = claims
+
+	registered, err := parseRegisteredClaims(claims)
+	if err != nil {
+		return err
 	}
+	t.Claims = registered
 
-	t.Meta = claims
+	if err := t.Config.VerifyOptions.verify(registered, claims, time.Now()); err != nil {
+		return err
+	}
 
-	exp, ok := claims["exp"].(float64)
-	if !ok {
-		return fmt.Errorf("token is invalid")
+	isRefresh := claims[claimTokenType] == tokenTypeRefresh
+	if refresh && !isRefresh {
+		return fmt.Errorf("%w: not a refresh token", ErrTokenType)
+	}
+	if !refresh && isRefresh {
+		return fmt.Errorf("%w: refresh token cannot be used as access token", ErrTokenType)
 	}
 
-	expireTime := time.Unix(int64(exp), 0)
-	if expireTime.Before(time.Now()) {
-		return fmt.Errorf("token is expired")
+	if err := t.checkRevoked(registered.ID, claims, !refresh); err != nil {
+		return err
 	}
 
 	if t.ValidMethod != nil {
 		if err := t.ValidMethod(claims); err != nil {
-			return fmt.Errorf("token is invalid: %w", err)
+			return fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+	}
+
+	return nil
+}
+
+// parse 解析令牌并验证签名，时间相关的声明由 VerifyOptions 检查，以支持时钟偏差
+func (t *TokenBuilder) parse() (jwt.MapClaims, error) {
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	token, err := parser.Parse(t.TokenStr, t.verifyKey)
+	if err != nil {
+		return nil, parseError(err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalid
+	}
+	return claims, nil
+}
+
+// parseError 将解析错误转换为包内的错误
+func parseError(err error) error {
+	var ve *jwt.ValidationError
+	if errors.As(err, &ve) {
+		switch {
+		case ve.Errors&jwt.ValidationErrorMalformed != 0:
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		case ve.Errors&jwt.ValidationErrorUnverifiable != 0 && ve.Inner != nil:
+			// 选择密钥时的错误，例如 ErrAlgorithm、ErrUnknownKey
+			return fmt.Errorf("token parsed error: %w", ve.Inner)
+		case ve.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+			return ErrSignature
+		}
+	}
+	return fmt.Errorf("token parsed error: %w", err)
+}
+
+// signingKey 返回签名使用的密钥，没有设置密钥集时使用 SecretKey，签名方法默认为 HS256
+func (t *TokenBuilder) signingKey() (*Key, error) {
+	if t.Config.KeySet != nil {
+		return t.Config.KeySet.SigningKey()
+	}
+	signingMethod := t.Config.SigningMethod
+	if signingMethod == nil {
+		signingMethod = jwt.SigningMethodHS256
+	}
+	if _, ok := signingMethod.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("signing method %s requires a key set", signingMethod.Alg())
+	}
+	return NewHMACKey("", signingMethod, []byte(t.Config.SecretKey))
+}
+
+// verifyKey 返回验证令牌使用的密钥
+func (t *TokenBuilder) verifyKey(token *jwt.Token) (any, error) {
+	// 只接受允许的算法，防止令牌自己声明的 alg 绕过签名验证
+	if alg := token.Method.Alg(); !slices.Contains(t.validMethods(), alg) {
+		return nil, fmt.Errorf("%w: %s", ErrAlgorithm, alg)
+	}
+	if resolver := t.keyResolver(); resolver != nil {
+		key, err := resolver.ResolveKey(token)
+		if err != nil {
+			return nil, err
 		}
+		return key.PublicKey, nil
 	}
+	return []byte(t.Config.SecretKey), nil
+}
 
+// keyResolver 返回验证使用的密钥，没有设置时使用 SecretKey
+func (t *TokenBuilder) keyResolver() KeyResolver {
+	if t.Config.VerifyKeys != nil {
+		return t.Config.VerifyKeys
+	}
+	if t.Config.KeySet != nil {
+		return t.Config.KeySet
+	}
 	return nil
 }
+
+// validMethods 验证时允许的签名算法
+func (t *TokenBuilder) validMethods() []string {
+	if len(t.Config.ValidMethods) > 0 {
+		return t.Config.ValidMethods
+	}
+	if resolver := t.keyResolver(); resolver != nil {
+		return resolver.Methods()
+	}
+	if t.Config.SigningMethod != nil {
+		return []string{t.Config.SigningMethod.Alg()}
+	}
+	return []string{jwt.SigningMethodHS256.Alg()}
+}
@@ -0,0 +1,122 @@
+package jwt_tools
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TypedValidMethod 验证类型化声明的函数
+type TypedValidMethod[C any] func(claims C) error
+
+// registeredClaimsHolder 嵌入了 RegisteredClaims 的结构体通过这个接口读写标准声明
+type registeredClaimsHolder interface {
+	registeredClaims() *RegisteredClaims
+}
+
+func (c *RegisteredClaims) registeredClaims() *RegisteredClaims {
+	return c
+}
+
+// holderOf 返回声明中嵌入的 RegisteredClaims，C 可以是结构体或结构体指针，没有嵌入或者为空指针时返回 nil
+func holderOf[C any](claims *C) *RegisteredClaims {
+	if holder, ok := any(*claims).(registeredClaimsHolder); ok {
+		if v := reflect.ValueOf(holder); v.Kind() == reflect.Pointer && v.IsNil() {
+			return nil
+		}
+		return holder.registeredClaims()
+	}
+	if holder, ok := any(claims).(registeredClaimsHolder); ok {
+		return holder.registeredClaims()
+	}
+	return nil
+}
+
+// GenerateTyped 使用结构体或结构体指针生成令牌，自定义字段按 json 标签写入声明
+// 结构体嵌入 RegisteredClaims 时使用其中的标准声明，否则使用 SetClaims 设置的标准声明
+//
+//	type UserClaims struct {
+//		jwt_tools.RegisteredClaims
+//		UserID int64  `json:"user_id"`
+//		Role   string `json:"role"`
+//	}
+//	token, err := jwt_tools.GenerateTyped(tb, UserClaims{RegisteredClaims: jwt_tools.RegisteredClaims{Subject: "1"}, UserID: 1})
+func GenerateTyped[C any](t *TokenBuilder, claims C) (string, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("marshal claims: %w", err)
+	}
+	var meta map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // 保留整数的精度
+	if err := decoder.Decode(&meta); err != nil {
+		return "", fmt.Errorf("claims must be a struct or map: %w", err)
+	}
+	if meta == nil {
+		return "", errors.New("claims must not be nil")
+	}
+
+	registered := t.Claims
+	if holder := holderOf(&claims); holder != nil {
+		registered = *holder
+	}
+	if len(meta) == 0 && registered.Subject == "" && registered.Issuer == "" && registered.ID == "" && len(registered.Audience) == 0 {
+		return "", ErrMetaEmpty
+	}
+
+	tokenString, _, err := t.sign(meta, registered, t.Config.ExpireTime, nil)
+	if err != nil {
+		return "", err
+	}
+	t.TokenStr = tokenString
+	return tokenString, nil
+}
+
+// VerifyTyped 验证令牌并将声明解析到结构体，自定义字段按 json 标签读取，嵌入的 RegisteredClaims 设置为令牌中的标准声明
+// 验证规则与 VerifyToken 相同，RegisterValidateFunc 注册的 ValidMethod 先以 map 形式的声明调用，
+// 通过后再解析结构体并依次调用 validators，任一返回错误时包装为 ErrInvalid
+//
+//	claims, err := jwt_tools.VerifyTyped(tb, token, func(c UserClaims) error { ... })
+func VerifyTyped[C any](t *TokenBuilder, token string, validators ...TypedValidMethod[C]) (C, error) {
+	var claims C
+	t.TokenStr = token
+	if err := t.VerifyToken(); err != nil {
+		return claims, err
+	}
+
+	// 直接解析载荷，避免整数经过 float64 丢失精度
+	payload, err := tokenPayload(token)
+	if err != nil {
+		return claims, err
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	if holder := holderOf(&claims); holder != nil {
+		*holder = t.Claims
+	}
+
+	for _, validate := range validators {
+		if err := validate(claims); err != nil {
+			return claims, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+	}
+	return claims, nil
+}
+
+// tokenPayload 返回令牌载荷的JSON
+func tokenPayload(token string) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,124 @@
+package jwt_tools
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type userClaims struct {
+	RegisteredClaims
+	UserID int64    `json:"user_id"`
+	Role   string   `json:"role"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// 测试结构体声明的生成和验证，整数不丢失精度
+func TestTypedClaims(t *testing.T) {
+	config := JwtConfig{SecretKey: "secret", ExpireTime: time.Hour}
+	token, err := GenerateTyped(NewTokenBuilder(config), userClaims{
+		RegisteredClaims: RegisteredClaims{Subject: "user-1", Audience: []string{"api"}},
+		UserID:           1<<53 + 1,
+		Role:             "admin",
+		Scopes:           []string{"read", "write"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tb := NewTokenBuilder(config)
+	claims, err := VerifyTyped[userClaims](tb, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != 1<<53+1 || claims.Role != "admin" || len(claims.Scopes) != 2 {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+	if claims.Subject != "user-1" || claims.Audience[0] != "api" || claims.ExpiresAt.IsZero() {
+		t.Errorf("Unexpected registered claims %+v", claims.RegisteredClaims)
+	}
+	if _, ok := tb.GetMeta()["Subject"]; ok {
+		t.Errorf("Expected registered claims not serialized as custom claims")
+	}
+
+	// 没有嵌入 RegisteredClaims 时使用 SetClaims 设置的标准声明
+	type plain struct {
+		Name string `json:"name"`
+	}
+	tb = NewTokenBuilder(config).SetClaims(RegisteredClaims{Issuer: "auth"})
+	token, err = GenerateTyped(tb, plain{Name: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := VerifyTyped[plain](tb, token)
+	if err != nil || p.Name != "test" || tb.GetClaims().Issuer != "auth" {
+		t.Errorf("Unexpected claims %+v %v", p, err)
+	}
+
+	// 使用结构体指针时同样读写嵌入的标准声明
+	token, err = GenerateTyped(NewTokenBuilder(config), &userClaims{RegisteredClaims: RegisteredClaims{Subject: "u1"}, UserID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ptr, err := VerifyTyped[*userClaims](NewTokenBuilder(config), token)
+	if err != nil || ptr.Subject != "u1" || ptr.UserID != 2 || ptr.ExpiresAt.IsZero() {
+		t.Errorf("Unexpected claims %+v %v", ptr, err)
+	}
+	if _, err := GenerateTyped(NewTokenBuilder(config), (*userClaims)(nil)); err == nil {
+		t.Errorf("Expected error for nil claims")
+	}
+
+	if _, err := GenerateTyped(NewTokenBuilder(config), struct{}{}); !errors.Is(err, ErrMetaEmpty) {
+		t.Errorf("Expected ErrMetaEmpty, got %v", err)
+	}
+	if _, err := GenerateTyped(NewTokenBuilder(config), 1); err == nil {
+		t.Errorf("Expected error for non-struct claims")
+	}
+}
+
+// 测试类型化的验证函数和验证失败
+func TestTypedValidateFunc(t *testing.T) {
+	config := JwtConfig{SecretKey: "secret", ExpireTime: time.Hour}
+	token, _ := GenerateTyped(NewTokenBuilder(config), userClaims{UserID: 1, Role: "guest"})
+
+	isAdmin := func(c userClaims) error {
+		if c.Role != "admin" {
+			return errors.New("role is not allowed")
+		}
+		return nil
+	}
+	if _, err := VerifyTyped(NewTokenBuilder(config), token, isAdmin); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+	hasUser := func(c userClaims) error {
+		if c.UserID == 0 {
+			return errors.New("missing user")
+		}
+		return nil
+	}
+	if claims, err := VerifyTyped(NewTokenBuilder(config), token, hasUser); err != nil || claims.Role != "guest" {
+		t.Errorf("Unexpected claims %+v %v", claims, err)
+	}
+
+	expired, _ := GenerateTyped(NewTokenBuilder(JwtConfig{SecretKey: "secret", ExpireTime: -time.Minute}), userClaims{UserID: 1})
+	if _, err := VerifyTyped[userClaims](NewTokenBuilder(config), expired); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+
+	// ValidMethod 收到 map 形式的声明，先于类型化的验证函数调用
+	var order []string
+	tb := NewTokenBuilder(config).RegisterValidateFunc(func(meta map[string]any) error {
+		order = append(order, "map")
+		if _, ok := meta["user_id"].(float64); !ok {
+			return errors.New("unexpected meta")
+		}
+		return nil
+	})
+	_, err := VerifyTyped(tb, token, func(c userClaims) error {
+		order = append(order, "typed")
+		return nil
+	})
+	if err != nil || len(order) != 2 || order[0] != "map" || order[1] != "typed" {
+		t.Errorf("Expected ValidMethod before typed validators, got %v %v", order, err)
+	}
+}
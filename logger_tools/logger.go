@@ -255,6 +255,19 @@ func Panic(ctx context.Context, args ...any) {
 	}
 }
 
+// GetTraceID 获取 context 中的 trace_id，context 不是由 NewContext 创建时返回空字符串
+func GetTraceID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	logger, ok := ctx.Value(Key).(*Logger)
+	if !ok {
+		return ""
+	}
+	traceID, _ := logger.Logger.Data["trace_id"].(string)
+	return traceID
+}
+
 // getLogger 从 context 获取 logger
 func getLogger(ctx context.Context) *Logger {
 	if ctx == nil {